@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+
+	"github.com/Madh93/karakeepbot/internal/config"
+	"github.com/Madh93/karakeepbot/internal/karakeepbot"
+	"github.com/Madh93/karakeepbot/internal/logging"
+)
+
+// Exit codes returned by commands, so they can be used from scripts, CI and
+// container init steps.
+const (
+	exitOK      = 0 // Command succeeded.
+	exitFailure = 1 // Command ran but failed (e.g. invalid configuration or failed checks).
+	exitUsage   = 2 // Command was invoked incorrectly.
+)
+
+// errUsage is returned by commands invoked with wrong arguments.
+var errUsage = errors.New("invalid usage")
+
+// errReported is returned by commands that already reported their failure.
+var errReported = errors.New("command failed")
+
+// command represents a subcommand of the application.
+type command struct {
+	// run runs the command with the remaining command line arguments.
+	run func(ctx context.Context, logger *logging.Logger, config *config.Config, args []string) error
+	// validate tells whether the configuration must be valid before running,
+	// which is checked before setting up the logger.
+	validate bool
+}
+
+// commands are the available subcommands, indexed by name.
+var commands = map[string]command{
+	"validate":  {run: runValidate, validate: false},
+	"doctor":    {run: runDoctor, validate: false},
+	"send-test": {run: runSendTest, validate: true},
+	"import":    {run: runImport, validate: true},
+}
+
+// runCommand runs the subcommand named by the first argument and returns the
+// process exit code.
+func runCommand(logger *logging.Logger, config *config.Config, args []string) int {
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Unknown command %q. Run with -help to see the available commands.\n", args[0])
+		return exitUsage
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	err := cmd.run(ctx, logger, config, args[1:])
+	switch {
+	case err == nil, errors.Is(err, flag.ErrHelp):
+		return exitOK
+	case errors.Is(err, errUsage):
+		return exitUsage
+	case errors.Is(err, errReported):
+		return exitFailure
+	default:
+		logger.Error("💥 Command failed.", "command", args[0], "error", err)
+		return exitFailure
+	}
+}
+
+// printValidationErrors prints every configuration problem found in err.
+func printValidationErrors(err error) {
+	errs := []error{err}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		errs = joined.Unwrap()
+	}
+	for _, e := range errs {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", e)
+	}
+}
+
+// parseFlags parses the command flags, mapping parsing errors to errUsage. If
+// help was requested, the usage is printed and [flag.ErrHelp] is returned.
+func parseFlags(f *flag.FlagSet, args []string) error {
+	if err := f.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return flag.ErrHelp
+		}
+		return errUsage
+	}
+	return nil
+}
+
+// newFlagSet returns the flag set of a command, whose usage prints the
+// synopsis and the description of the command, and its flags if any.
+func newFlagSet(name, synopsis, description string) *flag.FlagSet {
+	f := flag.NewFlagSet(name, flag.ContinueOnError)
+	f.Usage = func() {
+		fmt.Fprintln(f.Output(), "Usage: "+synopsis)
+		fmt.Fprintln(f.Output(), "\n"+description)
+		hasFlags := false
+		f.VisitAll(func(*flag.Flag) { hasFlags = true })
+		if hasFlags {
+			fmt.Fprintln(f.Output(), "\nFlags:")
+			f.PrintDefaults()
+		}
+	}
+	return f
+}
+
+// runValidate implements the "validate" command, which validates the
+// configuration and prints every problem found.
+func runValidate(_ context.Context, _ *logging.Logger, config *config.Config, args []string) error {
+	f := newFlagSet("validate", "validate", "Validate the configuration and print every problem found.")
+	if err := parseFlags(f, args); err != nil {
+		return err
+	}
+	if f.NArg() > 0 {
+		f.Usage()
+		return errUsage
+	}
+
+	if err := config.Validate(); err != nil {
+		printValidationErrors(err)
+		return errReported
+	}
+
+	fmt.Println("✅ Configuration is valid")
+	return nil
+}
+
+// runDoctor implements the "doctor" command, which checks the configuration,
+// the connectivity with Karakeep and Telegram, and the local environment.
+func runDoctor(ctx context.Context, logger *logging.Logger, config *config.Config, args []string) error {
+	f := newFlagSet("doctor", "doctor", "Check the configuration, the connectivity with Karakeep and Telegram, and the local environment.")
+	if err := parseFlags(f, args); err != nil {
+		return err
+	}
+	if f.NArg() > 0 {
+		f.Usage()
+		return errUsage
+	}
+
+	if err := config.Validate(); err != nil {
+		printValidationErrors(err)
+		return errReported
+	}
+	fmt.Println("✅ Configuration: valid")
+
+	if !karakeepbot.Doctor(ctx, logger, config, os.Stdout) {
+		return errReported
+	}
+	return nil
+}
+
+// runSendTest implements the "send-test" command, which sends a test message
+// to a chat to verify the bot permissions.
+func runSendTest(ctx context.Context, logger *logging.Logger, config *config.Config, args []string) error {
+	f := newFlagSet("send-test", "send-test [flags] <chat-id>", "Send a test message to a chat and delete it, to verify the bot permissions.")
+	threadID := f.Int("thread", 0, "Thread ID (a.k.a topic) to send the message to")
+
+	if err := parseFlags(f, args); err != nil {
+		return err
+	}
+	if f.NArg() != 1 {
+		f.Usage()
+		return errUsage
+	}
+
+	chatID, err := strconv.ParseInt(f.Arg(0), 10, 64)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid chat ID %q\n", f.Arg(0))
+		return errUsage
+	}
+
+	if err := karakeepbot.SendTest(ctx, logger, config, chatID, *threadID, os.Stdout); err != nil {
+		return errReported
+	}
+	return nil
+}
@@ -6,7 +6,6 @@ require (
 	github.com/Madh93/go-karakeep v0.27.1
 	github.com/go-telegram/bot v1.17.0
 	github.com/knadh/koanf/parsers/toml/v2 v2.2.0
-	github.com/knadh/koanf/providers/env v1.1.0
 	github.com/knadh/koanf/providers/file v1.2.0
 	github.com/knadh/koanf/v2 v2.3.0
 	github.com/lmittmann/tint v1.1.2
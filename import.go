@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/Madh93/karakeepbot/internal/config"
+	"github.com/Madh93/karakeepbot/internal/karakeepbot"
+	"github.com/Madh93/karakeepbot/internal/logging"
+)
+
+// runImport implements the "import" command, which imports bookmarks from
+// external sources into Karakeep.
+func runImport(ctx context.Context, logger *logging.Logger, config *config.Config, args []string) error {
+	if len(args) == 0 || args[0] != "telegram-export" {
+		fmt.Fprintln(os.Stderr, "Usage: import telegram-export [flags] <dir>")
+		if len(args) > 0 && slices.Contains([]string{"-h", "-help", "--help"}, args[0]) {
+			return flag.ErrHelp
+		}
+		return errUsage
+	}
+
+	f := newFlagSet("import telegram-export", "import telegram-export [flags] <dir>", "Import the messages of a Telegram Desktop JSON export (result.json) into Karakeep.")
+	dryRun := f.Bool("dry-run", false, "Show what would be imported without uploading anything")
+	interval := f.Duration("interval", time.Second, "Minimum time between uploads to Karakeep")
+	checkpoint := f.String("checkpoint", "", "Checkpoint file used to resume the import (default: <dir>/"+karakeepbot.DefaultCheckpointFile+")")
+	if err := parseFlags(f, args[1:]); err != nil {
+		return err
+	}
+	if f.NArg() != 1 {
+		f.Usage()
+		return errUsage
+	}
+
+	importer := karakeepbot.NewImporter(logger, config)
+	return importer.ImportTelegramExport(ctx, f.Arg(0), karakeepbot.ImportOptions{
+		DryRun:     *dryRun,
+		Interval:   *interval,
+		Checkpoint: *checkpoint,
+		Output:     os.Stdout,
+	})
+}
@@ -0,0 +1,100 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ChatConfig represents the settings overridden for a chat, or for a thread
+// (a.k.a topic) of a chat. Unset fields inherit the value of the chat-wide
+// block, or of the global configuration.
+type ChatConfig struct {
+	ID        int64    `koanf:"id"`        // Chat ID.
+	Thread    *int     `koanf:"thread"`    // Thread ID. If unset, the block applies to the whole chat.
+	Interval  *int     `koanf:"interval"`  // Interval (in seconds) before retrying tagging status.
+	Timeout   *int     `koanf:"timeout"`   // Maximum time (in seconds) to wait for AI tags. 0 disables waiting.
+	ReplyMode *string  `koanf:"replymode"` // What to do with the original message: "replace", "reply" or "none".
+	Tags      []string `koanf:"tags"`      // Extra tags added to every bookmark.
+	Types     []string `koanf:"types"`     // Allowed bookmark types: "link", "text" and "asset". If unset, all types are allowed.
+}
+
+// ChatsConfig represents the list of per-chat overrides.
+type ChatsConfig []ChatConfig
+
+// String returns a human-readable representation of the overrides, showing
+// only the fields that are set.
+func (c ChatConfig) String() string {
+	fields := []string{fmt.Sprintf("id=%d", c.ID)}
+	if c.Thread != nil {
+		fields = append(fields, fmt.Sprintf("thread=%d", *c.Thread))
+	}
+	if c.Interval != nil {
+		fields = append(fields, fmt.Sprintf("interval=%d", *c.Interval))
+	}
+	if c.Timeout != nil {
+		fields = append(fields, fmt.Sprintf("timeout=%d", *c.Timeout))
+	}
+	if c.ReplyMode != nil {
+		fields = append(fields, fmt.Sprintf("replymode=%s", *c.ReplyMode))
+	}
+	if c.Tags != nil {
+		fields = append(fields, fmt.Sprintf("tags=%v", c.Tags))
+	}
+	if c.Types != nil {
+		fields = append(fields, fmt.Sprintf("types=%v", c.Types))
+	}
+	return "{" + strings.Join(fields, " ") + "}"
+}
+
+// Validate checks if the per-chat overrides are valid.
+func (c ChatsConfig) Validate() error {
+	var errs ValidationErrors
+
+	type scope struct {
+		id     int64
+		thread int
+		all    bool
+	}
+	seen := make(map[scope]bool)
+
+	for i, chat := range c {
+		key := fmt.Sprintf("[%d].", i)
+
+		if chat.ID == 0 {
+			errs.addf(key+"id", "invalid id: must be set")
+		}
+
+		s := scope{id: chat.ID, all: chat.Thread == nil}
+		if chat.Thread != nil {
+			s.thread = *chat.Thread
+		}
+		if seen[s] {
+			errs.addf(key+"id", "duplicate block for chat %d", chat.ID)
+		}
+		seen[s] = true
+
+		if chat.Interval != nil && *chat.Interval <= 0 {
+			errs.addf(key+"interval", "invalid interval: must be a positive value, got %d", *chat.Interval)
+		}
+		if chat.Timeout != nil && *chat.Timeout < 0 {
+			errs.addf(key+"timeout", "invalid timeout: must not be negative, got %d", *chat.Timeout)
+		}
+		if chat.ReplyMode != nil && !slices.Contains(validReplyModes, *chat.ReplyMode) {
+			errs.addf(key+"replymode", "invalid reply mode %q: must be one of %v", *chat.ReplyMode, validReplyModes)
+		}
+		for _, tag := range chat.Tags {
+			if strings.TrimSpace(tag) == "" {
+				errs.addf(key+"tags", "invalid tags: contains an empty entry")
+				break
+			}
+		}
+		for _, typ := range chat.Types {
+			if !slices.Contains(validBookmarkTypes, typ) {
+				errs.addf(key+"types", "invalid type %q: must be one of %v", typ, validBookmarkTypes)
+			}
+		}
+	}
+
+	return errs.err()
+}
@@ -0,0 +1,70 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestChatsConfigValidate(t *testing.T) {
+	ptr := func(v int) *int { return &v }
+	mode := func(v string) *string { return &v }
+
+	tests := []struct {
+		name     string
+		config   ChatsConfig
+		expected bool
+	}{
+		{"Empty", nil, true},
+		{"Valid chat and thread blocks", ChatsConfig{{ID: 1, ReplyMode: mode("reply")}, {ID: 1, Thread: ptr(2), Timeout: ptr(0), Types: []string{"link", "text"}}}, true},
+		{"Missing ID", ChatsConfig{{ReplyMode: mode("reply")}}, false},
+		{"Duplicate chat block", ChatsConfig{{ID: 1}, {ID: 1}}, false},
+		{"Duplicate thread block", ChatsConfig{{ID: 1, Thread: ptr(2)}, {ID: 1, Thread: ptr(2)}}, false},
+		{"Invalid interval", ChatsConfig{{ID: 1, Interval: ptr(0)}}, false},
+		{"Negative timeout", ChatsConfig{{ID: 1, Timeout: ptr(-1)}}, false},
+		{"Invalid reply mode", ChatsConfig{{ID: 1, ReplyMode: mode("delete")}}, false},
+		{"Invalid type", ChatsConfig{{ID: 1, Types: []string{"video"}}}, false},
+		{"Empty tag", ChatsConfig{{ID: 1, Tags: []string{""}}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if (err == nil) != tt.expected {
+				t.Errorf("For config %v, expected valid: %v, but got error: %v", tt.config, tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestNewChats(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	data := `
+[[chats]]
+id = -100123
+replymode = "reply"
+
+[[chats]]
+id = -100123
+thread = 42
+timeout = 0
+`
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+
+	config, err := New([]string{"-config", path})
+	if err != nil {
+		t.Fatalf("New() returned an unexpected error: %v", err)
+	}
+
+	if len(config.Chats) != 2 {
+		t.Fatalf("expected 2 chat blocks, got %v", config.Chats)
+	}
+	if chat := config.Chats[0]; chat.Thread != nil || chat.ReplyMode == nil || *chat.ReplyMode != "reply" || chat.Timeout != nil {
+		t.Errorf("unexpected chat-wide block: %v", chat)
+	}
+	if chat := config.Chats[1]; chat.Thread == nil || *chat.Thread != 42 || chat.Timeout == nil || *chat.Timeout != 0 || chat.ReplyMode != nil {
+		t.Errorf("unexpected thread block: %v", chat)
+	}
+}
@@ -25,23 +25,64 @@
 //     timeouts, maximum file sizes, and the temporary directory for storing
 //     files.
 //
+//   - URLCleanerConfig: Controls how shared links are rewritten before being
+//     saved, including redirect resolution and user-defined rewrite rules.
+//
+//   - TaggingConfig: Defines how long and how often to wait for the AI tags of
+//     new bookmarks, and for which bookmark types.
+//
+//   - HashtagsConfig: Normalizes Karakeep tags into Telegram hashtags and
+//     back, with aliases, a blocklist and a maximum number of hashtags, and
+//     defines how AI tags are shown.
+//
+//   - ListsConfig: Sets the prefix used to add new bookmarks to a Karakeep
+//     list from the message (e.g. "@reading").
+//
+//   - InlineConfig: Answers inline queries with the bookmarks found in
+//     Karakeep, to share them in any chat.
+//
+//   - WebhookConfig: Receives Karakeep webhook events to know when bookmarks
+//     are tagged, instead of polling for it.
+//
+//   - RateLimitConfig: Limits the rate of incoming saves per user and chat, and
+//     the number of bookmarks processed by Karakeep at once.
+//
+//   - RolesConfig: Assigns roles (admins, contributors and readers) to Telegram
+//     users, and defines what to do with users without a role.
+//
+//   - StateConfig: Sets where the bot state, such as the roles granted with
+//     commands, is persisted.
+//
+//   - ChatsConfig: Overrides settings such as the reply mode or the tagging
+//     wait for specific chats and threads.
+//
+//   - SchedulerConfig: Sets the time zone of the jobs run on a schedule.
+//
+//   - DigestsConfig: Posts digests of the bookmarks not archived yet to chats
+//     on a cron schedule.
+//
+//   - RandomConfig: Posts random bookmarks to chats on a cron schedule, and
+//     avoids posting the same bookmark again too soon.
+//
 // The package also provides a New function to create a new configuration
 // instance, initializing it with default values, loading settings from a file,
-// and processing command line parameters. It ensures that settings are
-// validated before they are used, enhancing the reliability of the application.
+// and processing command line parameters. The Validate method checks every
+// section and reports all the problems found at once, so settings can be
+// validated before they are used.
 package config
 
 import (
+	"errors"
 	"flag"
 	"fmt"
-	"log"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 
+	"github.com/Madh93/karakeepbot/internal/secret"
 	"github.com/Madh93/karakeepbot/internal/version"
 	"github.com/knadh/koanf/parsers/toml/v2"
-	"github.com/knadh/koanf/providers/env"
 	"github.com/knadh/koanf/providers/file"
 	"github.com/knadh/koanf/v2"
 )
@@ -53,7 +94,25 @@ type Config struct {
 	Karakeep      KarakeepConfig      `koanf:"karakeep"`      // Karakeep configuration
 	Logging       LoggingConfig       `koanf:"logging"`       // Logging configuration
 	FileProcessor FileProcessorConfig `koanf:"fileprocessor"` // File processor configuration
+	URLCleaner    URLCleanerConfig    `koanf:"urlcleaner"`    // URL cleaner configuration
+	Tagging       TaggingConfig       `koanf:"tagging"`       // Tagging configuration
+	Hashtags      HashtagsConfig      `koanf:"hashtags"`      // Hashtags configuration
+	Lists         ListsConfig         `koanf:"lists"`         // Karakeep lists configuration
+	Inline        InlineConfig        `koanf:"inline"`        // Inline mode configuration
+	Webhook       WebhookConfig       `koanf:"webhook"`       // Karakeep webhook configuration
+	RateLimit     RateLimitConfig     `koanf:"ratelimit"`     // Rate limit configuration
+	Roles         RolesConfig         `koanf:"roles"`         // Roles configuration
+	State         StateConfig         `koanf:"state"`         // State configuration
+	Chats         ChatsConfig         `koanf:"chats"`         // Per-chat and per-thread overrides
+	Scheduler     SchedulerConfig     `koanf:"scheduler"`     // Scheduler configuration
+	Digests       DigestsConfig       `koanf:"digests"`       // Scheduled digests
+	Random        RandomConfig        `koanf:"random"`        // Random bookmarks
 	Path          string              `koanf:"path"`          // Path to the configuration file
+	Args          []string            `koanf:"-"`             // Positional command line arguments (subcommand and its arguments)
+
+	args         []string          // Command line arguments used to load the configuration
+	sources      map[string]string // Source of each loaded key (file or environment variable)
+	deprecations []string          // Warnings about deprecated keys in use
 }
 
 // AppName is the name of the bot.
@@ -76,10 +135,13 @@ var DefaultConfig = Config{
 		Threads:      []int(nil),
 		ProxyEnabled: false,
 		ProxyURL:     "",
+		ReplyMode:    ReplyModeReplace,
+		Retries:      3,
 	},
 	Karakeep: KarakeepConfig{
-		URL:      "http://localhost:3000",
-		Interval: 5, // In seconds
+		URL:     "http://localhost:3000",
+		Retries: 3,
+		Timeout: 30, // In seconds
 	},
 	Logging: LoggingConfig{
 		Level:   "info",
@@ -99,94 +161,305 @@ var DefaultConfig = Config{
 		},
 		Timeout: 30, // In seconds
 	},
+	URLCleaner: URLCleanerConfig{
+		Enabled: true,
+		Resolve: false,
+		Timeout: 5, // In seconds
+		Params:  []string(nil),
+		Rules:   []URLRewriteRule(nil),
+	},
+	Tagging: TaggingConfig{
+		Interval:    2,  // In seconds
+		MaxInterval: 10, // In seconds
+		Timeout:     30, // In seconds
+		Types:       []string{LinkBookmarkType, TextBookmarkType, AssetBookmarkType},
+		Deferred:    false,
+		Watch:       600, // In seconds
+	},
+	Hashtags: HashtagsConfig{
+		Case:          HashtagCaseKeep,
+		Transliterate: false,
+		Aliases:       []HashtagAlias(nil),
+		Blocklist:     []string(nil),
+		Max:           0, // No limit
+		AITags:        AITagsSeparate,
+		MaxAI:         0, // No limit
+	},
+	Lists: ListsConfig{
+		Prefix: "@",
+	},
+	Inline: InlineConfig{
+		Enabled: false,
+		Results: 10,
+		Cache:   30, // In seconds
+	},
+	Webhook: WebhookConfig{
+		Enabled: false,
+		Listen:  ":8088",
+		Path:    "/webhooks/karakeep",
+	},
+	RateLimit: RateLimitConfig{
+		Enabled:   false,
+		UserRate:  20, // Per minute
+		UserBurst: 10,
+		ChatRate:  60, // Per minute
+		ChatBurst: 30,
+		Crawls:    4,
+	},
+	Roles: RolesConfig{
+		Admins:       []int64(nil),
+		Contributors: []int64(nil),
+		Readers:      []int64(nil),
+		Unknown:      IgnoreUnknownPolicy,
+		Reply:        "⛔ You are not allowed to use this bot.",
+	},
+	State: StateConfig{
+		Dir: "", // Empty means in memory
+	},
+	Chats: ChatsConfig(nil),
+	Scheduler: SchedulerConfig{
+		Timezone: "Local",
+	},
+	Digests: DigestsConfig(nil),
+	Random: RandomConfig{
+		Window: 30, // Days
+		Posts:  []RandomPostConfig(nil),
+	},
 	Path: DefaultPath,
 }
 
+// SecretFileSuffix is appended to the keys in SecretKeys (and to their
+// environment variables, in uppercase) to read their value from a file instead.
+const SecretFileSuffix = "_file"
+
+// SecretKeys are the configuration keys whose value can also be read from a
+// file, e.g. "telegram.token_file" or KARAKEEPBOT_TELEGRAM_TOKEN_FILE.
+var SecretKeys = []string{"telegram.token", "telegram.proxyurl", "karakeep.token", "karakeep.proxyurl", "webhook.token"}
+
 // New returns a new config instance. This initializes the default configuration
-// and loads configurations from files (.toml) and environment variables.
-func New() *Config {
-	// Default config
+// and loads configurations from command line arguments (without the program
+// name), files (.toml) and environment variables. The configuration is not
+// validated; use Validate before using it. If the help or version information
+// was requested, it is printed and [flag.ErrHelp] or [ErrVersion] is returned.
+//
+// Values are loaded with the following precedence, from lowest to highest:
+//
+//  1. Default configuration.
+//  2. Configuration file key (e.g. "token").
+//  3. Configuration file secret key (e.g. "token_file").
+//  4. Environment variable (e.g. KARAKEEPBOT_TELEGRAM_TOKEN).
+//  5. Environment variable secret (e.g. KARAKEEPBOT_TELEGRAM_TOKEN_FILE).
+func New(args []string) (*Config, error) {
+	// Default config. Slices are cloned, so loading values into them doesn't
+	// modify the defaults.
 	config := DefaultConfig
-
-	// Setup koanf
-	k := koanf.New(".")
+	config.Telegram.Allowlist = slices.Clone(config.Telegram.Allowlist)
+	config.FileProcessor.Mimetypes = slices.Clone(config.FileProcessor.Mimetypes)
+	config.Tagging.Types = slices.Clone(config.Tagging.Types)
+	config.args = args
+	config.sources = make(map[string]string)
 
 	// Parse command line flags
-	parseCommandLineFlags(&config)
+	if err := parseCommandLineFlags(&config, args); err != nil {
+		return nil, err
+	}
 
 	// Load configuration from path
-	if err := k.Load(file.Provider(config.Path), toml.Parser()); err != nil {
+	fileConfig := koanf.New(".")
+	if err := fileConfig.Load(file.Provider(config.Path), toml.Parser()); err != nil {
 		if config.Path != DefaultPath {
-			log.Fatalf("Error loading config file: %v", err)
+			return nil, fmt.Errorf("error loading config file: %w", err)
 		}
 		config.Path = "" // Ignore configuration path if default path doesn't exist
 	}
+	for _, key := range fileConfig.Keys() {
+		config.sources[key] = "file " + config.Path
+	}
+	if err := loadSecretFiles(fileConfig, config.sources); err != nil {
+		return nil, err
+	}
 
 	// Configuration from environment variables (with KARAKEEPBOT_ prefix)
-	// NOTE: This can't handle multi-word environment variables like TELEGRAM_SECRET_KEY
-	// See: https://github.com/knadh/koanf/issues/295
-	prefix := strings.ToUpper(AppName)
-	if err := k.Load(env.ProviderWithValue(prefix, ".", func(s string, v string) (string, any) {
-		// Strip out the prefix, lowercase and using . as key delimiter.
-		key := strings.ReplaceAll(strings.ToLower(strings.TrimPrefix(s, prefix+"_")), "_", ".")
-		// Split comma-separated values into a slice.
-		if strings.Contains(v, ",") {
-			return key, strings.Split(v, ",")
-		}
-		return key, v
-	}), nil); err != nil {
-		log.Fatalf("Error loading environment variables: %v", err)
+	envConfig, err := loadEnv(os.Environ(), config.sources)
+	if err != nil {
+		return nil, fmt.Errorf("error loading environment variables: %w", err)
+	}
+	if err := loadSecretFiles(envConfig, config.sources); err != nil {
+		return nil, err
 	}
 
-	// Override the configuration
+	// Override the configuration (environment variables take precedence)
+	k := koanf.New(".")
+	if err := k.Merge(fileConfig); err != nil {
+		return nil, fmt.Errorf("error merging config file: %w", err)
+	}
+	if err := k.Merge(envConfig); err != nil {
+		return nil, fmt.Errorf("error merging environment variables: %w", err)
+	}
+	if err := applyDeprecatedKeys(k, &config); err != nil {
+		return nil, err
+	}
 	if err := k.Unmarshal("", &config); err != nil {
-		log.Fatalf("Error unmarshaling config: %v", err)
+		return nil, fmt.Errorf("error unmarshaling config: %w", err)
 	}
 
-	// Validate the configuration
-	if err := validateConfig(&config); err != nil {
-		log.Fatalf("Invalid configuration: %v", err)
+	return &config, nil
+}
+
+// applyDeprecatedKeys copies the values of deprecated keys loaded in k to the
+// keys replacing them, unless these are also set, and records a warning for
+// each deprecated key in use.
+func applyDeprecatedKeys(k *koanf.Koanf, config *Config) error {
+	// karakeep.interval was a fixed interval, so it sets both the initial and
+	// the maximum interval.
+	if !k.Exists("karakeep.interval") {
+		return nil
+	}
+	config.deprecations = append(config.deprecations, fmt.Sprintf("karakeep.interval (from %s) is deprecated, use tagging.interval and tagging.maxinterval instead", config.Source("karakeep.interval")))
+	for _, key := range []string{"tagging.interval", "tagging.maxinterval"} {
+		if k.Exists(key) {
+			continue
+		}
+		if err := k.Set(key, k.Int("karakeep.interval")); err != nil {
+			return fmt.Errorf("error loading karakeep.interval: %w", err)
+		}
+		config.sources[key] = config.sources["karakeep.interval"]
 	}
+	return nil
+}
 
-	return &config
+// Deprecations returns a warning for each deprecated key in use.
+func (c *Config) Deprecations() []string {
+	return c.deprecations
+}
+
+// loadSecretFiles replaces the secret file keys loaded in k (e.g.
+// "telegram.token_file") with the content of the files they point to (e.g.
+// "telegram.token"), so they take precedence over the plain keys of the same
+// source.
+func loadSecretFiles(k *koanf.Koanf, sources map[string]string) error {
+	for _, key := range SecretKeys {
+		fileKey := key + SecretFileSuffix
+		if !k.Exists(fileKey) {
+			continue
+		}
+
+		path := k.String(fileKey)
+		value, err := secret.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("error loading %s (from %s): %w", fileKey, sources[fileKey], err)
+		}
+
+		if err := k.Set(key, value.Value()); err != nil {
+			return fmt.Errorf("error loading %s: %w", fileKey, err)
+		}
+		k.Delete(fileKey)
+		sources[key] = fmt.Sprintf("file %s via %s", path, sources[fileKey])
+	}
+
+	return nil
 }
 
 // parseCommandLineFlags parses the command line flags for the configuration.
-func parseCommandLineFlags(config *Config) {
-	f := flag.NewFlagSet(AppName, flag.ExitOnError)
+func parseCommandLineFlags(config *Config, args []string) error {
+	f := flag.NewFlagSet(AppName, flag.ContinueOnError)
 
 	f.StringVar(&config.Path, "config", DefaultPath, "Custom configuration file")
 	showVersion := f.Bool("version", false, "Show version information")
 	showHelp := f.Bool("help", false, "Show help information")
+	f.Usage = func() {
+		fmt.Fprintf(f.Output(), "Usage: %s [flags] [command]\n\n", AppName)
+		fmt.Fprintln(f.Output(), "Commands:")
+		fmt.Fprintln(f.Output(), "  validate                              Validate the configuration and print every problem found")
+		fmt.Fprintln(f.Output(), "  doctor                                Check connectivity with Karakeep and Telegram, and the environment")
+		fmt.Fprintln(f.Output(), "  send-test [flags] <chat-id>           Send a test message to verify the bot permissions in a chat")
+		fmt.Fprintln(f.Output(), "  import telegram-export [flags] <dir>  Import a Telegram Desktop chat export")
+		fmt.Fprintln(f.Output(), "\nWithout a command, the bot is started.")
+		fmt.Fprintln(f.Output(), "\nFlags:")
+		f.PrintDefaults()
+	}
 
-	if err := f.Parse(os.Args[1:]); err != nil {
-		log.Fatalf("Error parsing command line flags: %v", err)
+	if err := f.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return fmt.Errorf("error parsing command line flags: %w", err)
 	}
+	config.Args = f.Args()
 
 	if *showVersion {
 		fmt.Println(AppName, version.Get())
-		os.Exit(0)
+		return ErrVersion
 	}
 
 	if *showHelp {
 		f.Usage()
-		os.Exit(0)
+		return flag.ErrHelp
 	}
+
+	return nil
 }
 
-// validateConfig checks the validity of the configuration.
-func validateConfig(config *Config) error {
-	if err := config.Telegram.Validate(); err != nil {
-		return err
+// Validate checks the validity of the configuration. Every section is
+// validated and all the problems found are returned together as
+// [ValidationErrors], including the key path and the source of each value.
+func (c *Config) Validate() error {
+	sections := []struct {
+		name      string
+		validator interface{ Validate() error }
+	}{
+		{"telegram", c.Telegram},
+		{"karakeep", c.Karakeep},
+		{"logging", c.Logging},
+		{"fileprocessor", c.FileProcessor},
+		{"urlcleaner", c.URLCleaner},
+		{"tagging", c.Tagging},
+		{"hashtags", c.Hashtags},
+		{"lists", c.Lists},
+		{"inline", c.Inline},
+		{"webhook", c.Webhook},
+		{"ratelimit", c.RateLimit},
+		{"roles", c.Roles},
+		{"state", c.State},
+		{"chats", c.Chats},
+		{"scheduler", c.Scheduler},
+		{"digests", c.Digests},
+		{"random", c.Random},
 	}
-	if err := config.Karakeep.Validate(); err != nil {
-		return err
+
+	var errs ValidationErrors
+	for _, section := range sections {
+		err := section.validator.Validate()
+		if err == nil {
+			continue
+		}
+
+		var sectionErrs ValidationErrors
+		if !errors.As(err, &sectionErrs) {
+			errs.add(section.name, err)
+			continue
+		}
+		for _, fieldErr := range sectionErrs {
+			key := section.name + "." + fieldErr.Key
+			if strings.HasPrefix(fieldErr.Key, "[") {
+				key = section.name + fieldErr.Key // List items, e.g. "chats[0].id"
+			}
+			errs = append(errs, &FieldError{Key: key, Source: c.Source(key), Err: fieldErr.Err})
+		}
 	}
-	if err := config.Logging.Validate(); err != nil {
-		return err
+
+	return errs.err()
+}
+
+// Source returns the source that supplied the value at key: an environment
+// variable, the configuration file or the default configuration.
+func (c *Config) Source(key string) string {
+	// Lists are loaded as a whole, e.g. "chats[0].id" comes from "chats".
+	if i := strings.Index(key, "["); i >= 0 {
+		key = key[:i]
 	}
-	if err := config.FileProcessor.Validate(); err != nil {
-		return err
+	if source, ok := c.sources[key]; ok {
+		return source
 	}
-	return nil
+	return "default"
 }
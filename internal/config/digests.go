@@ -0,0 +1,53 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/Madh93/karakeepbot/internal/cron"
+)
+
+// Defaults and limits of the digest settings.
+const (
+	DefaultDigestDays  = 7  // Days of bookmarks included when unset.
+	DefaultDigestMax   = 10 // Bookmarks included when unset.
+	maxDigestBookmarks = 20 // Keeps digests within the length of a Telegram message.
+)
+
+// DigestConfig represents a digest of the bookmarks saved but not archived
+// yet, posted to a chat (or a thread of a chat) on a schedule.
+type DigestConfig struct {
+	Chat   int64  `koanf:"chat"`   // Chat ID.
+	Thread *int   `koanf:"thread"` // Thread ID. If unset, the digest is posted to the chat.
+	Cron   string `koanf:"cron"`   // Cron expression of when to post the digest (e.g. "0 9 * * MON").
+	Days   int    `koanf:"days"`   // Include the bookmarks saved in the past days. Defaults to 7.
+	List   string `koanf:"list"`   // Only include the bookmarks in this Karakeep list.
+	Tag    string `koanf:"tag"`    // Only include the bookmarks with this tag.
+	Max    int    `koanf:"max"`    // Maximum number of bookmarks, up to 20. Defaults to 10.
+}
+
+// DigestsConfig represents the list of scheduled digests.
+type DigestsConfig []DigestConfig
+
+// Validate checks if the digests are valid.
+func (c DigestsConfig) Validate() error {
+	var errs ValidationErrors
+
+	for i, digest := range c {
+		key := fmt.Sprintf("[%d].", i)
+
+		if digest.Chat == 0 {
+			errs.addf(key+"chat", "invalid chat: must be set")
+		}
+		if _, err := cron.Parse(digest.Cron); err != nil {
+			errs.addf(key+"cron", "%v", err)
+		}
+		if digest.Days < 0 {
+			errs.addf(key+"days", "invalid days: must not be negative, got %d", digest.Days)
+		}
+		if digest.Max < 0 || digest.Max > maxDigestBookmarks {
+			errs.addf(key+"max", "invalid max: must be between 0 and %d, got %d", maxDigestBookmarks, digest.Max)
+		}
+	}
+
+	return errs.err()
+}
@@ -0,0 +1,33 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestDigestsConfigValidate(t *testing.T) {
+	thread := 42
+
+	tests := []struct {
+		name     string
+		config   DigestsConfig
+		expected bool
+	}{
+		{"No digests", nil, true},
+		{"Valid digest", DigestsConfig{{Chat: -100, Cron: "0 9 * * MON"}}, true},
+		{"Valid filtered digest", DigestsConfig{{Chat: -100, Thread: &thread, Cron: "@daily", Days: 1, List: "Reading", Tag: "golang", Max: 20}}, true},
+		{"Missing chat", DigestsConfig{{Cron: "@daily"}}, false},
+		{"Missing cron", DigestsConfig{{Chat: -100}}, false},
+		{"Invalid cron", DigestsConfig{{Chat: -100, Cron: "0 25 * * *"}}, false},
+		{"Negative days", DigestsConfig{{Chat: -100, Cron: "@daily", Days: -1}}, false},
+		{"Too many bookmarks", DigestsConfig{{Chat: -100, Cron: "@daily", Max: 21}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if (err == nil) != tt.expected {
+				t.Errorf("For config %+v, expected valid: %v, but got error: %v", tt.config, tt.expected, err)
+			}
+		})
+	}
+}
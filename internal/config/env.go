@@ -0,0 +1,196 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/knadh/koanf/v2"
+)
+
+// EnvPrefix is the prefix of the environment variables used to override the
+// configuration.
+var EnvPrefix = strings.ToUpper(AppName) + "_"
+
+// envNestingDelimiter separates nested keys in environment variable names. A
+// single underscore is also accepted, as long as the name is unambiguous.
+const envNestingDelimiter = "__"
+
+// envVar describes a configuration key that can be set with an environment
+// variable.
+type envVar struct {
+	key string       // Koanf key path (e.g. "telegram.proxyenabled").
+	typ reflect.Type // Type of the configuration value.
+}
+
+// envSchema maps every valid environment variable name to its configuration
+// key. It is derived from the koanf tags of Config.
+var envSchema = buildEnvSchema()
+
+// buildEnvSchema returns the environment variables accepted for every
+// configuration key, in both nested (KARAKEEPBOT_TELEGRAM__PROXYURL) and flat
+// (KARAKEEPBOT_TELEGRAM_PROXYURL) forms.
+func buildEnvSchema() map[string]envVar {
+	schema := make(map[string]envVar)
+	add := func(key string, typ reflect.Type) {
+		for _, delimiter := range []string{envNestingDelimiter, "_"} {
+			name := EnvPrefix + strings.ToUpper(strings.ReplaceAll(key, ".", delimiter))
+			schema[name] = envVar{key: key, typ: typ}
+		}
+	}
+
+	// Only sections can be set, not top-level values like the config path.
+	configType := reflect.TypeOf(Config{})
+	for i := range configType.NumField() {
+		section := configType.Field(i)
+		tag := section.Tag.Get("koanf")
+		if section.Type.Kind() != reflect.Struct || tag == "" || tag == "-" {
+			continue
+		}
+		for j := range section.Type.NumField() {
+			field := section.Type.Field(j)
+			fieldTag := field.Tag.Get("koanf")
+			if fieldTag == "" || fieldTag == "-" || !isEnvType(field.Type) {
+				continue
+			}
+			add(tag+"."+fieldTag, field.Type)
+		}
+	}
+
+	for _, key := range SecretKeys {
+		add(key+SecretFileSuffix, reflect.TypeOf(""))
+	}
+
+	return schema
+}
+
+// isEnvType reports whether values of type t can be parsed from an environment
+// variable.
+func isEnvType(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.String, reflect.Bool, reflect.Int, reflect.Int64:
+		return true
+	case reflect.Slice:
+		return isEnvType(t.Elem()) && t.Elem().Kind() != reflect.Slice
+	default:
+		return false
+	}
+}
+
+// loadEnv loads the configuration values from the environment variables with
+// the KARAKEEPBOT_ prefix found in environ (as returned by [os.Environ]),
+// recording the variable that supplied each key in sources. Unknown variables
+// and values of the wrong type are rejected.
+func loadEnv(environ []string, sources map[string]string) (*koanf.Koanf, error) {
+	k := koanf.New(".")
+
+	var errs []error
+	for _, entry := range environ {
+		name, value, _ := strings.Cut(entry, "=")
+		if !strings.HasPrefix(name, EnvPrefix) {
+			continue
+		}
+
+		v, ok := envSchema[name]
+		if !ok {
+			err := fmt.Errorf("unknown environment variable %s", name)
+			if suggestion := suggestEnv(name); suggestion != "" {
+				err = fmt.Errorf("%w, did you mean %s?", err, suggestion)
+			}
+			errs = append(errs, err)
+			continue
+		}
+
+		parsed, err := parseEnvValue(value, v.typ)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid value for %s: %w", name, err))
+			continue
+		}
+
+		if err := k.Set(v.key, parsed); err != nil {
+			errs = append(errs, fmt.Errorf("error loading %s: %w", name, err))
+			continue
+		}
+		sources[v.key] = "env " + name
+	}
+
+	return k, errors.Join(errs...)
+}
+
+// parseEnvValue parses value according to the configuration type t. Slices are
+// comma-separated, and an empty value means an empty slice.
+func parseEnvValue(value string, t reflect.Type) (any, error) {
+	switch t.Kind() {
+	case reflect.Bool:
+		b, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a boolean", value)
+		}
+		return b, nil
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not an integer", value)
+		}
+		return n, nil
+	case reflect.Slice:
+		items := []any{}
+		if strings.TrimSpace(value) == "" {
+			return items, nil
+		}
+		for _, item := range strings.Split(value, ",") {
+			parsed, err := parseEnvValue(strings.TrimSpace(item), t.Elem())
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, parsed)
+		}
+		return items, nil
+	default:
+		return value, nil
+	}
+}
+
+// suggestEnv returns the valid environment variable closest to name, or an
+// empty string if none is close enough.
+func suggestEnv(name string) string {
+	const maxDistance = 4
+
+	suggestion, best := "", maxDistance+1
+	for candidate := range envSchema {
+		// Suggest the flat form, which is the documented one.
+		if strings.Contains(strings.TrimPrefix(candidate, EnvPrefix), envNestingDelimiter) {
+			continue
+		}
+		if d := levenshtein(name, candidate); d < best || (d == best && candidate < suggestion) {
+			suggestion, best = candidate, d
+		}
+	}
+
+	return suggestion
+}
+
+// levenshtein returns the edit distance between a and b.
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+
+	return prev[len(b)]
+}
@@ -0,0 +1,90 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadEnv(t *testing.T) {
+	tests := []struct {
+		name     string
+		environ  []string
+		key      string
+		expected any
+	}{
+		{"Multi-word key", []string{"KARAKEEPBOT_TELEGRAM_PROXYENABLED=true"}, "telegram.proxyenabled", true},
+		{"Nested key", []string{"KARAKEEPBOT_TELEGRAM__PROXYURL=socks5://127.0.0.1:1080"}, "telegram.proxyurl", "socks5://127.0.0.1:1080"},
+		{"Key with underscore", []string{"KARAKEEPBOT_TELEGRAM__TOKEN_FILE=/run/secrets/token"}, "telegram.token_file", "/run/secrets/token"},
+		{"Single element list", []string{"KARAKEEPBOT_TELEGRAM_ALLOWLIST=123"}, "telegram.allowlist", []any{int64(123)}},
+		{"Multiple element list", []string{"KARAKEEPBOT_TELEGRAM_THREADS=1, 2"}, "telegram.threads", []any{int64(1), int64(2)}},
+		{"Empty list", []string{"KARAKEEPBOT_TELEGRAM_ALLOWLIST="}, "telegram.allowlist", []any{}},
+		{"String list", []string{"KARAKEEPBOT_FILEPROCESSOR_MIMETYPES=image/png,image/jpeg"}, "fileprocessor.mimetypes", []any{"image/png", "image/jpeg"}},
+		{"Integer", []string{"KARAKEEPBOT_FILEPROCESSOR_MAXSIZE=1024"}, "fileprocessor.maxsize", int64(1024)},
+		{"Other variables are ignored", []string{"HOME=/root", "KARAKEEPBOT_LOGGING_LEVEL=debug"}, "logging.level", "debug"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sources := make(map[string]string)
+			k, err := loadEnv(tt.environ, sources)
+			if err != nil {
+				t.Fatalf("loadEnv() returned an unexpected error: %v", err)
+			}
+			if got := k.Get(tt.key); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %#v for key %q, got %#v", tt.expected, tt.key, got)
+			}
+			if !strings.HasPrefix(sources[tt.key], "env KARAKEEPBOT_") {
+				t.Errorf("expected key %q to be sourced from an environment variable, got %q", tt.key, sources[tt.key])
+			}
+		})
+	}
+}
+
+func TestLoadEnvErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		environ  []string
+		expected string
+	}{
+		{"Unknown variable with suggestion", []string{"KARAKEEPBOT_TELEGRAM_PROXY_URL=x"}, "did you mean KARAKEEPBOT_TELEGRAM_PROXYURL?"},
+		{"Unknown variable", []string{"KARAKEEPBOT_SOMETHING_COMPLETELY_DIFFERENT=x"}, "unknown environment variable KARAKEEPBOT_SOMETHING_COMPLETELY_DIFFERENT"},
+		{"Top-level value", []string{"KARAKEEPBOT_PATH=x"}, "unknown environment variable KARAKEEPBOT_PATH"},
+		{"Invalid boolean", []string{"KARAKEEPBOT_TELEGRAM_PROXYENABLED=maybe"}, "invalid value for KARAKEEPBOT_TELEGRAM_PROXYENABLED"},
+		{"Invalid list element", []string{"KARAKEEPBOT_TELEGRAM_ALLOWLIST=1,abc"}, `"abc" is not an integer`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadEnv(tt.environ, make(map[string]string))
+			if err == nil {
+				t.Fatal("expected an error, got nil")
+			}
+			if !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("expected error to contain %q, got: %v", tt.expected, err)
+			}
+		})
+	}
+
+	t.Run("All errors are reported", func(t *testing.T) {
+		_, err := loadEnv([]string{"KARAKEEPBOT_FOO=x", "KARAKEEPBOT_BAR=y"}, make(map[string]string))
+		if err == nil || !strings.Contains(err.Error(), "KARAKEEPBOT_FOO") || !strings.Contains(err.Error(), "KARAKEEPBOT_BAR") {
+			t.Errorf("expected both variables to be reported, got: %v", err)
+		}
+	})
+}
+
+func TestNewDoesNotModifyDefaults(t *testing.T) {
+	t.Setenv("KARAKEEPBOT_TELEGRAM_ALLOWLIST", "42")
+
+	config, err := New(nil)
+	if err != nil {
+		t.Fatalf("New() returned an unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(config.Telegram.Allowlist, []int64{42}) {
+		t.Errorf("expected allowlist [42], got %v", config.Telegram.Allowlist)
+	}
+	if !reflect.DeepEqual(DefaultConfig.Telegram.Allowlist, []int64{-1}) {
+		t.Errorf("expected default allowlist to be kept, got %v", DefaultConfig.Telegram.Allowlist)
+	}
+}
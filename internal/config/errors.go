@@ -0,0 +1,73 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrVersion is returned by New when the version information was requested
+// and printed.
+var ErrVersion = errors.New("version requested")
+
+// FieldError represents a problem with a single configuration value.
+type FieldError struct {
+	Key    string // Koanf key path of the value (e.g. "telegram.proxyurl").
+	Source string // Source that supplied the value (e.g. "env KARAKEEPBOT_TELEGRAM_PROXYURL").
+	Err    error  // Underlying validation error.
+}
+
+// Error returns the error message, including the key and the source of the
+// value.
+func (e *FieldError) Error() string {
+	if e.Source == "" {
+		return fmt.Sprintf("%s: %v", e.Key, e.Err)
+	}
+	return fmt.Sprintf("%s: %v (from %s)", e.Key, e.Err, e.Source)
+}
+
+// Unwrap returns the underlying validation error.
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationErrors represents all the problems found while validating a
+// configuration.
+type ValidationErrors []*FieldError
+
+// Error returns the messages of all the errors, one per line.
+func (e ValidationErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "\n")
+}
+
+// Unwrap returns the individual errors, so they can be inspected with
+// [errors.Is] and [errors.As].
+func (e ValidationErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, err := range e {
+		errs[i] = err
+	}
+	return errs
+}
+
+// add records a problem with the value at key.
+func (e *ValidationErrors) add(key string, err error) {
+	*e = append(*e, &FieldError{Key: key, Err: err})
+}
+
+// addf records a problem with the value at key using a formatted message.
+func (e *ValidationErrors) addf(key, format string, args ...any) {
+	e.add(key, fmt.Errorf(format, args...))
+}
+
+// err returns the errors as an error, or nil if there are none.
+func (e ValidationErrors) err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
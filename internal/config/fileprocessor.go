@@ -1,8 +1,6 @@
 package config
 
 import (
-	"errors"
-	"fmt"
 	"strings"
 )
 
@@ -16,25 +14,28 @@ type FileProcessorConfig struct {
 
 // Validate checks if the FileProcessor configuration is valid.
 func (c FileProcessorConfig) Validate() error {
+	var errs ValidationErrors
+
 	if c.Maxsize <= 0 {
-		return fmt.Errorf("invalid maxsize: must be a positive value, got %d", c.Maxsize)
+		errs.addf("maxsize", "invalid maxsize: must be a positive value, got %d", c.Maxsize)
 	}
 
 	if c.Timeout <= 0 {
-		return fmt.Errorf("invalid timeout: must be a positive value, got %d", c.Timeout)
+		errs.addf("timeout", "invalid timeout: must be a positive value, got %d", c.Timeout)
 	}
 
 	// Check for empty or duplicate entries in Mimetypes.
 	seen := make(map[string]bool)
 	for _, mimeType := range c.Mimetypes {
 		if strings.TrimSpace(mimeType) == "" {
-			return errors.New("invalid mimetypes: contains an empty entry")
+			errs.addf("mimetypes", "invalid mimetypes: contains an empty entry")
+			continue
 		}
 		if seen[mimeType] {
-			return fmt.Errorf("invalid mimetypes: contains duplicate entry '%s'", mimeType)
+			errs.addf("mimetypes", "invalid mimetypes: contains duplicate entry '%s'", mimeType)
 		}
 		seen[mimeType] = true
 	}
 
-	return nil
+	return errs.err()
 }
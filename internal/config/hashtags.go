@@ -0,0 +1,95 @@
+package config
+
+import (
+	"slices"
+	"strings"
+)
+
+// Hashtag case styles, which define how the words of a tag are joined into a
+// hashtag.
+const (
+	HashtagCaseKeep   = "keep"   // Join the words as they are: "Machine Learning" -> #MachineLearning.
+	HashtagCaseLower  = "lower"  // Join the words in lowercase: #machinelearning.
+	HashtagCaseCamel  = "camel"  // Join the words in camelCase: #machineLearning.
+	HashtagCasePascal = "pascal" // Join the words in PascalCase: #MachineLearning.
+	HashtagCaseSnake  = "snake"  // Join the words in lowercase with underscores: #machine_learning.
+)
+
+// validHashtagCases are the allowed hashtag case styles.
+var validHashtagCases = []string{HashtagCaseKeep, HashtagCaseLower, HashtagCaseCamel, HashtagCasePascal, HashtagCaseSnake}
+
+// AI tags modes, which define how the tags attached by Karakeep's AI are shown
+// next to the tags attached by the user.
+const (
+	AITagsSeparate = "separate" // Show AI tags on their own line, after a marker.
+	AITagsInline   = "inline"   // Show AI tags next to the user tags, without distinction.
+	AITagsHide     = "hide"     // Don't show AI tags.
+)
+
+// validAITags are the allowed AI tags modes.
+var validAITags = []string{AITagsSeparate, AITagsInline, AITagsHide}
+
+// HashtagsConfig represents a configuration for converting Karakeep tags into
+// Telegram hashtags and back.
+type HashtagsConfig struct {
+	Case          string         `koanf:"case"`          // How to join the words of a tag: "keep", "lower", "camel", "pascal" or "snake".
+	Transliterate bool           `koanf:"transliterate"` // Whether to replace accented Latin letters with their ASCII equivalent (e.g. "é" -> "e").
+	Aliases       []HashtagAlias `koanf:"aliases"`       // Synonyms replaced with a canonical tag.
+	Blocklist     []string       `koanf:"blocklist"`     // Tags never sent to Karakeep nor shown as hashtags.
+	Max           int            `koanf:"max"`           // Maximum number of hashtags in the reply. 0 means no limit.
+	AITags        string         `koanf:"aitags"`        // How to show AI tags: "separate", "inline" or "hide".
+	MaxAI         int            `koanf:"maxai"`         // Maximum number of AI hashtags in the reply. 0 means no limit.
+}
+
+// HashtagAlias represents a canonical tag and its synonyms. Tags are matched
+// ignoring case, accents and separators, so "Machine Learning",
+// "machine-learning" and #MachineLearning are the same tag.
+type HashtagAlias struct {
+	Tag      string   `koanf:"tag"`      // Canonical tag, as named in Karakeep.
+	Synonyms []string `koanf:"synonyms"` // Tags replaced with the canonical one.
+}
+
+// Validate checks if the hashtags configuration is valid.
+func (c HashtagsConfig) Validate() error {
+	var errs ValidationErrors
+
+	if !slices.Contains(validHashtagCases, c.Case) {
+		errs.addf("case", "invalid case %q: must be one of %v", c.Case, validHashtagCases)
+	}
+
+	for i, alias := range c.Aliases {
+		if strings.TrimSpace(alias.Tag) == "" {
+			errs.addf("aliases", "invalid alias #%d: tag must not be empty", i+1)
+		}
+		if len(alias.Synonyms) == 0 {
+			errs.addf("aliases", "invalid alias #%d: synonyms must not be empty", i+1)
+		}
+		for _, synonym := range alias.Synonyms {
+			if strings.TrimSpace(synonym) == "" {
+				errs.addf("aliases", "invalid alias #%d: synonyms contain an empty entry", i+1)
+				break
+			}
+		}
+	}
+
+	for _, tag := range c.Blocklist {
+		if strings.TrimSpace(tag) == "" {
+			errs.addf("blocklist", "invalid blocklist: contains an empty entry")
+			break
+		}
+	}
+
+	if c.Max < 0 {
+		errs.addf("max", "invalid max: must not be negative, got %d", c.Max)
+	}
+
+	if !slices.Contains(validAITags, c.AITags) {
+		errs.addf("aitags", "invalid aitags %q: must be one of %v", c.AITags, validAITags)
+	}
+
+	if c.MaxAI < 0 {
+		errs.addf("maxai", "invalid maxai: must not be negative, got %d", c.MaxAI)
+	}
+
+	return errs.err()
+}
@@ -0,0 +1,34 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestHashtagsConfigValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   HashtagsConfig
+		expected bool
+	}{
+		{"Valid config", HashtagsConfig{Case: HashtagCaseKeep, AITags: AITagsSeparate}, true},
+		{"Valid config with rules", HashtagsConfig{Case: HashtagCaseSnake, AITags: AITagsHide, MaxAI: 3, Transliterate: true, Aliases: []HashtagAlias{{Tag: "Machine Learning", Synonyms: []string{"ml", "ai"}}}, Blocklist: []string{"misc"}, Max: 5}, true},
+		{"Invalid case", HashtagsConfig{Case: "kebab", AITags: AITagsSeparate}, false},
+		{"Empty case", HashtagsConfig{}, false},
+		{"Alias without tag", HashtagsConfig{Case: HashtagCaseKeep, AITags: AITagsSeparate, Aliases: []HashtagAlias{{Synonyms: []string{"ml"}}}}, false},
+		{"Alias without synonyms", HashtagsConfig{Case: HashtagCaseKeep, AITags: AITagsSeparate, Aliases: []HashtagAlias{{Tag: "Machine Learning"}}}, false},
+		{"Alias with an empty synonym", HashtagsConfig{Case: HashtagCaseKeep, AITags: AITagsSeparate, Aliases: []HashtagAlias{{Tag: "Machine Learning", Synonyms: []string{" "}}}}, false},
+		{"Empty blocklist entry", HashtagsConfig{Case: HashtagCaseKeep, AITags: AITagsSeparate, Blocklist: []string{""}}, false},
+		{"Negative max", HashtagsConfig{Case: HashtagCaseKeep, AITags: AITagsSeparate, Max: -1}, false},
+		{"Invalid aitags", HashtagsConfig{Case: HashtagCaseKeep, AITags: "bottom"}, false},
+		{"Negative maxai", HashtagsConfig{Case: HashtagCaseKeep, AITags: AITagsInline, MaxAI: -1}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if (err == nil) != tt.expected {
+				t.Errorf("For config %+v, expected valid: %v, but got error: %v", tt.config, tt.expected, err)
+			}
+		})
+	}
+}
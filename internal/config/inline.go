@@ -0,0 +1,28 @@
+package config
+
+// maxInlineResults is the maximum number of results Telegram accepts in an
+// answer to an inline query.
+const maxInlineResults = 50
+
+// InlineConfig represents a configuration for searching bookmarks with inline
+// queries (e.g. "@karakeepbot golang") from any chat.
+type InlineConfig struct {
+	Enabled bool `koanf:"enabled"` // Whether to answer inline queries. Inline mode must also be enabled with @BotFather.
+	Results int  `koanf:"results"` // Number of results per page, up to 50.
+	Cache   int  `koanf:"cache"`   // Time (in seconds) to cache the results of a query. 0 disables caching.
+}
+
+// Validate checks if the inline configuration is valid.
+func (c InlineConfig) Validate() error {
+	var errs ValidationErrors
+
+	if c.Results < 1 || c.Results > maxInlineResults {
+		errs.addf("results", "invalid results: must be between 1 and %d, got %d", maxInlineResults, c.Results)
+	}
+
+	if c.Cache < 0 {
+		errs.addf("cache", "invalid cache: must not be negative, got %d", c.Cache)
+	}
+
+	return errs.err()
+}
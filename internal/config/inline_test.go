@@ -0,0 +1,28 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestInlineConfigValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   InlineConfig
+		expected bool
+	}{
+		{"Valid config", InlineConfig{Enabled: true, Results: 10, Cache: 30}, true},
+		{"Without cache", InlineConfig{Enabled: true, Results: 50}, true},
+		{"No results", InlineConfig{Enabled: true, Results: 0, Cache: 30}, false},
+		{"Too many results", InlineConfig{Enabled: true, Results: 51, Cache: 30}, false},
+		{"Negative cache", InlineConfig{Enabled: true, Results: 10, Cache: -1}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if (err == nil) != tt.expected {
+				t.Errorf("For config %+v, expected valid: %v, but got error: %v", tt.config, tt.expected, err)
+			}
+		})
+	}
+}
@@ -1,7 +1,12 @@
 package config
 
 import (
-	"fmt"
+	"errors"
+	"maps"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
 
 	"github.com/Madh93/karakeepbot/internal/secret"
 	"github.com/Madh93/karakeepbot/internal/validation"
@@ -9,19 +14,67 @@ import (
 
 // KarakeepConfig represents a configuration for the Karakeep server.
 type KarakeepConfig struct {
-	URL      string        `koanf:"url"`      // Base URL of the Karakeep server
-	Token    secret.String `koanf:"token"`    // Karakeep API key
-	Interval int           `koanf:"interval"` // Interval (in seconds) before retrying tagging status
+	URL                string                   `koanf:"url"`                // Base URL of the Karakeep server
+	Token              secret.String            `koanf:"token"`              // Karakeep API key
+	Interval           int                      `koanf:"interval"`           // Deprecated: use TaggingConfig.Interval. 0 means unset.
+	Retries            int                      `koanf:"retries"`            // Maximum number of retries for requests that fail with transient errors
+	Timeout            int                      `koanf:"timeout"`            // Maximum time (in seconds) to wait for each request attempt
+	CACert             string                   `koanf:"cacert"`             // Path to a PEM bundle of CAs trusted in addition to the system ones
+	ClientCert         string                   `koanf:"clientcert"`         // Path to a PEM client certificate for mutual TLS
+	ClientKey          string                   `koanf:"clientkey"`          // Path to the PEM private key of the client certificate
+	InsecureSkipVerify bool                     `koanf:"insecureskipverify"` // Whether to skip the verification of the server certificate (insecure)
+	ProxyURL           secret.String            `koanf:"proxyurl"`           // Proxy URL. If empty, the proxy is taken from the environment. It may contain credentials.
+	Headers            map[string]secret.String `koanf:"headers"`            // Headers added to every request (e.g. Cloudflare Access service tokens)
 }
 
 // Validate checks if the Karakeep configuration is valid.
 func (c KarakeepConfig) Validate() error {
+	var errs ValidationErrors
+
 	if err := validation.ValidateURL(c.URL); err != nil {
-		return fmt.Errorf("invalid URL: %w", err)
+		errs.addf("url", "invalid URL: %w", err)
 	}
 	if err := validation.ValidateKarakeepToken(c.Token); err != nil {
-		return err
+		errs.add("token", err)
+	}
+
+	if c.Interval < 0 {
+		errs.addf("interval", "invalid interval: must not be negative, got %d", c.Interval)
+	}
+
+	if c.Retries < 0 {
+		errs.addf("retries", "invalid retries: must not be negative, got %d", c.Retries)
+	}
+
+	if c.Timeout <= 0 {
+		errs.addf("timeout", "invalid timeout: must be a positive value, got %d", c.Timeout)
+	}
+
+	for _, file := range []struct{ key, path string }{{"cacert", c.CACert}, {"clientcert", c.ClientCert}, {"clientkey", c.ClientKey}} {
+		if file.path == "" {
+			continue
+		}
+		if _, err := os.Stat(file.path); err != nil {
+			errs.addf(file.key, "invalid %s: %w", file.key, err)
+		}
+	}
+
+	if (c.ClientCert == "") != (c.ClientKey == "") {
+		errs.add("clientkey", errors.New("clientcert and clientkey must be set together"))
+	}
+
+	if c.ProxyURL != "" {
+		// Don't include the parsing error, as it contains the full URL.
+		if proxyURL, err := url.Parse(c.ProxyURL.Value()); err != nil || proxyURL.Scheme == "" || proxyURL.Host == "" {
+			errs.addf("proxyurl", "invalid proxy URL %s", c.ProxyURL)
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(c.Headers)) {
+		if strings.TrimSpace(name) == "" || strings.ContainsAny(name, " :\t\r\n") {
+			errs.addf("headers", "invalid header name %q", name)
+		}
 	}
 
-	return nil
+	return errs.err()
 }
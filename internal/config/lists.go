@@ -0,0 +1,26 @@
+package config
+
+import (
+	"strings"
+	"unicode"
+)
+
+// ListsConfig represents a configuration for adding bookmarks to Karakeep
+// lists from Telegram.
+type ListsConfig struct {
+	Prefix string `koanf:"prefix"` // Prefix of the list name at the start of a message (e.g. "@" for "@reading"). Empty disables it.
+}
+
+// Validate checks if the lists configuration is valid.
+func (c ListsConfig) Validate() error {
+	var errs ValidationErrors
+
+	switch {
+	case strings.ContainsFunc(c.Prefix, unicode.IsSpace):
+		errs.addf("prefix", "invalid prefix %q: must not contain spaces", c.Prefix)
+	case strings.HasPrefix(c.Prefix, "#") || strings.HasPrefix(c.Prefix, "/"):
+		errs.addf("prefix", "invalid prefix %q: must not start with '#' or '/', used by hashtags and commands", c.Prefix)
+	}
+
+	return errs.err()
+}
@@ -0,0 +1,29 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestListsConfigValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   ListsConfig
+		expected bool
+	}{
+		{"Valid config", ListsConfig{Prefix: "@"}, true},
+		{"Longer prefix", ListsConfig{Prefix: "list:"}, true},
+		{"Disabled", ListsConfig{}, true},
+		{"Prefix with spaces", ListsConfig{Prefix: "@ "}, false},
+		{"Hashtag prefix", ListsConfig{Prefix: "#"}, false},
+		{"Command prefix", ListsConfig{Prefix: "/list"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if (err == nil) != tt.expected {
+				t.Errorf("For config %+v, expected valid: %v, but got error: %v", tt.config, tt.expected, err)
+			}
+		})
+	}
+}
@@ -1,8 +1,6 @@
 package config
 
 import (
-	"fmt"
-
 	"github.com/Madh93/karakeepbot/internal/validation"
 )
 
@@ -43,18 +41,20 @@ var validLogging = map[string][]string{
 
 // Validate checks if the logging configuration is valid.
 func (c LoggingConfig) Validate() error {
+	var errs ValidationErrors
+
 	if err := validation.Validate(c.Level, validLogging["Level"]); err != nil {
-		return fmt.Errorf("invalid log level: %w", err)
+		errs.addf("level", "invalid log level: %w", err)
 	}
 	if err := validation.Validate(c.Format, validLogging["Format"]); err != nil {
-		return fmt.Errorf("invalid log format: %w", err)
+		errs.addf("format", "invalid log format: %w", err)
 	}
 	if err := validation.Validate(c.Output, validLogging["Output"]); err != nil {
-		return fmt.Errorf("invalid output destination: %w", err)
+		errs.addf("output", "invalid output destination: %w", err)
 	}
 	if err := validation.Validate(c.Colored, []bool{true, false}); err != nil {
-		return fmt.Errorf("invalid colored setting: %w", err)
+		errs.addf("colored", "invalid colored setting: %w", err)
 	}
 
-	return nil
+	return errs.err()
 }
@@ -0,0 +1,49 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/Madh93/karakeepbot/internal/cron"
+)
+
+// RandomConfig represents the settings of the random bookmarks, posted with
+// the /random command or on a schedule.
+type RandomConfig struct {
+	Window int                `koanf:"window"` // Days before a bookmark can be posted again in the same chat. Zero allows repeats.
+	Posts  []RandomPostConfig `koanf:"posts"`  // Random bookmarks posted on a schedule.
+}
+
+// RandomPostConfig represents a random bookmark posted to a chat (or a thread
+// of a chat) on a schedule.
+type RandomPostConfig struct {
+	Chat   int64  `koanf:"chat"`   // Chat ID.
+	Thread *int   `koanf:"thread"` // Thread ID. If unset, the bookmark is posted to the chat.
+	Cron   string `koanf:"cron"`   // Cron expression of when to post the bookmark (e.g. "0 18 * * FRI").
+	List   string `koanf:"list"`   // Only pick bookmarks in this Karakeep list.
+	Tag    string `koanf:"tag"`    // Only pick bookmarks with this tag.
+}
+
+// Validate checks if the random bookmarks configuration is valid.
+func (c RandomConfig) Validate() error {
+	var errs ValidationErrors
+
+	if c.Window < 0 {
+		errs.addf("window", "invalid window: must not be negative, got %d", c.Window)
+	}
+
+	for i, post := range c.Posts {
+		key := fmt.Sprintf("posts[%d].", i)
+
+		if post.Chat == 0 {
+			errs.addf(key+"chat", "invalid chat: must be set")
+		}
+		if _, err := cron.Parse(post.Cron); err != nil {
+			errs.addf(key+"cron", "%v", err)
+		}
+		if post.List != "" && post.Tag != "" {
+			errs.addf(key+"tag", "invalid tag: can't be set together with list")
+		}
+	}
+
+	return errs.err()
+}
@@ -0,0 +1,33 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestRandomConfigValidate(t *testing.T) {
+	thread := 42
+
+	tests := []struct {
+		name     string
+		config   RandomConfig
+		expected bool
+	}{
+		{"Default", RandomConfig{Window: 30}, true},
+		{"Repeats allowed", RandomConfig{}, true},
+		{"Valid post", RandomConfig{Posts: []RandomPostConfig{{Chat: -100, Cron: "0 18 * * FRI"}}}, true},
+		{"Valid filtered post", RandomConfig{Posts: []RandomPostConfig{{Chat: -100, Thread: &thread, Cron: "@daily", Tag: "golang"}}}, true},
+		{"Negative window", RandomConfig{Window: -1}, false},
+		{"Missing chat", RandomConfig{Posts: []RandomPostConfig{{Cron: "@daily"}}}, false},
+		{"Invalid cron", RandomConfig{Posts: []RandomPostConfig{{Chat: -100, Cron: "* * *"}}}, false},
+		{"List and tag", RandomConfig{Posts: []RandomPostConfig{{Chat: -100, Cron: "@daily", List: "Reading", Tag: "golang"}}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if (err == nil) != tt.expected {
+				t.Errorf("For config %+v, expected valid: %v, but got error: %v", tt.config, tt.expected, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package config
+
+import (
+	"slices"
+	"strings"
+)
+
+// RateLimitConfig represents a configuration for rate limiting incoming saves
+// and Karakeep crawls.
+type RateLimitConfig struct {
+	Enabled   bool `koanf:"enabled"`   // Whether to rate limit incoming saves.
+	UserRate  int  `koanf:"userrate"`  // Saves allowed per minute for each user.
+	UserBurst int  `koanf:"userburst"` // Saves allowed in a burst for each user.
+	ChatRate  int  `koanf:"chatrate"`  // Saves allowed per minute for each chat.
+	ChatBurst int  `koanf:"chatburst"` // Saves allowed in a burst for each chat.
+	Crawls    int  `koanf:"crawls"`    // Maximum number of bookmarks processed by Karakeep at once. 0 means unlimited.
+}
+
+// Validate checks if the rate limit configuration is valid.
+func (c RateLimitConfig) Validate() error {
+	var errs ValidationErrors
+
+	if c.Enabled {
+		for key, value := range map[string]int{"userrate": c.UserRate, "userburst": c.UserBurst, "chatrate": c.ChatRate, "chatburst": c.ChatBurst} {
+			if value <= 0 {
+				errs.addf(key, "invalid %s: must be a positive value, got %d", key, value)
+			}
+		}
+	}
+
+	if c.Crawls < 0 {
+		errs.addf("crawls", "invalid crawls: must not be negative, got %d", c.Crawls)
+	}
+
+	slices.SortFunc(errs, func(a, b *FieldError) int { return strings.Compare(a.Key, b.Key) })
+
+	return errs.err()
+}
@@ -0,0 +1,28 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestRateLimitConfigValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   RateLimitConfig
+		expected bool
+	}{
+		{"Valid config", RateLimitConfig{Enabled: true, UserRate: 20, UserBurst: 10, ChatRate: 60, ChatBurst: 30, Crawls: 4}, true},
+		{"Disabled config ignores rates", RateLimitConfig{Enabled: false}, true},
+		{"Invalid rate", RateLimitConfig{Enabled: true, UserRate: 0, UserBurst: 10, ChatRate: 60, ChatBurst: 30}, false},
+		{"Invalid burst", RateLimitConfig{Enabled: true, UserRate: 20, UserBurst: 10, ChatRate: 60, ChatBurst: -1}, false},
+		{"Negative crawls", RateLimitConfig{Crawls: -1}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if (err == nil) != tt.expected {
+				t.Errorf("For config %+v, expected valid: %v, but got error: %v", tt.config, tt.expected, err)
+			}
+		})
+	}
+}
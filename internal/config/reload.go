@@ -0,0 +1,86 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+
+	"github.com/knadh/koanf/providers/file"
+)
+
+// Change represents a configuration value that changed between two
+// configurations. Secret values are masked.
+type Change struct {
+	Key string // Koanf key path of the value (e.g. "telegram.allowlist").
+	Old string // Previous value.
+	New string // Current value.
+}
+
+// Reload loads the configuration again from the same command line arguments,
+// configuration file and environment variables. The returned configuration is
+// not validated.
+func (c *Config) Reload() (*Config, error) {
+	return New(c.args)
+}
+
+// Watch watches the configuration file and calls cb every time it changes, or
+// with an error if it can't be watched anymore. The returned function stops
+// watching the file.
+func (c *Config) Watch(cb func(err error)) (stop func(), err error) {
+	if c.Path == "" {
+		return nil, errors.New("no configuration file loaded")
+	}
+
+	provider := file.Provider(c.Path)
+	if err := provider.Watch(func(_ any, err error) { cb(err) }); err != nil {
+		return nil, fmt.Errorf("error watching config file: %w", err)
+	}
+
+	return func() { _ = provider.Unwatch() }, nil
+}
+
+// Diff returns the values that changed from one configuration to another,
+// sorted by key.
+func Diff(from, to *Config) []Change {
+	oldValues := flatten(reflect.ValueOf(*from), "")
+	newValues := flatten(reflect.ValueOf(*to), "")
+
+	var changes []Change
+	for _, key := range slices.Sorted(maps.Keys(newValues)) {
+		oldValue, newValue := oldValues[key].Interface(), newValues[key].Interface()
+		if !reflect.DeepEqual(oldValue, newValue) {
+			// fmt uses the String method of secret.String, which masks the value.
+			changes = append(changes, Change{Key: key, Old: fmt.Sprintf("%v", oldValue), New: fmt.Sprintf("%v", newValue)})
+		}
+	}
+
+	return changes
+}
+
+// flatten returns every value in v (a struct) indexed by its koanf key path,
+// skipping ignored and unexported fields.
+func flatten(v reflect.Value, prefix string) map[string]reflect.Value {
+	values := make(map[string]reflect.Value)
+
+	for i := range v.NumField() {
+		field := v.Type().Field(i)
+		tag := field.Tag.Get("koanf")
+		if !field.IsExported() || tag == "" || tag == "-" {
+			continue
+		}
+
+		key := prefix + tag
+		if field.Type.Kind() == reflect.Struct {
+			for k, value := range flatten(v.Field(i), key+".") {
+				values[k] = value
+			}
+			continue
+		}
+
+		values[key] = v.Field(i)
+	}
+
+	return values
+}
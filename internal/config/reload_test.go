@@ -0,0 +1,62 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	old := DefaultConfig
+	old.Telegram.Token = "123456789:first"
+	updated := DefaultConfig
+	updated.Telegram.Token = "123456789:second"
+	updated.Telegram.Allowlist = []int64{1, 2}
+	updated.Logging.Level = "debug"
+
+	changes := Diff(&old, &updated)
+	expected := []Change{
+		{Key: "logging.level", Old: "info", New: "debug"},
+		{Key: "telegram.allowlist", Old: "[-1]", New: "[1 2]"},
+		{Key: "telegram.token", Old: "1234********", New: "1234********"},
+	}
+	if len(changes) != len(expected) {
+		t.Fatalf("expected %d changes, got %d: %+v", len(expected), len(changes), changes)
+	}
+	for i, change := range changes {
+		if change != expected[i] {
+			t.Errorf("expected change %+v, got %+v", expected[i], change)
+		}
+	}
+
+	if changes := Diff(&old, &old); len(changes) != 0 {
+		t.Errorf("expected no changes for the same configuration, got %+v", changes)
+	}
+}
+
+func TestConfigReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(path, []byte("[logging]\nlevel = \"info\"\n"), 0600); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+
+	config, err := New([]string{"-config", path})
+	if err != nil {
+		t.Fatalf("New() returned an unexpected error: %v", err)
+	}
+
+	if err := os.WriteFile(path, []byte("[logging]\nlevel = \"debug\"\n"), 0600); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+
+	reloaded, err := config.Reload()
+	if err != nil {
+		t.Fatalf("Reload() returned an unexpected error: %v", err)
+	}
+	if reloaded.Logging.Level != "debug" {
+		t.Errorf("expected reloaded level %q, got %q", "debug", reloaded.Logging.Level)
+	}
+	if reloaded.Path != path {
+		t.Errorf("expected reloaded path %q, got %q", path, reloaded.Path)
+	}
+}
@@ -0,0 +1,50 @@
+package config
+
+import (
+	"slices"
+	"strings"
+)
+
+// Policies for users without a role.
+const (
+	IgnoreUnknownPolicy = "ignore" // Ignore their messages.
+	ReplyUnknownPolicy  = "reply"  // Reply with a configurable message.
+)
+
+// validRoles maps each roles field to a list of allowed values.
+var validRoles = map[string][]string{
+	"Unknown": {IgnoreUnknownPolicy, ReplyUnknownPolicy},
+}
+
+// RolesConfig represents the roles assigned to Telegram user IDs. When no role
+// is assigned (neither here nor with the /allow command), every user in an
+// allowed chat can save bookmarks.
+type RolesConfig struct {
+	Admins       []int64 `koanf:"admins"`       // Users that can run management commands.
+	Contributors []int64 `koanf:"contributors"` // Users that can save bookmarks.
+	Readers      []int64 `koanf:"readers"`      // Users that can only search bookmarks.
+	Unknown      string  `koanf:"unknown"`      // What to do with users without a role: "ignore" or "reply".
+	Reply        string  `koanf:"reply"`        // Reply sent to users without a role (if unknown is "reply").
+}
+
+// Validate checks if the roles configuration is valid.
+func (c RolesConfig) Validate() error {
+	var errs ValidationErrors
+
+	for key, ids := range map[string][]int64{"admins": c.Admins, "contributors": c.Contributors, "readers": c.Readers} {
+		if slices.Contains(ids, 0) {
+			errs.addf(key, "invalid %s: contains an empty user ID", key)
+		}
+	}
+
+	if !slices.Contains(validRoles["Unknown"], c.Unknown) {
+		errs.addf("unknown", "invalid unknown users policy %q: must be one of %v", c.Unknown, validRoles["Unknown"])
+	}
+	if c.Unknown == ReplyUnknownPolicy && strings.TrimSpace(c.Reply) == "" {
+		errs.addf("reply", "must be set when unknown is %q", ReplyUnknownPolicy)
+	}
+
+	slices.SortFunc(errs, func(a, b *FieldError) int { return strings.Compare(a.Key, b.Key) })
+
+	return errs.err()
+}
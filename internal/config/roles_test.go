@@ -0,0 +1,29 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestRolesConfigValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   RolesConfig
+		expected bool
+	}{
+		{"Valid empty config", RolesConfig{Unknown: "ignore"}, true},
+		{"Valid roles", RolesConfig{Admins: []int64{1}, Contributors: []int64{2, 3}, Readers: []int64{4}, Unknown: "ignore"}, true},
+		{"Valid reply policy", RolesConfig{Unknown: "reply", Reply: "Go away"}, true},
+		{"Invalid policy", RolesConfig{Unknown: "ban"}, false},
+		{"Missing reply", RolesConfig{Unknown: "reply"}, false},
+		{"Empty user ID", RolesConfig{Admins: []int64{0}, Unknown: "ignore"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if (err == nil) != tt.expected {
+				t.Errorf("For config %+v, expected valid: %v, but got error: %v", tt.config, tt.expected, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package config
+
+import (
+	"time"
+)
+
+// SchedulerConfig represents a configuration for the jobs run on a schedule,
+// such as digests.
+type SchedulerConfig struct {
+	Timezone string `koanf:"timezone"` // IANA time zone of the schedules (e.g. "Europe/Madrid"). "Local" uses the system time zone.
+}
+
+// Validate checks if the scheduler configuration is valid.
+func (c SchedulerConfig) Validate() error {
+	var errs ValidationErrors
+
+	if c.Timezone == "" {
+		errs.addf("timezone", "invalid timezone: must be set")
+	} else if _, err := time.LoadLocation(c.Timezone); err != nil {
+		errs.addf("timezone", "invalid timezone %q: %v", c.Timezone, err)
+	}
+
+	return errs.err()
+}
+
+// Location returns the time zone of the schedules, or the system time zone if
+// it's invalid.
+func (c SchedulerConfig) Location() *time.Location {
+	loc, err := time.LoadLocation(c.Timezone)
+	if err != nil || c.Timezone == "" {
+		return time.Local
+	}
+	return loc
+}
@@ -0,0 +1,32 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSchedulerConfigValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   SchedulerConfig
+		expected bool
+	}{
+		{"System time zone", SchedulerConfig{Timezone: "Local"}, true},
+		{"UTC", SchedulerConfig{Timezone: "UTC"}, true},
+		{"IANA time zone", SchedulerConfig{Timezone: "Europe/Madrid"}, true},
+		{"Empty time zone", SchedulerConfig{Timezone: ""}, false},
+		{"Unknown time zone", SchedulerConfig{Timezone: "Mars/Olympus"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := time.LoadLocation("Europe/Madrid"); err != nil && tt.config.Timezone == "Europe/Madrid" {
+				t.Skipf("Time zone database not available: %v", err)
+			}
+			err := tt.config.Validate()
+			if (err == nil) != tt.expected {
+				t.Errorf("For config %+v, expected valid: %v, but got error: %v", tt.config, tt.expected, err)
+			}
+		})
+	}
+}
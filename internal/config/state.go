@@ -0,0 +1,12 @@
+package config
+
+// StateConfig represents a configuration for the bot state (e.g. roles
+// granted with commands).
+type StateConfig struct {
+	Dir string `koanf:"dir"` // Directory where the state is stored. If empty, it is kept in memory.
+}
+
+// Validate checks if the state configuration is valid.
+func (c StateConfig) Validate() error {
+	return nil
+}
@@ -0,0 +1,55 @@
+package config
+
+import (
+	"slices"
+)
+
+// Bookmark types, to wait for their AI tags or to allow them per chat.
+const (
+	LinkBookmarkType  = "link"
+	TextBookmarkType  = "text"
+	AssetBookmarkType = "asset"
+)
+
+// validBookmarkTypes are the allowed bookmark types, for tagging and per chat.
+var validBookmarkTypes = []string{LinkBookmarkType, TextBookmarkType, AssetBookmarkType}
+
+// TaggingConfig represents a configuration for waiting for the AI tags of new
+// bookmarks.
+type TaggingConfig struct {
+	Interval    int      `koanf:"interval"`    // Initial interval (in seconds) before retrying tagging status, doubled on every retry.
+	MaxInterval int      `koanf:"maxinterval"` // Maximum interval (in seconds) between retries.
+	Timeout     int      `koanf:"timeout"`     // Maximum time (in seconds) to wait for AI tags. 0 disables waiting.
+	Types       []string `koanf:"types"`       // Bookmark types to wait for AI tags: "link", "text" and "asset".
+	Deferred    bool     `koanf:"deferred"`    // Whether to reply at once with the current tags and edit the message when AI tags arrive.
+	Watch       int      `koanf:"watch"`       // Maximum time (in seconds) to watch for AI tags after replying, to edit the message. 0 disables watching.
+}
+
+// Validate checks if the tagging configuration is valid.
+func (c TaggingConfig) Validate() error {
+	var errs ValidationErrors
+
+	if c.Interval <= 0 {
+		errs.addf("interval", "invalid interval: must be a positive value, got %d", c.Interval)
+	}
+
+	if c.MaxInterval < c.Interval {
+		errs.addf("maxinterval", "invalid maxinterval: must be greater than or equal to interval (%d), got %d", c.Interval, c.MaxInterval)
+	}
+
+	if c.Timeout < 0 {
+		errs.addf("timeout", "invalid timeout: must not be negative, got %d", c.Timeout)
+	}
+
+	if c.Watch < 0 {
+		errs.addf("watch", "invalid watch: must not be negative, got %d", c.Watch)
+	}
+
+	for _, bookmarkType := range c.Types {
+		if !slices.Contains(validBookmarkTypes, bookmarkType) {
+			errs.addf("types", "invalid bookmark type %q: must be one of %v", bookmarkType, validBookmarkTypes)
+		}
+	}
+
+	return errs.err()
+}
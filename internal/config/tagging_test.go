@@ -0,0 +1,64 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTaggingConfigValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   TaggingConfig
+		expected bool
+	}{
+		{"Valid config", TaggingConfig{Interval: 2, MaxInterval: 10, Timeout: 30, Types: []string{"link", "asset"}}, true},
+		{"Waiting disabled", TaggingConfig{Interval: 2, MaxInterval: 2, Timeout: 0}, true},
+		{"Invalid interval", TaggingConfig{Interval: 0, MaxInterval: 10, Timeout: 30}, false},
+		{"Max interval lower than interval", TaggingConfig{Interval: 5, MaxInterval: 2, Timeout: 30}, false},
+		{"Negative timeout", TaggingConfig{Interval: 2, MaxInterval: 10, Timeout: -1}, false},
+		{"Negative watch", TaggingConfig{Interval: 2, MaxInterval: 10, Timeout: 30, Watch: -1}, false},
+		{"Invalid type", TaggingConfig{Interval: 2, MaxInterval: 10, Timeout: 30, Types: []string{"video"}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if (err == nil) != tt.expected {
+				t.Errorf("For config %+v, expected valid: %v, but got error: %v", tt.config, tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestNewDeprecatedInterval(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	data := `
+[karakeep]
+interval = 7
+
+[tagging]
+maxinterval = 20
+`
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+
+	config, err := New([]string{"-config", path})
+	if err != nil {
+		t.Fatalf("New() returned an unexpected error: %v", err)
+	}
+
+	if config.Tagging.Interval != 7 {
+		t.Errorf("expected karakeep.interval to set tagging.interval, got %d", config.Tagging.Interval)
+	}
+	if config.Tagging.MaxInterval != 20 {
+		t.Errorf("expected tagging.maxinterval to take precedence over karakeep.interval, got %d", config.Tagging.MaxInterval)
+	}
+	if got := config.Source("tagging.interval"); got != "file "+path {
+		t.Errorf("expected tagging.interval to come from the config file, got %q", got)
+	}
+	if len(config.Deprecations()) != 1 {
+		t.Errorf("expected a deprecation warning, got %v", config.Deprecations())
+	}
+}
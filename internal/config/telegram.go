@@ -1,34 +1,58 @@
 package config
 
 import (
-	"fmt"
+	"errors"
+	"slices"
 
 	"github.com/Madh93/karakeepbot/internal/secret"
 	"github.com/Madh93/karakeepbot/internal/validation"
 )
 
+// Reply modes, which define what the bot does with the original message once
+// the bookmark is created.
+const (
+	ReplyModeReplace = "replace" // Send a new message with the hashtags and delete the original one.
+	ReplyModeReply   = "reply"   // Reply to the original message with the hashtags.
+	ReplyModeNone    = "none"    // Don't send anything back.
+)
+
+// validReplyModes are the allowed reply modes, globally and per chat.
+var validReplyModes = []string{ReplyModeReplace, ReplyModeReply, ReplyModeNone}
+
 // TelegramConfig represents a configuration for Telegram.
 type TelegramConfig struct {
 	Token        secret.String `koanf:"token"`        // Telegram bot token.
 	Allowlist    []int64       `koanf:"allowlist"`    // Allowed chat IDs for the bot to interact with.
 	Threads      []int         `koanf:"threads"`      // Allowed thread IDs (a.k.a topics) for the bot to interact with.
 	ProxyEnabled bool          `koanf:"proxyenabled"` // Whether to use a proxy for Telegram Bot API connections.
-	ProxyURL     string        `koanf:"proxyurl"`     // Proxy URL (e.g., "socks5://127.0.0.1:1080").
+	ProxyURL     secret.String `koanf:"proxyurl"`     // Proxy URL (e.g., "socks5://127.0.0.1:1080"). It may contain credentials.
+	ReplyMode    string        `koanf:"replymode"`    // What to do with the original message: "replace", "reply" or "none".
+	Retries      int           `koanf:"retries"`      // Maximum number of retries for requests rejected with 429 Too Many Requests.
 }
 
 // Validate checks if the Telegram configuration is valid.
 func (c TelegramConfig) Validate() error {
+	var errs ValidationErrors
+
 	if err := validation.ValidateTelegramToken(c.Token); err != nil {
-		return err
+		errs.add("token", err)
 	}
 
 	if len(c.Allowlist) == 1 && c.Allowlist[0] == -1 {
-		return fmt.Errorf("invalid Telegram Allowlist (-1). Please configure it with your actual chat ID or an empty list to allow all users (not recommended)")
+		errs.add("allowlist", errors.New("invalid Telegram Allowlist (-1). Please configure it with your actual chat ID or an empty list to allow all users (not recommended)"))
 	}
 
 	if c.ProxyEnabled && c.ProxyURL == "" {
-		return fmt.Errorf("proxyurl must be set when proxyenabled is true")
+		errs.add("proxyurl", errors.New("must be set when proxyenabled is true"))
+	}
+
+	if !slices.Contains(validReplyModes, c.ReplyMode) {
+		errs.addf("replymode", "invalid reply mode %q: must be one of %v", c.ReplyMode, validReplyModes)
+	}
+
+	if c.Retries < 0 {
+		errs.addf("retries", "invalid retries: must not be negative, got %d", c.Retries)
 	}
 
-	return nil
+	return errs.err()
 }
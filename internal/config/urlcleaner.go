@@ -0,0 +1,51 @@
+package config
+
+import (
+	"regexp"
+	"strings"
+)
+
+// URLCleanerConfig represents a configuration for the URL cleaning pipeline.
+type URLCleanerConfig struct {
+	Enabled bool             `koanf:"enabled"` // Whether to clean URLs before saving them.
+	Resolve bool             `koanf:"resolve"` // Whether to follow HTTP redirects with a HEAD request.
+	Timeout int              `koanf:"timeout"` // Maximum time to wait for redirect resolution in seconds.
+	Params  []string         `koanf:"params"`  // Additional query parameters to strip. A trailing '*' matches any suffix.
+	Rules   []URLRewriteRule `koanf:"rules"`   // User-defined regex rewrite rules.
+}
+
+// URLRewriteRule represents a user-defined URL rewrite rule. The pattern is a
+// regular expression matched against the whole URL and the replacement may
+// reference capture groups (e.g. "$1").
+type URLRewriteRule struct {
+	Pattern     string `koanf:"pattern"`     // Regular expression to match.
+	Replacement string `koanf:"replacement"` // Replacement string.
+}
+
+// Validate checks if the URL cleaner configuration is valid.
+func (c URLCleanerConfig) Validate() error {
+	var errs ValidationErrors
+
+	if c.Resolve && c.Timeout <= 0 {
+		errs.addf("timeout", "invalid timeout: must be a positive value, got %d", c.Timeout)
+	}
+
+	for _, param := range c.Params {
+		if strings.TrimSpace(param) == "" {
+			errs.addf("params", "invalid params: contains an empty entry")
+			break
+		}
+	}
+
+	for i, rule := range c.Rules {
+		if rule.Pattern == "" {
+			errs.addf("rules", "invalid rule #%d: pattern must not be empty", i+1)
+			continue
+		}
+		if _, err := regexp.Compile(rule.Pattern); err != nil {
+			errs.addf("rules", "invalid rule #%d: %w", i+1, err)
+		}
+	}
+
+	return errs.err()
+}
@@ -0,0 +1,60 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestURLCleanerConfig_Validate(t *testing.T) {
+	// Define test cases for the Validate() method
+	tests := []struct {
+		name     string
+		config   URLCleanerConfig
+		expected bool
+	}{
+		{
+			name:     "Valid config",
+			config:   URLCleanerConfig{Enabled: true, Timeout: 5},
+			expected: true,
+		},
+		{
+			name:     "Valid config with rules and params",
+			config:   URLCleanerConfig{Enabled: true, Params: []string{"ref"}, Rules: []URLRewriteRule{{Pattern: `^http://`, Replacement: "https://"}}},
+			expected: true,
+		},
+		{
+			name:     "Valid config without timeout when not resolving",
+			config:   URLCleanerConfig{Enabled: true, Resolve: false, Timeout: 0},
+			expected: true,
+		},
+		{
+			name:     "Invalid Timeout when resolving",
+			config:   URLCleanerConfig{Enabled: true, Resolve: true, Timeout: 0},
+			expected: false,
+		},
+		{
+			name:     "Invalid with empty param",
+			config:   URLCleanerConfig{Enabled: true, Params: []string{" "}},
+			expected: false,
+		},
+		{
+			name:     "Invalid with empty pattern",
+			config:   URLCleanerConfig{Enabled: true, Rules: []URLRewriteRule{{Pattern: "", Replacement: "x"}}},
+			expected: false,
+		},
+		{
+			name:     "Invalid with malformed pattern",
+			config:   URLCleanerConfig{Enabled: true, Rules: []URLRewriteRule{{Pattern: "([", Replacement: "x"}}},
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			got := err == nil
+			if got != tt.expected {
+				t.Errorf("For config %+v, expected valid: %v, but got error: %v", tt.config, tt.expected, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package config
+
+import (
+	"errors"
+	"net"
+	"strings"
+
+	"github.com/Madh93/karakeepbot/internal/secret"
+)
+
+// WebhookConfig represents a configuration for receiving Karakeep webhook
+// events, which tell when a bookmark is tagged instead of polling for it.
+type WebhookConfig struct {
+	Enabled bool          `koanf:"enabled"` // Whether to listen for Karakeep webhook events.
+	Listen  string        `koanf:"listen"`  // Address to listen on (e.g., ":8088").
+	Path    string        `koanf:"path"`    // Path of the webhook endpoint.
+	Token   secret.String `koanf:"token"`   // Token shared with Karakeep, sent as a bearer token.
+}
+
+// Validate checks if the webhook configuration is valid.
+func (c WebhookConfig) Validate() error {
+	var errs ValidationErrors
+
+	if !c.Enabled {
+		return nil
+	}
+
+	if _, _, err := net.SplitHostPort(c.Listen); err != nil {
+		errs.addf("listen", "invalid listen address %q: %w", c.Listen, err)
+	}
+
+	if !strings.HasPrefix(c.Path, "/") {
+		errs.addf("path", "invalid path %q: must start with '/'", c.Path)
+	}
+
+	if c.Token == "" {
+		errs.add("token", errors.New("must be set when enabled is true"))
+	}
+
+	return errs.err()
+}
@@ -0,0 +1,28 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestWebhookConfigValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   WebhookConfig
+		expected bool
+	}{
+		{"Valid config", WebhookConfig{Enabled: true, Listen: ":8088", Path: "/webhooks/karakeep", Token: "secret"}, true},
+		{"Disabled config", WebhookConfig{Enabled: false}, true},
+		{"Invalid listen address", WebhookConfig{Enabled: true, Listen: "8088", Path: "/webhooks/karakeep", Token: "secret"}, false},
+		{"Invalid path", WebhookConfig{Enabled: true, Listen: ":8088", Path: "webhooks", Token: "secret"}, false},
+		{"Missing token", WebhookConfig{Enabled: true, Listen: ":8088", Path: "/webhooks/karakeep"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if (err == nil) != tt.expected {
+				t.Errorf("For config %+v, expected valid: %v, but got error: %v", tt.config, tt.expected, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,197 @@
+// Package cron parses cron expressions and computes when they are due.
+//
+// Expressions have the five standard fields: minute, hour, day of month,
+// month and day of week. Each field accepts "*", single values, ranges
+// ("1-5"), steps ("*/15", "0-30/10") and lists of them ("1,15,30"). Months
+// and days of week also accept English names ("JAN", "MON"), and both 0 and 7
+// are Sunday. As in most cron implementations, when both the day of month and
+// the day of week are restricted, a day matching either one is due.
+//
+// The descriptors @yearly (or @annually), @monthly, @weekly, @daily (or
+// @midnight) and @hourly are also supported.
+package cron
+
+import (
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxSearch is how far in the future Next looks for a due time, so impossible
+// expressions such as "0 0 30 2 *" don't loop forever.
+const maxSearch = 5 * 366 * 24 * time.Hour
+
+// descriptors are the shorthands for common expressions.
+var descriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// field describes the allowed values of a field of an expression.
+type field struct {
+	name     string
+	min, max int
+	names    []string // Names of the values, starting at min.
+}
+
+var (
+	minuteField = field{name: "minute", min: 0, max: 59}
+	hourField   = field{name: "hour", min: 0, max: 23}
+	domField    = field{name: "day of month", min: 1, max: 31}
+	monthField  = field{name: "month", min: 1, max: 12, names: []string{"JAN", "FEB", "MAR", "APR", "MAY", "JUN", "JUL", "AUG", "SEP", "OCT", "NOV", "DEC"}}
+	dowField    = field{name: "day of week", min: 0, max: 7, names: []string{"SUN", "MON", "TUE", "WED", "THU", "FRI", "SAT", "SUN"}}
+)
+
+// Schedule is a parsed cron expression. Each field is a bit set of the
+// allowed values.
+type Schedule struct {
+	expr                          string
+	minute, hour, dom, month, dow uint64
+	domRestricted, dowRestricted  bool
+}
+
+// Parse parses a cron expression.
+func Parse(expr string) (*Schedule, error) {
+	spec := strings.TrimSpace(expr)
+	if descriptor, ok := descriptors[strings.ToLower(spec)]; ok {
+		spec = descriptor
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid cron expression %q: expected 5 fields, got %d", expr, len(fields))
+	}
+
+	s := &Schedule{expr: expr}
+	var err error
+	for i, target := range []struct {
+		field field
+		bits  *uint64
+	}{
+		{minuteField, &s.minute},
+		{hourField, &s.hour},
+		{domField, &s.dom},
+		{monthField, &s.month},
+		{dowField, &s.dow},
+	} {
+		if *target.bits, err = parseField(fields[i], target.field); err != nil {
+			return nil, fmt.Errorf("invalid cron expression %q: %w", expr, err)
+		}
+	}
+
+	// Sunday can be written as 0 or 7
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+	s.domRestricted = fields[2] != "*" && !strings.HasPrefix(fields[2], "*/")
+	s.dowRestricted = fields[4] != "*" && !strings.HasPrefix(fields[4], "*/")
+
+	return s, nil
+}
+
+// String returns the expression the schedule was parsed from.
+func (s *Schedule) String() string {
+	return s.expr
+}
+
+// Next returns the first due time strictly after t, in the location of t. It
+// returns the zero time if the expression is never due (e.g. February 30th).
+func (s *Schedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+	limit := t.Add(maxSearch)
+	t = t.Truncate(time.Minute).Add(time.Minute)
+
+	for t.Before(limit) {
+		switch {
+		case s.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+		case !s.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+		case s.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+		case s.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// dayMatches reports whether the day of t is due, by day of month or day of
+// week.
+func (s *Schedule) dayMatches(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domRestricted && s.dowRestricted {
+		return dom || dow
+	}
+	return dom && dow
+}
+
+// parseField returns the bit set of the values allowed by a field.
+func parseField(spec string, f field) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(spec, ",") {
+		rangeSpec, stepSpec, hasStep := strings.Cut(part, "/")
+
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepSpec); err != nil || step < 1 {
+				return 0, fmt.Errorf("invalid step %q in %s field", stepSpec, f.name)
+			}
+		}
+
+		first, last := f.min, f.max
+		if rangeSpec != "*" {
+			start, end, isRange := strings.Cut(rangeSpec, "-")
+			var err error
+			if first, err = f.value(start); err != nil {
+				return 0, err
+			}
+			last = first
+			if isRange {
+				if last, err = f.value(end); err != nil {
+					return 0, err
+				}
+			} else if hasStep {
+				last = f.max
+			}
+			if first > last {
+				return 0, fmt.Errorf("invalid range %q in %s field", rangeSpec, f.name)
+			}
+		}
+
+		for v := first; v <= last; v += step {
+			set |= 1 << uint(v)
+		}
+	}
+
+	if bits.OnesCount64(set) == 0 {
+		return 0, fmt.Errorf("empty %s field", f.name)
+	}
+	return set, nil
+}
+
+// value parses a single value of a field, as a number or a name.
+func (f field) value(s string) (int, error) {
+	for i, name := range f.names {
+		if strings.EqualFold(s, name) {
+			return f.min + i, nil
+		}
+	}
+
+	v, err := strconv.Atoi(s)
+	if err != nil || v < f.min || v > f.max {
+		return 0, fmt.Errorf("invalid value %q in %s field: must be between %d and %d", s, f.name, f.min, f.max)
+	}
+	return v, nil
+}
@@ -0,0 +1,90 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		expr          string
+		expectedError bool
+	}{
+		{"* * * * *", false},
+		{"*/15 9-17 * * MON-FRI", false},
+		{"0 9 1,15 jan,jul 0", false},
+		{"30 8 * * 7", false},
+		{"@daily", false},
+		{"@WEEKLY", false},
+		{"", true},
+		{"* * * *", true},
+		{"* * * * * *", true},
+		{"60 * * * *", true},
+		{"* 24 * * *", true},
+		{"* * 0 * *", true},
+		{"* * * 13 *", true},
+		{"* * * * 8", true},
+		{"*/0 * * * *", true},
+		{"5-1 * * * *", true},
+		{"a * * * *", true},
+		{"@reboot", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			s, err := Parse(tt.expr)
+			if (err != nil) != tt.expectedError {
+				t.Fatalf("Expected error: %v, got %v", tt.expectedError, err)
+			}
+			if err == nil && s.String() != tt.expr {
+				t.Errorf("Expected String() %q, got %q", tt.expr, s.String())
+			}
+		})
+	}
+}
+
+func TestSchedule_Next(t *testing.T) {
+	madrid, err := time.LoadLocation("Europe/Madrid")
+	if err != nil {
+		t.Skipf("Time zone database not available: %v", err)
+	}
+	date := func(loc *time.Location, year int, month time.Month, day, hour, minute int) time.Time {
+		return time.Date(year, month, day, hour, minute, 0, 0, loc)
+	}
+
+	tests := []struct {
+		name     string
+		expr     string
+		from     time.Time
+		expected time.Time
+	}{
+		{"Every minute", "* * * * *", date(time.UTC, 2026, 10, 18, 10, 30), date(time.UTC, 2026, 10, 18, 10, 31)},
+		{"Seconds are truncated", "* * * * *", date(time.UTC, 2026, 10, 18, 10, 30).Add(30 * time.Second), date(time.UTC, 2026, 10, 18, 10, 31)},
+		{"Steps", "*/15 * * * *", date(time.UTC, 2026, 10, 18, 10, 31), date(time.UTC, 2026, 10, 18, 10, 45)},
+		{"Strictly after", "0 9 * * *", date(time.UTC, 2026, 10, 18, 9, 0), date(time.UTC, 2026, 10, 19, 9, 0)},
+		{"Next hour", "15 * * * *", date(time.UTC, 2026, 10, 18, 23, 30), date(time.UTC, 2026, 10, 19, 0, 15)},
+		{"Weekdays by name", "0 9 * * MON-FRI", date(time.UTC, 2026, 10, 16, 10, 0), date(time.UTC, 2026, 10, 19, 9, 0)},
+		{"Sunday as 7", "0 9 * * 7", date(time.UTC, 2026, 10, 12, 0, 0), date(time.UTC, 2026, 10, 18, 9, 0)},
+		{"Day of month or day of week", "0 0 1 * MON", date(time.UTC, 2026, 10, 20, 0, 0), date(time.UTC, 2026, 10, 26, 0, 0)},
+		{"Day of month with step in day of week", "0 0 1 * */2", date(time.UTC, 2026, 10, 20, 0, 0), date(time.UTC, 2026, 11, 1, 0, 0)},
+		{"Months by name", "0 0 1 jan *", date(time.UTC, 2026, 10, 18, 0, 0), date(time.UTC, 2027, 1, 1, 0, 0)},
+		{"Leap day", "0 0 29 2 *", date(time.UTC, 2026, 10, 18, 0, 0), date(time.UTC, 2028, 2, 29, 0, 0)},
+		{"Descriptor", "@monthly", date(time.UTC, 2026, 10, 18, 0, 0), date(time.UTC, 2026, 11, 1, 0, 0)},
+		{"Time zone", "0 9 * * *", date(madrid, 2026, 10, 18, 10, 0), date(madrid, 2026, 10, 19, 9, 0)},
+		{"Skipped by daylight saving time", "30 2 * * *", date(madrid, 2026, 3, 28, 12, 0), date(madrid, 2026, 3, 30, 2, 30)},
+		{"Across daylight saving time", "0 9 * * *", date(madrid, 2026, 10, 24, 12, 0), date(madrid, 2026, 10, 25, 9, 0)},
+		{"Never", "0 0 30 2 *", date(time.UTC, 2026, 10, 18, 0, 0), time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := Parse(tt.expr)
+			if err != nil {
+				t.Fatalf("Parse() returned an unexpected error: %v", err)
+			}
+			if got := s.Next(tt.from); !got.Equal(tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
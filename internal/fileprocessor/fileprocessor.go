@@ -8,11 +8,12 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"net/url"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/Madh93/karakeepbot/internal/config"
+	"github.com/Madh93/karakeepbot/internal/httpclient"
 )
 
 // Custom error types for better error handling.
@@ -28,11 +29,12 @@ type Validator func(file *os.File) (contentType string, err error)
 
 // Processor is responsible for downloading and processing files.
 type Processor struct {
-	tempdir      string
-	maxsize      int64
-	timeout      int
-	proxyEnabled bool
-	proxyURL     string
+	tempdir   string
+	transport http.RoundTripper
+
+	mu      sync.RWMutex // Protects the limits below, which can be updated at runtime.
+	maxsize int64
+	timeout int
 }
 
 // New creates a new Processor using the provided configuration.
@@ -48,19 +50,46 @@ func New(config *config.FileProcessorConfig, proxyEnabled bool, proxyURL string)
 		return nil, fmt.Errorf("failed to create temporary directory: %w", err)
 	}
 
+	// Build the HTTP transport, routing downloads through the proxy if enabled.
+	var opts httpclient.TransportOptions
+	if proxyEnabled {
+		opts.ProxyURL = proxyURL
+	}
+	transport, err := httpclient.NewTransport(opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP transport: %w", err)
+	}
+
 	return &Processor{
-		tempdir:      tempdir,
-		maxsize:      config.Maxsize,
-		timeout:      config.Timeout,
-		proxyEnabled: proxyEnabled,
-		proxyURL:     proxyURL,
+		tempdir:   tempdir,
+		transport: transport,
+		maxsize:   config.Maxsize,
+		timeout:   config.Timeout,
 	}, nil
 }
 
+// UpdateLimits updates the maximum file size and download timeout, e.g. on
+// configuration reload. Downloads in progress keep the previous limits.
+func (p *Processor) UpdateLimits(config *config.FileProcessorConfig) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.maxsize = config.Maxsize
+	p.timeout = config.Timeout
+}
+
+// limits returns the current maximum file size and download timeout.
+func (p *Processor) limits() (maxsize int64, timeout int) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.maxsize, p.timeout
+}
+
 // Process downloads a file from a URL, optionally validates it, and saves it to
 // a temporary location. The caller is responsible for cleaning up the file
 // using the Cleanup method.
 func (p *Processor) Process(fileURL string, validator Validator) (path string, contentType string, err error) {
+	maxsize, timeout := p.limits()
+
 	// Create a temporary file.
 	tmpFile, err := os.CreateTemp(p.tempdir, "karakeepbot-*.tmp")
 	if err != nil {
@@ -78,18 +107,10 @@ func (p *Processor) Process(fileURL string, validator Validator) (path string, c
 		}
 	}()
 
-	// Configure an HTTP client with the specified timeout and optional proxy.
-	transport := http.DefaultTransport.(*http.Transport).Clone()
-	if p.proxyEnabled && p.proxyURL != "" {
-		proxyURL, err := url.Parse(p.proxyURL)
-		if err != nil {
-			return "", "", fmt.Errorf("failed to parse proxy URL: %w", err)
-		}
-		transport.Proxy = http.ProxyURL(proxyURL)
-	}
+	// Configure an HTTP client with the specified timeout.
 	client := http.Client{
-		Transport: transport,
-		Timeout:   time.Duration(p.timeout) * time.Second,
+		Transport: p.transport,
+		Timeout:   time.Duration(timeout) * time.Second,
 	}
 
 	// Download the file.
@@ -108,15 +129,15 @@ func (p *Processor) Process(fileURL string, validator Validator) (path string, c
 	}
 
 	// Limit the download to prevent resource exhaustion and copy data
-	limitedReader := &io.LimitedReader{R: resp.Body, N: p.maxsize + 1}
+	limitedReader := &io.LimitedReader{R: resp.Body, N: maxsize + 1}
 	bytesWritten, err := io.Copy(tmpFile, limitedReader)
 	if err != nil {
 		return "", "", fmt.Errorf("%w: %w", ErrProcessingFailed, err)
 	}
 
 	// Check if the file was larger than the limit
-	if bytesWritten > p.maxsize {
-		return "", "", fmt.Errorf("%w: exceeds %d bytes", ErrValidationFailed, p.maxsize)
+	if bytesWritten > maxsize {
+		return "", "", fmt.Errorf("%w: exceeds %d bytes", ErrValidationFailed, maxsize)
 	}
 
 	// Run validation if a validator function is provided.
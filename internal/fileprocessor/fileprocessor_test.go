@@ -150,3 +150,26 @@ func TestProcessor_Process(t *testing.T) {
 		})
 	}
 }
+
+func TestProcessor_UpdateLimits(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write(make([]byte, 50))
+	}))
+	defer server.Close()
+
+	p, err := New(&config.FileProcessorConfig{Tempdir: t.TempDir(), Maxsize: 100, Timeout: 5}, false, "")
+	if err != nil {
+		t.Fatalf("New() returned an unexpected error: %v", err)
+	}
+
+	path, _, err := p.Process(server.URL, nil)
+	if err != nil {
+		t.Fatalf("Process() returned an unexpected error: %v", err)
+	}
+	_ = p.Cleanup(path)
+
+	p.UpdateLimits(&config.FileProcessorConfig{Maxsize: 10, Timeout: 5})
+	if _, _, err := p.Process(server.URL, nil); !errors.Is(err, ErrValidationFailed) {
+		t.Errorf("expected %v after lowering the maximum size, got: %v", ErrValidationFailed, err)
+	}
+}
@@ -0,0 +1,223 @@
+// Package hashtag converts Karakeep tags into Telegram hashtags and back.
+//
+// Telegram hashtags only contain letters, digits and underscores, so every
+// other character of a tag (spaces, hyphens, dots, slashes, emoji...) is a
+// word separator. The words are then joined following the configured case
+// style. Aliases and the blocklist are matched by key, which ignores case,
+// accents and separators, so "Machine Learning", "machine-learning" and
+// #MachineLearning are the same tag.
+//
+// Tags attached by Karakeep's AI can be shown on their own line, next to the
+// tags attached by the user or not at all.
+package hashtag
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/Madh93/karakeepbot/internal/config"
+)
+
+// aiMarker precedes the AI hashtags when they are shown on their own line.
+const aiMarker = "🤖"
+
+// Normalizer converts tags into hashtags and back.
+type Normalizer struct {
+	style         string
+	transliterate bool
+	aliases       map[string]string   // Canonical tag of each synonym key.
+	blocklist     map[string]struct{} // Keys of the blocked tags.
+	max           int
+	aiTags        string
+	maxAI         int
+}
+
+// New creates a new Normalizer using the provided configuration.
+func New(config *config.HashtagsConfig) *Normalizer {
+	n := &Normalizer{
+		style:         config.Case,
+		transliterate: config.Transliterate,
+		aliases:       make(map[string]string),
+		blocklist:     make(map[string]struct{}),
+		max:           config.Max,
+		aiTags:        config.AITags,
+		maxAI:         config.MaxAI,
+	}
+	for _, alias := range config.Aliases {
+		for _, synonym := range alias.Synonyms {
+			n.aliases[key(synonym)] = alias.Tag
+		}
+	}
+	for _, tag := range config.Blocklist {
+		n.blocklist[key(tag)] = struct{}{}
+	}
+	return n
+}
+
+// Hashtag returns the hashtag, without the leading '#', for a Karakeep tag. It
+// returns an empty string if the tag is blocked or has no valid characters.
+func (n *Normalizer) Hashtag(tag string) string {
+	tag, ok := n.resolve(tag)
+	if !ok {
+		return ""
+	}
+	return n.format(tag)
+}
+
+// Hashtags returns the unique hashtags, without the leading '#', for the
+// Karakeep tags, up to the maximum number of hashtags.
+func (n *Normalizer) Hashtags(tags []string) []string {
+	return n.hashtags(tags, nil, n.max)
+}
+
+// Render returns the hashtags, with the leading '#', for the tags attached by
+// the user and by the AI, laid out following the AI tags mode. AI tags that
+// are also user tags are only shown once, as user tags. The user tags come
+// first when limiting the number of hashtags.
+func (n *Normalizer) Render(userTags, aiTags []string) string {
+	user := n.Hashtags(userTags)
+
+	var ai []string
+	if n.aiTags != config.AITagsHide {
+		limit := n.maxAI
+		if n.max > 0 && (limit == 0 || n.max-len(user) < limit) {
+			limit = n.max - len(user)
+		}
+		if limit != 0 || n.max == 0 {
+			ai = n.hashtags(aiTags, user, limit)
+		}
+	}
+
+	if n.aiTags != config.AITagsSeparate {
+		return join(append(user, ai...))
+	}
+	lines := make([]string, 0, 2)
+	if len(user) > 0 {
+		lines = append(lines, join(user))
+	}
+	if len(ai) > 0 {
+		lines = append(lines, aiMarker+" "+join(ai))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// hashtags returns the unique hashtags for the tags that are not in exclude,
+// up to limit hashtags. A limit of 0 means no limit. Hashtags are compared by
+// key, as Telegram hashtags are case-insensitive.
+func (n *Normalizer) hashtags(tags, exclude []string, limit int) []string {
+	seen := make(map[string]struct{})
+	for _, hashtag := range exclude {
+		seen[key(hashtag)] = struct{}{}
+	}
+
+	var hashtags []string
+	for _, tag := range tags {
+		if limit > 0 && len(hashtags) == limit {
+			break
+		}
+		hashtag := n.Hashtag(tag)
+		if _, ok := seen[key(hashtag)]; hashtag == "" || ok {
+			continue
+		}
+		seen[key(hashtag)] = struct{}{}
+		hashtags = append(hashtags, hashtag)
+	}
+	return hashtags
+}
+
+// join returns the hashtags with the leading '#', separated by spaces.
+func join(hashtags []string) string {
+	prefixed := make([]string, 0, len(hashtags))
+	for _, hashtag := range hashtags {
+		prefixed = append(prefixed, "#"+hashtag)
+	}
+	return strings.Join(prefixed, " ")
+}
+
+// Tags returns the unique Karakeep tags for the hashtags found in a Telegram
+// message. Synonyms are replaced with their canonical tag, as named in
+// Karakeep, and blocked tags are dropped.
+func (n *Normalizer) Tags(hashtags []string) []string {
+	var tags []string
+	for _, hashtag := range hashtags {
+		tag, ok := n.resolve(hashtag)
+		if !ok {
+			continue
+		}
+		if _, aliased := n.aliases[key(hashtag)]; !aliased {
+			tag = n.format(tag)
+		}
+		if tag != "" && !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// resolve returns the canonical tag for a tag, and whether it's allowed.
+func (n *Normalizer) resolve(tag string) (string, bool) {
+	if canonical, ok := n.aliases[key(tag)]; ok {
+		tag = canonical
+	}
+	if _, blocked := n.blocklist[key(tag)]; blocked {
+		return "", false
+	}
+	return tag, true
+}
+
+// format joins the words of a tag following the case style.
+func (n *Normalizer) format(tag string) string {
+	if n.transliterate {
+		tag = transliterate(tag)
+	}
+
+	switch n.style {
+	case config.HashtagCaseLower:
+		return strings.ToLower(strings.Join(words(tag, false), ""))
+	case config.HashtagCaseCamel:
+		parts := words(tag, true)
+		for i, part := range parts {
+			if i == 0 {
+				parts[i] = changeFirst(part, unicode.ToLower)
+			} else {
+				parts[i] = changeFirst(part, unicode.ToUpper)
+			}
+		}
+		return strings.Join(parts, "")
+	case config.HashtagCasePascal:
+		parts := words(tag, true)
+		for i, part := range parts {
+			parts[i] = changeFirst(part, unicode.ToUpper)
+		}
+		return strings.Join(parts, "")
+	case config.HashtagCaseSnake:
+		return strings.ToLower(strings.Join(words(tag, true), "_"))
+	default: // config.HashtagCaseKeep
+		return strings.Join(words(tag, false), "")
+	}
+}
+
+// key returns the key used to match a tag against aliases and the blocklist.
+func key(tag string) string {
+	return strings.ToLower(strings.Join(words(transliterate(tag), true), ""))
+}
+
+// words splits a tag into the words allowed in a hashtag. Underscores are only
+// separators if splitUnderscores is true.
+func words(tag string, splitUnderscores bool) []string {
+	return strings.FieldsFunc(tag, func(r rune) bool {
+		if r == '_' {
+			return splitUnderscores
+		}
+		return !unicode.IsLetter(r) && !unicode.IsMark(r) && !unicode.IsNumber(r)
+	})
+}
+
+// changeFirst returns the word with its first letter changed by fn, e.g.
+// [unicode.ToUpper]. The rest of the word is kept, so acronyms are preserved.
+func changeFirst(word string, fn func(rune) rune) string {
+	r, size := utf8.DecodeRuneInString(word)
+	return string(fn(r)) + word[size:]
+}
@@ -0,0 +1,150 @@
+package hashtag
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/Madh93/karakeepbot/internal/config"
+)
+
+func TestNormalizer_Hashtag(t *testing.T) {
+	tests := []struct {
+		style    string
+		input    string
+		expected string
+	}{
+		{config.HashtagCaseKeep, "", ""},
+		{config.HashtagCaseKeep, "golang", "golang"},
+		{config.HashtagCaseKeep, "go programming", "goprogramming"},
+		{config.HashtagCaseKeep, "go-programming", "goprogramming"},
+		{config.HashtagCaseKeep, " spaces ", "spaces"},
+		{config.HashtagCaseKeep, "-hyphens-", "hyphens"},
+		{config.HashtagCaseKeep, "  multiple   spaces  ", "multiplespaces"},
+		{config.HashtagCaseKeep, "--multiple---hyphens--", "multiplehyphens"},
+		{config.HashtagCaseKeep, "--spaces and hyphens--", "spacesandhyphens"},
+		{config.HashtagCaseKeep, "Machine Learning", "MachineLearning"},
+		{config.HashtagCaseKeep, "Node.js", "Nodejs"},
+		{config.HashtagCaseKeep, "CI/CD", "CICD"},
+		{config.HashtagCaseKeep, "R&D", "RD"},
+		{config.HashtagCaseKeep, "🚀 rockets", "rockets"},
+		{config.HashtagCaseKeep, "🚀", ""},
+		{config.HashtagCaseKeep, "snake_case", "snake_case"},
+		{config.HashtagCaseKeep, "путешествия", "путешествия"},
+		{config.HashtagCaseKeep, "日本語", "日本語"},
+		{config.HashtagCaseKeep, "हिन्दी", "हिन्दी"},
+		{config.HashtagCaseLower, "Machine Learning", "machinelearning"},
+		{config.HashtagCaseLower, "machine-learning", "machinelearning"},
+		{config.HashtagCaseCamel, "Machine Learning", "machineLearning"},
+		{config.HashtagCaseCamel, "machine_learning", "machineLearning"},
+		{config.HashtagCaseCamel, "iOS development", "iOSDevelopment"},
+		{config.HashtagCasePascal, "machine learning", "MachineLearning"},
+		{config.HashtagCasePascal, "iOS development", "IOSDevelopment"},
+		{config.HashtagCaseSnake, "Machine Learning", "machine_learning"},
+		{config.HashtagCaseSnake, "CI/CD", "ci_cd"},
+		{config.HashtagCaseSnake, "año nuevo", "año_nuevo"},
+	}
+
+	for _, test := range tests {
+		got := New(&config.HashtagsConfig{Case: test.style}).Hashtag(test.input)
+		if got != test.expected {
+			t.Errorf("For input %q with case %q, expected %q, but got %q", test.input, test.style, test.expected, got)
+		}
+	}
+}
+
+func TestNormalizer_HashtagTransliterate(t *testing.T) {
+	n := New(&config.HashtagsConfig{Case: config.HashtagCaseSnake, Transliterate: true})
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"Año Nuevo", "ano_nuevo"},
+		{"Crème Brûlée", "creme_brulee"},
+		{"Straße", "strasse"},
+		{"Łódź", "lodz"},
+		{"путешествия", "путешествия"},
+	}
+
+	for _, test := range tests {
+		if got := n.Hashtag(test.input); got != test.expected {
+			t.Errorf("For input %q, expected %q, but got %q", test.input, test.expected, got)
+		}
+	}
+}
+
+func TestNormalizer_Hashtags(t *testing.T) {
+	cfg := &config.HashtagsConfig{
+		Case:      config.HashtagCaseKeep,
+		Aliases:   []config.HashtagAlias{{Tag: "Machine Learning", Synonyms: []string{"ML", "machine-learning"}}},
+		Blocklist: []string{"misc", "to read"},
+	}
+
+	tests := []struct {
+		name     string
+		max      int
+		tags     []string
+		expected []string
+	}{
+		{"No tags", 0, nil, nil},
+		{"Aliases are replaced", 0, []string{"ml", "golang"}, []string{"MachineLearning", "golang"}},
+		{"Duplicates are removed", 0, []string{"Machine Learning", "machine-learning", "ML"}, []string{"MachineLearning"}},
+		{"Duplicates are case-insensitive", 0, []string{"golang", "GoLang"}, []string{"golang"}},
+		{"Blocked tags are removed", 0, []string{"Misc", "golang", "To-Read"}, []string{"golang"}},
+		{"Tags without valid characters are removed", 0, []string{"🚀", "golang"}, []string{"golang"}},
+		{"Maximum number of hashtags", 2, []string{"golang", "🚀", "misc", "ml", "rust"}, []string{"golang", "MachineLearning"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg.Max = tt.max
+			got := New(cfg).Hashtags(tt.tags)
+			if !slices.Equal(got, tt.expected) {
+				t.Errorf("Hashtags(%v) = %v, expected %v", tt.tags, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNormalizer_Tags(t *testing.T) {
+	n := New(&config.HashtagsConfig{
+		Case:      config.HashtagCaseSnake,
+		Aliases:   []config.HashtagAlias{{Tag: "Machine Learning", Synonyms: []string{"ml"}}},
+		Blocklist: []string{"misc"},
+		Max:       1, // Only limits the reply.
+	})
+
+	tests := []struct {
+		name     string
+		hashtags []string
+		expected []string
+	}{
+		{"No hashtags", nil, nil},
+		{"Hashtags are formatted", []string{"GoLang", "Open_Source"}, []string{"golang", "open_source"}},
+		{"Aliases are replaced with the canonical tag", []string{"ML", "golang"}, []string{"Machine Learning", "golang"}},
+		{"Blocked hashtags are removed", []string{"MISC", "golang"}, []string{"golang"}},
+		{"Duplicates are removed", []string{"golang", "GOLANG"}, []string{"golang"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := n.Tags(tt.hashtags)
+			if !slices.Equal(got, tt.expected) {
+				t.Errorf("Tags(%v) = %v, expected %v", tt.hashtags, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNormalizer_RoundTrip(t *testing.T) {
+	// A tag sent to Karakeep from a hashtag must be rendered as the same hashtag.
+	for _, style := range []string{config.HashtagCaseKeep, config.HashtagCaseLower, config.HashtagCaseCamel, config.HashtagCasePascal, config.HashtagCaseSnake} {
+		n := New(&config.HashtagsConfig{Case: style, Transliterate: true})
+		for _, tag := range []string{"Machine Learning", "node.js", "Año-Nuevo"} {
+			hashtag := n.Hashtag(tag)
+			if got := n.Hashtag(n.Tags([]string{hashtag})[0]); got != hashtag {
+				t.Errorf("For tag %q with case %q, expected hashtag %q to round trip, but got %q", tag, style, hashtag, got)
+			}
+		}
+	}
+}
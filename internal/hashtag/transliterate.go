@@ -0,0 +1,63 @@
+package hashtag
+
+import (
+	"strings"
+	"unicode"
+)
+
+// latinLetters maps the ASCII equivalent of accented Latin letters to the
+// letters, in lowercase.
+var latinLetters = map[string]string{
+	"a":  "àáâãäåāăą",
+	"c":  "çćĉċč",
+	"d":  "ďđð",
+	"e":  "èéêëēĕėęě",
+	"g":  "ĝğġģ",
+	"h":  "ĥħ",
+	"i":  "ìíîïĩīĭįı",
+	"j":  "ĵ",
+	"k":  "ķ",
+	"l":  "ĺļľŀł",
+	"n":  "ñńņňŉ",
+	"o":  "òóôõöøōŏő",
+	"r":  "ŕŗř",
+	"s":  "śŝşšș",
+	"t":  "ţťŧț",
+	"u":  "ùúûüũūŭůűų",
+	"w":  "ŵ",
+	"y":  "ýÿŷ",
+	"z":  "źżž",
+	"ss": "ß",
+	"ae": "æ",
+	"oe": "œ",
+	"th": "þ",
+}
+
+// transliterations maps accented Latin letters, in lowercase and uppercase, to
+// their ASCII equivalent.
+var transliterations = func() map[rune]string {
+	m := make(map[rune]string)
+	for ascii, letters := range latinLetters {
+		for _, r := range letters {
+			m[r] = ascii
+			if upper := unicode.ToUpper(r); upper != r {
+				m[upper] = strings.ToUpper(ascii)
+			}
+		}
+	}
+	return m
+}()
+
+// transliterate replaces the accented Latin letters of s with their ASCII
+// equivalent. Letters of other scripts are kept as they are.
+func transliterate(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if ascii, ok := transliterations[r]; ok {
+			b.WriteString(ascii)
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
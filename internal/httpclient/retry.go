@@ -0,0 +1,209 @@
+// Package httpclient provides HTTP transports shared by the API clients of the
+// bot.
+//
+// RetryTransport retries requests that fail with transient errors, using
+// exponential backoff between attempts. Retries are idempotency-aware:
+// requests with idempotent methods (GET, HEAD, OPTIONS, PUT, DELETE...) are
+// retried on connection errors and on 429, 502, 503 and 504 responses, while
+// POST and PATCH requests are only retried when they carry an Idempotency-Key
+// header, or when the connection failed before the request was sent.
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"time"
+
+	"github.com/Madh93/karakeepbot/internal/logging"
+)
+
+// IdempotencyKeyHeader is the header that marks a non-idempotent request as
+// safe to retry.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// Default backoff between attempts.
+const (
+	DefaultMinBackoff = 500 * time.Millisecond
+	DefaultMaxBackoff = 10 * time.Second
+)
+
+// retryableStatuses are the response status codes considered transient.
+var retryableStatuses = map[int]bool{
+	http.StatusTooManyRequests:    true,
+	http.StatusBadGateway:         true,
+	http.StatusServiceUnavailable: true,
+	http.StatusGatewayTimeout:     true,
+}
+
+// RetryTransport is an http.RoundTripper that retries failed requests.
+type RetryTransport struct {
+	Base       http.RoundTripper // Transport used for each attempt. If nil, http.DefaultTransport is used.
+	Retries    int               // Maximum number of retries after the first attempt.
+	Timeout    time.Duration     // Timeout of each attempt, including reading the response body. 0 means no timeout.
+	MinBackoff time.Duration     // Backoff before the first retry, doubled on every retry.
+	MaxBackoff time.Duration     // Maximum backoff between attempts.
+	Logger     *logging.Logger   // Logger used to report retried attempts. If nil, nothing is logged.
+}
+
+// NewRetryTransport creates a new RetryTransport wrapping base with the
+// default backoff.
+func NewRetryTransport(base http.RoundTripper, retries int, timeout time.Duration, logger *logging.Logger) *RetryTransport {
+	return &RetryTransport{
+		Base:       base,
+		Retries:    retries,
+		Timeout:    timeout,
+		MinBackoff: DefaultMinBackoff,
+		MaxBackoff: DefaultMaxBackoff,
+		Logger:     logger,
+	}
+}
+
+// RoundTrip executes the request, retrying it on transient failures.
+func (t *RetryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 {
+			body, err := rewindBody(req)
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+
+		resp, sent, err := t.roundTrip(req)
+
+		retry := attempt < t.Retries && t.shouldRetry(req, resp, sent, err)
+		if !retry {
+			return resp, err
+		}
+
+		backoff := t.backoff(attempt)
+		if t.Logger != nil {
+			attrs := []any{"method", req.Method, "url", req.URL.Redacted(), "attempt", attempt + 1, "backoff", backoff}
+			if err != nil {
+				attrs = append(attrs, "error", err)
+			} else {
+				attrs = append(attrs, "status", resp.StatusCode)
+			}
+			t.Logger.Warn("HTTP request failed, retrying", attrs...)
+		}
+
+		// Discard the failed response so the connection can be reused.
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			_ = resp.Body.Close()
+		}
+
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(backoff):
+		}
+	}
+}
+
+// roundTrip executes a single attempt, with its own timeout. It also reports
+// whether the request was (at least partially) sent to the server.
+func (t *RetryTransport) roundTrip(req *http.Request) (resp *http.Response, sent bool, err error) {
+	ctx := req.Context()
+	cancel := context.CancelFunc(func() {})
+	if t.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, t.Timeout)
+	}
+
+	trace := &httptrace.ClientTrace{
+		WroteHeaders: func() { sent = true },
+	}
+	ctx = httptrace.WithClientTrace(ctx, trace)
+
+	resp, err = t.base().RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, sent, err
+	}
+
+	// Keep the timeout running until the body is closed.
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, sent, nil
+}
+
+// shouldRetry reports whether a failed attempt can be retried.
+func (t *RetryTransport) shouldRetry(req *http.Request, resp *http.Response, sent bool, err error) bool {
+	// Never retry when the caller gave up.
+	if req.Context().Err() != nil {
+		return false
+	}
+
+	// The body can't be sent again.
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+
+	if err != nil {
+		return isIdempotent(req) || !sent
+	}
+
+	return retryableStatuses[resp.StatusCode] && isIdempotent(req)
+}
+
+// backoff returns the time to wait before the retry following attempt.
+func (t *RetryTransport) backoff(attempt int) time.Duration {
+	backoff := t.MinBackoff
+	for range attempt {
+		backoff *= 2
+		if t.MaxBackoff > 0 && backoff >= t.MaxBackoff {
+			return t.MaxBackoff
+		}
+	}
+	return backoff
+}
+
+// base returns the underlying transport.
+func (t *RetryTransport) base() http.RoundTripper {
+	if t.Base == nil {
+		return http.DefaultTransport
+	}
+	return t.Base
+}
+
+// isIdempotent reports whether the request can be sent more than once without
+// side effects.
+func isIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return req.Header.Get(IdempotencyKeyHeader) != ""
+}
+
+// rewindBody returns a fresh copy of the request body for a new attempt.
+func rewindBody(req *http.Request) (io.ReadCloser, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return req.Body, nil
+	}
+	if req.GetBody == nil {
+		return nil, errors.New("request body can't be rewound")
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, fmt.Errorf("failed to rewind request body: %w", err)
+	}
+	return body, nil
+}
+
+// cancelBody cancels the context of an attempt when its body is closed.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+// Close closes the body and releases the attempt context.
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
@@ -0,0 +1,171 @@
+package httpclient
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newFlakyServer returns a server that fails the first failures requests with
+// the given status code, and records every request body.
+func newFlakyServer(t *testing.T, failures int, status int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		body, _ := io.ReadAll(r.Body)
+		if int(n) <= failures {
+			w.WriteHeader(status)
+			return
+		}
+		_, _ = w.Write(body)
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &calls
+}
+
+func newTestTransport(retries int) *RetryTransport {
+	return &RetryTransport{Retries: retries, MinBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond}
+}
+
+func TestRetryTransport(t *testing.T) {
+	tests := []struct {
+		name           string
+		method         string
+		idempotencyKey string
+		failures       int
+		status         int
+		retries        int
+		expectedStatus int
+		expectedCalls  int32
+	}{
+		{"GET succeeds at first", http.MethodGet, "", 0, http.StatusBadGateway, 3, http.StatusOK, 1},
+		{"GET retried on 502", http.MethodGet, "", 2, http.StatusBadGateway, 3, http.StatusOK, 3},
+		{"GET retries exhausted", http.MethodGet, "", 5, http.StatusServiceUnavailable, 2, http.StatusServiceUnavailable, 3},
+		{"GET not retried on 500", http.MethodGet, "", 1, http.StatusInternalServerError, 3, http.StatusInternalServerError, 1},
+		{"POST not retried on 502", http.MethodPost, "", 1, http.StatusBadGateway, 3, http.StatusBadGateway, 1},
+		{"POST with idempotency key retried on 502", http.MethodPost, "key", 1, http.StatusBadGateway, 3, http.StatusOK, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, calls := newFlakyServer(t, tt.failures, tt.status)
+			client := &http.Client{Transport: newTestTransport(tt.retries)}
+
+			req, err := http.NewRequest(tt.method, server.URL, strings.NewReader("payload"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.idempotencyKey != "" {
+				req.Header.Set(IdempotencyKeyHeader, tt.idempotencyKey)
+			}
+
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			_ = resp.Body.Close()
+
+			if resp.StatusCode != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, resp.StatusCode)
+			}
+			if got := calls.Load(); got != tt.expectedCalls {
+				t.Errorf("Expected %d calls, got %d", tt.expectedCalls, got)
+			}
+			if resp.StatusCode == http.StatusOK && string(body) != "payload" {
+				t.Errorf("Expected the request body to be sent again, got %q", body)
+			}
+		})
+	}
+}
+
+func TestRetryTransport_ConnectionErrors(t *testing.T) {
+	// Reserve an address and close it so connections are refused.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	_ = listener.Close()
+
+	var attempts atomic.Int32
+	transport := newTestTransport(2)
+	transport.Base = roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		attempts.Add(1)
+		return http.DefaultTransport.RoundTrip(req)
+	})
+	client := &http.Client{Transport: transport}
+
+	// POST requests are retried when the connection failed before sending.
+	_, err = client.Post("http://"+addr, "text/plain", strings.NewReader("payload"))
+	if err == nil {
+		t.Fatal("Expected a connection error")
+	}
+	if got := attempts.Load(); got != 3 {
+		t.Errorf("Expected 3 attempts, got %d", got)
+	}
+}
+
+func TestRetryTransport_Timeout(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			time.Sleep(200 * time.Millisecond)
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	t.Cleanup(server.Close)
+
+	transport := newTestTransport(1)
+	transport.Timeout = 50 * time.Millisecond
+	client := &http.Client{Transport: transport}
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil || string(body) != "ok" {
+		t.Errorf("Expected body %q, got %q (error: %v)", "ok", body, err)
+	}
+	if got := calls.Load(); got != 2 {
+		t.Errorf("Expected 2 calls, got %d", got)
+	}
+}
+
+func TestRetryTransport_backoff(t *testing.T) {
+	transport := &RetryTransport{MinBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+
+	tests := []struct {
+		attempt  int
+		expected time.Duration
+	}{
+		{0, 100 * time.Millisecond},
+		{1, 200 * time.Millisecond},
+		{3, 800 * time.Millisecond},
+		{4, time.Second},
+		{10, time.Second},
+	}
+
+	for _, tt := range tests {
+		if got := transport.backoff(tt.attempt); got != tt.expected {
+			t.Errorf("For attempt %d, expected backoff %s, got %s", tt.attempt, tt.expected, got)
+		}
+	}
+}
+
+// roundTripperFunc adapts a function to an http.RoundTripper.
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
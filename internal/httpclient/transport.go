@@ -0,0 +1,95 @@
+package httpclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+)
+
+// TransportOptions configures the transport built by NewTransport.
+type TransportOptions struct {
+	CACert             string            // Path to a PEM bundle of CAs trusted in addition to the system ones.
+	ClientCert         string            // Path to a PEM client certificate for mutual TLS.
+	ClientKey          string            // Path to the PEM private key of the client certificate.
+	InsecureSkipVerify bool              // Whether to skip the verification of server certificates.
+	ProxyURL           string            // Proxy URL. If empty, the proxy is taken from the environment.
+	Headers            map[string]string // Headers added to every request.
+}
+
+// NewTransport builds an HTTP transport from the default one, with the given
+// TLS, proxy and header options.
+func NewTransport(opts TransportOptions) (http.RoundTripper, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if opts.ProxyURL != "" {
+		// Don't include the parsing error, as it contains the full URL.
+		proxyURL, err := url.Parse(opts.ProxyURL)
+		if err != nil {
+			return nil, errors.New("invalid proxy URL")
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	tlsConfig, err := newTLSConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	transport.TLSClientConfig = tlsConfig
+
+	if len(opts.Headers) == 0 {
+		return transport, nil
+	}
+	return &headerTransport{base: transport, headers: opts.Headers}, nil
+}
+
+// newTLSConfig returns the TLS configuration for the given options.
+func newTLSConfig(opts TransportOptions) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: opts.InsecureSkipVerify,
+	}
+
+	if opts.CACert != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		pem, err := os.ReadFile(opts.CACert)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %s", opts.CACert)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if opts.ClientCert != "" || opts.ClientKey != "" {
+		cert, err := tls.LoadX509KeyPair(opts.ClientCert, opts.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+// headerTransport adds static headers to every request.
+type headerTransport struct {
+	base    http.RoundTripper
+	headers map[string]string
+}
+
+// RoundTrip executes the request with the static headers added.
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	for name, value := range t.headers {
+		req.Header.Set(name, value)
+	}
+	return t.base.RoundTrip(req)
+}
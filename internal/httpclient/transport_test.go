@@ -0,0 +1,139 @@
+package httpclient
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writePEM writes a PEM block to a new file in dir and returns its path.
+func writePEM(t *testing.T, dir, name, blockType string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: data}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// writeClientCert generates a self-signed client certificate and returns the
+// paths of the certificate and its key.
+func writeClientCert(t *testing.T, dir string) (certPath, keyPath string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	cert, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return writePEM(t, dir, "client.crt", "CERTIFICATE", cert), writePEM(t, dir, "client.key", "EC PRIVATE KEY", keyDER)
+}
+
+func TestNewTransport(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.TLS.PeerCertificates) == 0 {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.Header.Get("Cf-Access-Client-Id") != "id" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequestClientCert}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	dir := t.TempDir()
+	caPath := writePEM(t, dir, "ca.pem", "CERTIFICATE", server.Certificate().Raw)
+	certPath, keyPath := writeClientCert(t, dir)
+	headers := map[string]string{"CF-Access-Client-Id": "id"}
+
+	tests := []struct {
+		name           string
+		opts           TransportOptions
+		expectedStatus int // 0 means a connection error is expected.
+	}{
+		{"Unknown CA", TransportOptions{ClientCert: certPath, ClientKey: keyPath, Headers: headers}, 0},
+		{"Custom CA", TransportOptions{CACert: caPath, ClientCert: certPath, ClientKey: keyPath, Headers: headers}, http.StatusOK},
+		{"Insecure skip verify", TransportOptions{InsecureSkipVerify: true, ClientCert: certPath, ClientKey: keyPath, Headers: headers}, http.StatusOK},
+		{"Without client certificate", TransportOptions{CACert: caPath, Headers: headers}, http.StatusUnauthorized},
+		{"Without headers", TransportOptions{CACert: caPath, ClientCert: certPath, ClientKey: keyPath}, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport, err := NewTransport(tt.opts)
+			if err != nil {
+				t.Fatalf("NewTransport() returned an unexpected error: %v", err)
+			}
+
+			resp, err := (&http.Client{Transport: transport}).Get(server.URL)
+			if tt.expectedStatus == 0 {
+				if err == nil {
+					_ = resp.Body.Close()
+					t.Fatal("Expected a connection error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			_ = resp.Body.Close()
+			if resp.StatusCode != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, resp.StatusCode)
+			}
+		})
+	}
+}
+
+func TestNewTransport_InvalidOptions(t *testing.T) {
+	dir := t.TempDir()
+	invalidPEM := filepath.Join(dir, "invalid.pem")
+	if err := os.WriteFile(invalidPEM, []byte("not a certificate"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		opts TransportOptions
+	}{
+		{"Missing CA bundle", TransportOptions{CACert: filepath.Join(dir, "missing.pem")}},
+		{"Invalid CA bundle", TransportOptions{CACert: invalidPEM}},
+		{"Invalid client certificate", TransportOptions{ClientCert: invalidPEM, ClientKey: invalidPEM}},
+		{"Invalid proxy URL", TransportOptions{ProxyURL: "http://user:pass@[::1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewTransport(tt.opts); err == nil {
+				t.Error("Expected an error, got nil")
+			}
+		})
+	}
+}
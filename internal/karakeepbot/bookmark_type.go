@@ -110,3 +110,18 @@ func ToJSONReader(b BookmarkType) (io.Reader, error) {
 	}
 	return bytes.NewReader(data), nil
 }
+
+// bookmarkTypeOf returns the Karakeep type of the bookmark ("link", "text" or
+// "asset").
+func bookmarkTypeOf(b BookmarkType) string {
+	switch b := b.(type) {
+	case *LinkBookmark:
+		return b.Type
+	case *TextBookmark:
+		return b.Type
+	case *AssetBookmark:
+		return b.Type
+	default:
+		return ""
+	}
+}
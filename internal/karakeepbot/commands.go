@@ -0,0 +1,229 @@
+package karakeepbot
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/go-telegram/bot/models"
+)
+
+// botCommand represents a command that users can send to the bot.
+type botCommand struct {
+	role        Role   // Minimum role required to run the command.
+	usage       string // Arguments accepted by the command.
+	description string // Short description of the command.
+	run         func(ctx context.Context, msg *TelegramMessage, args []string)
+}
+
+// commands returns the commands supported by the bot, indexed by name.
+func (kb *KarakeepBot) commands() map[string]botCommand {
+	return map[string]botCommand{
+		"allow": {
+			role:        RoleAdmin,
+			usage:       "<user_id> [admin|contributor|reader]",
+			description: "Grant a role to a user (or reply to one of their messages)",
+			run:         kb.handleAllowCommand,
+		},
+		"revoke": {
+			role:        RoleAdmin,
+			usage:       "<user_id>",
+			description: "Revoke the role granted to a user (or reply to one of their messages)",
+			run:         kb.handleRevokeCommand,
+		},
+		"tags": {
+			role:        RoleReader,
+			description: "List the tags by number of bookmarks",
+			run:         kb.handleTagsCommand,
+		},
+		"tag": {
+			role:        RoleReader,
+			usage:       "<name> | rename <old> <new> | merge <from> <into>",
+			description: "List the recent bookmarks with a tag (renaming and merging tags requires the admin role)",
+			run:         kb.handleTagCommand,
+		},
+		"lists": {
+			role:        RoleReader,
+			description: "List the Karakeep lists, including smart lists",
+			run:         kb.handleListsCommand,
+		},
+		"list": {
+			role:        RoleReader,
+			usage:       "<name>",
+			description: "List the recent bookmarks in a list",
+			run:         kb.handleListCommand,
+		},
+		"random": {
+			role:        RoleReader,
+			usage:       "[tag|list]",
+			description: "Show a random bookmark, optionally with a tag (#name) or in a list (@name)",
+			run:         kb.handleRandomCommand,
+		},
+		"export": {
+			role:        RoleReader,
+			usage:       "[tag|list] [markdown|json|html]",
+			description: "Send a file with the bookmarks, optionally with a tag (#name) or in a list (@name)",
+			run:         kb.handleExportCommand,
+		},
+		"stats": {
+			role:        RoleReader,
+			usage:       "[--since <when>]",
+			description: "Show the bookmarks saved per period, type, chat, domain and tag, 30 days by default",
+			run:         kb.handleStatsCommand,
+		},
+		"remind": {
+			role:        RoleContributor,
+			usage:       "<when>",
+			description: "Post a bookmark again later (reply to my message about it), e.g. 3d or tomorrow 9am",
+			run:         kb.handleRemindCommand,
+		},
+		"reminders": {
+			role:        RoleContributor,
+			description: "List and cancel the reminders of this chat",
+			run:         kb.handleRemindersCommand,
+		},
+	}
+}
+
+// parseCommand returns the command name and arguments if the message starts
+// with a bot command. Commands addressed to other bots (e.g. "/start@otherbot")
+// are reported with an empty name.
+func (kb *KarakeepBot) parseCommand(msg *TelegramMessage) (name string, args []string, ok bool) {
+	if len(msg.Entities) == 0 || msg.Entities[0].Type != models.MessageEntityTypeBotCommand || msg.Entities[0].Offset != 0 {
+		return "", nil, false
+	}
+
+	// Commands are ASCII, so UTF-16 offsets match byte offsets.
+	length := min(msg.Entities[0].Length, len(msg.Text))
+	command := strings.TrimPrefix(msg.Text[:length], "/")
+	args = strings.Fields(msg.Text[length:])
+
+	command, username, addressed := strings.Cut(command, "@")
+	if addressed && !strings.EqualFold(username, kb.telegram.username) {
+		return "", nil, true
+	}
+
+	return strings.ToLower(command), args, true
+}
+
+// handleCommand runs the command sent by a user with the given role.
+func (kb *KarakeepBot) handleCommand(ctx context.Context, msg *TelegramMessage, role Role, name string, args []string) {
+	if name == "" {
+		kb.logger.Debug("Ignoring command addressed to another bot", msg.Attrs()...)
+		return
+	}
+
+	cmd, ok := kb.commands()[name]
+	if !ok {
+		kb.logger.Debug("Received unknown command", append(msg.Attrs(), "command", name)...)
+		kb.reply(ctx, msg, fmt.Sprintf("❓ Unknown command /%s. Available commands:\n%s", name, kb.commandsHelp(role)))
+		return
+	}
+
+	if role < cmd.role {
+		kb.logger.Warn("Denied command: insufficient role", append(msg.Attrs(), "command", name, "role", role, "required_role", cmd.role)...)
+		kb.reply(ctx, msg, fmt.Sprintf("⛔ /%s requires the %s role.", name, cmd.role))
+		return
+	}
+
+	kb.logger.Info("Running command", append(msg.Attrs(), "command", name, "role", role)...)
+	cmd.run(ctx, msg, args)
+}
+
+// commandsHelp returns the list of commands available for the role.
+func (kb *KarakeepBot) commandsHelp(role Role) string {
+	commands := kb.commands()
+	names := make([]string, 0, len(commands))
+	for name, cmd := range commands {
+		if role >= cmd.role {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return "(none)"
+	}
+	slices.Sort(names)
+
+	lines := make([]string, len(names))
+	for i, name := range names {
+		cmd := commands[name]
+		lines[i] = strings.TrimSpace(fmt.Sprintf("/%s %s", name, cmd.usage)) + " - " + cmd.description
+	}
+	return strings.Join(lines, "\n")
+}
+
+// reply sends a reply to the message, logging any error.
+func (kb *KarakeepBot) reply(ctx context.Context, msg *TelegramMessage, text string) {
+	if _, err := kb.telegram.SendReply(ctx, msg, text); err != nil {
+		kb.logger.Error("Failed to send reply to user", msg.AttrsWithError(err)...)
+	}
+}
+
+// commandTarget returns the user targeted by an admin command: the author of
+// the replied message, or the user ID passed as first argument. The remaining
+// arguments are returned.
+func commandTarget(msg *TelegramMessage, args []string) (int64, []string, error) {
+	if reply := msg.ReplyToMessage; reply != nil && reply.From != nil && !reply.From.IsBot {
+		return reply.From.ID, args, nil
+	}
+	if len(args) == 0 {
+		return 0, nil, fmt.Errorf("missing user ID")
+	}
+	userID, err := parseUserID(args[0])
+	return userID, args[1:], err
+}
+
+// handleAllowCommand grants a role to a user.
+func (kb *KarakeepBot) handleAllowCommand(ctx context.Context, msg *TelegramMessage, args []string) {
+	userID, args, err := commandTarget(msg, args)
+	if err != nil {
+		kb.reply(ctx, msg, fmt.Sprintf("⚠️ %v. Usage: /allow <user_id> [admin|contributor|reader]", err))
+		return
+	}
+
+	role := RoleContributor
+	if len(args) > 0 {
+		if role, err = parseRole(strings.ToLower(args[0])); err != nil {
+			kb.reply(ctx, msg, fmt.Sprintf("⚠️ %v. Usage: /allow <user_id> [admin|contributor|reader]", err))
+			return
+		}
+	}
+
+	if err := kb.grants.grant(userID, role); err != nil {
+		kb.logger.Error("Failed to grant role", append(msg.AttrsWithError(err), "target_user_id", userID, "role", role)...)
+		kb.reply(ctx, msg, "⚠️ Failed to save the role, try again later")
+		return
+	}
+
+	kb.logger.Info("Granted role", append(msg.Attrs(), "target_user_id", userID, "role", role)...)
+	kb.reply(ctx, msg, fmt.Sprintf("✅ User %d is now %s.", userID, role))
+}
+
+// handleRevokeCommand revokes the role granted to a user. Roles assigned in
+// the configuration can't be revoked.
+func (kb *KarakeepBot) handleRevokeCommand(ctx context.Context, msg *TelegramMessage, args []string) {
+	userID, _, err := commandTarget(msg, args)
+	if err != nil {
+		kb.reply(ctx, msg, fmt.Sprintf("⚠️ %v. Usage: /revoke <user_id>", err))
+		return
+	}
+
+	revoked, err := kb.grants.revoke(userID)
+	if err != nil {
+		kb.logger.Error("Failed to revoke role", append(msg.AttrsWithError(err), "target_user_id", userID)...)
+		kb.reply(ctx, msg, "⚠️ Failed to save the role, try again later")
+		return
+	}
+
+	text := fmt.Sprintf("✅ Revoked the role granted to user %d.", userID)
+	if !revoked {
+		text = fmt.Sprintf("ℹ️ User %d had no granted role.", userID)
+	}
+	if role := configuredRole(&kb.settings.Load().roles, userID); role != RoleNone {
+		text += fmt.Sprintf(" They are still %s in the configuration.", role)
+	}
+
+	kb.logger.Info("Revoked role", append(msg.Attrs(), "target_user_id", userID, "revoked", revoked)...)
+	kb.reply(ctx, msg, text)
+}
@@ -0,0 +1,280 @@
+package karakeepbot
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Madh93/karakeepbot/internal/config"
+	"github.com/Madh93/karakeepbot/internal/cron"
+	"github.com/Madh93/karakeepbot/internal/scheduler"
+	"github.com/Madh93/karakeepbot/internal/state"
+	"github.com/go-telegram/bot/models"
+)
+
+const (
+	digestsState         = "digests"  // Name of the state document with the last run of each digest.
+	archiveCallbackData  = "archive:" // Prefix of the callback data of the buttons archiving a bookmark.
+	archiveButtonsPerRow = 5          // Number of archive buttons in each row of a digest.
+	maxMessageLength     = 4096       // Maximum number of characters of a Telegram message.
+)
+
+// digestRuns holds when each digest was last posted, which is persisted in the
+// state store so the digests missed while the bot was down are posted on
+// start.
+type digestRuns struct {
+	mu    sync.Mutex
+	store *state.Store
+	runs  map[string]time.Time
+}
+
+// newDigestRuns loads the last runs of the digests from the state store.
+func newDigestRuns(store *state.Store) (*digestRuns, error) {
+	runs := make(map[string]time.Time)
+	if err := store.Load(digestsState, &runs); err != nil {
+		return nil, err
+	}
+	return &digestRuns{store: store, runs: runs}, nil
+}
+
+// last returns when the digest was last posted, if ever.
+func (d *digestRuns) last(key string) (time.Time, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	last, ok := d.runs[key]
+	return last, ok
+}
+
+// record sets when the digest was last posted and persists it.
+func (d *digestRuns) record(key string, at time.Time) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.runs[key] = at
+	return d.store.Save(digestsState, d.runs)
+}
+
+// digestKey returns the key identifying a digest in the state store and the
+// scheduler.
+func digestKey(d config.DigestConfig) string {
+	thread := ""
+	if d.Thread != nil {
+		thread = strconv.Itoa(*d.Thread)
+	}
+	return strings.Join([]string{"digest", strconv.FormatInt(d.Chat, 10), thread, d.Cron, d.List, d.Tag}, "/")
+}
+
+// digestAttrs returns a slice of logging attributes for the digest.
+func digestAttrs(d config.DigestConfig) []any {
+	attrs := []any{"scope", "digest", "chat_id", d.Chat, "cron", d.Cron}
+	if d.Thread != nil {
+		attrs = append(attrs, "thread_id", *d.Thread)
+	}
+	return attrs
+}
+
+// scheduleDigests schedules the configured digests in the configured time
+// zone. Digests are scheduled from their last run, so a digest missed while
+// the bot was down is posted right away.
+func (kb *KarakeepBot) scheduleDigests() {
+	loc := kb.config.Scheduler.Location()
+	for _, d := range kb.config.Digests {
+		schedule, err := cron.Parse(d.Cron)
+		if err != nil {
+			kb.logger.Error("Skipping digest with invalid schedule", append(digestAttrs(d), "error", err)...)
+			continue
+		}
+
+		key := digestKey(d)
+		from, ok := kb.digests.last(key)
+		if !ok {
+			// Remember when the digest was first scheduled, so its first run
+			// is caught up if missed
+			from = kb.clock.Now()
+			if err := kb.digests.record(key, from); err != nil {
+				kb.logger.Error("Failed to persist digest schedule", append(digestAttrs(d), "error", err)...)
+			}
+		}
+
+		if kb.scheduler.Add(key, scheduler.In(schedule, loc), from, func(ctx context.Context, _ time.Time) { kb.postDigest(ctx, d) }) {
+			next, _ := kb.scheduler.Next(key)
+			kb.logger.Info("Scheduled digest", append(digestAttrs(d), "next", next.In(loc))...)
+		}
+	}
+}
+
+// postDigest posts the digest of the bookmarks not archived yet to its chat.
+// Nothing is posted if there are no such bookmarks.
+func (kb *KarakeepBot) postDigest(ctx context.Context, d config.DigestConfig) {
+	attrs := digestAttrs(d)
+	now := kb.clock.Now()
+
+	days := orDefault(d.Days, config.DefaultDigestDays)
+	bookmarks, err := kb.digestBookmarks(ctx, d, now.AddDate(0, 0, -days), orDefault(d.Max, config.DefaultDigestMax))
+	if err != nil {
+		kb.logger.Error("Failed to get digest bookmarks", append(attrs, "error", err)...)
+		return
+	}
+
+	if len(bookmarks) > 0 {
+		text, keyboard := renderDigest(d, days, bookmarks)
+		msg := &TelegramMessage{Chat: models.Chat{ID: d.Chat}, Text: text}
+		if d.Thread != nil {
+			msg.MessageThreadID = *d.Thread
+		}
+		if _, err := kb.telegram.SendNewMessageWithKeyboard(ctx, msg, keyboard); err != nil {
+			kb.logger.Error("Failed to send digest", append(attrs, "error", err)...)
+			return
+		}
+		kb.logger.Info("Posted digest", append(attrs, "bookmarks", len(bookmarks))...)
+	} else {
+		kb.logger.Info("Skipped digest: no unread bookmarks", attrs...)
+	}
+
+	if err := kb.digests.record(digestKey(d), now); err != nil {
+		kb.logger.Error("Failed to persist digest run", append(attrs, "error", err)...)
+	}
+}
+
+// orDefault returns v, or def if v is zero.
+func orDefault(v, def int) int {
+	if v == 0 {
+		return def
+	}
+	return v
+}
+
+// digestBookmarks returns up to limit bookmarks not archived yet that were
+// saved since the given time, most recent first, filtered by the list and tag
+// of the digest.
+func (kb *KarakeepBot) digestBookmarks(ctx context.Context, d config.DigestConfig, since time.Time, limit int) ([]KarakeepBookmark, error) {
+	fetch := kb.karakeep.bookmarksFetcher(ctx)
+	tag := d.Tag
+	switch {
+	case d.List != "":
+		list, err := kb.karakeep.ResolveList(ctx, d.List)
+		if err != nil {
+			return nil, err
+		}
+		if list == nil {
+			return nil, fmt.Errorf("list %q not found", d.List)
+		}
+		fetch = kb.karakeep.listBookmarksFetcher(ctx, list.Id, newestFirst)
+	case d.Tag != "":
+		t, err := kb.findTag(ctx, d.Tag)
+		if err != nil {
+			return nil, err
+		}
+		if t == nil {
+			return nil, fmt.Errorf("tag %q not found", d.Tag)
+		}
+		fetch, tag = kb.karakeep.tagBookmarksFetcher(ctx, t.Id, newestFirst), ""
+	}
+
+	var bookmarks []KarakeepBookmark
+	err := walkBookmarks(fetch, func(bookmark KarakeepBookmark) bool {
+		// Bookmarks come most recent first
+		if created := bookmark.Created(); !created.IsZero() && created.Before(since) {
+			return false
+		}
+		if !bookmark.Archived && (tag == "" || kb.hasTag(bookmark, tag)) {
+			bookmarks = append(bookmarks, bookmark)
+		}
+		return len(bookmarks) < limit
+	})
+	return bookmarks, err
+}
+
+// hasTag reports whether the bookmark has the tag with the given name, which
+// can also be a hashtag as shown by the bot.
+func (kb *KarakeepBot) hasTag(bookmark KarakeepBookmark, name string) bool {
+	hashtags := kb.settings.Load().hashtags
+	name = strings.TrimPrefix(name, "#")
+	for _, tag := range bookmark.Tags {
+		if strings.EqualFold(tag.Name, name) || strings.EqualFold(hashtags.Hashtag(tag.Name), name) {
+			return true
+		}
+	}
+	return false
+}
+
+// renderDigest returns the text of the digest and the buttons to archive each
+// bookmark. Bookmarks that don't fit in a message are left out.
+func renderDigest(d config.DigestConfig, days int, bookmarks []KarakeepBookmark) (string, *models.InlineKeyboardMarkup) {
+	var text string
+	for n := len(bookmarks); n > 0; n-- {
+		bookmarks = bookmarks[:n]
+		header := fmt.Sprintf("📬 Unread bookmarks from the past %d days", days)
+		if d.List != "" {
+			header += fmt.Sprintf(" in %q", d.List)
+		}
+		if d.Tag != "" {
+			header += fmt.Sprintf(" tagged %q", strings.TrimPrefix(d.Tag, "#"))
+		}
+		text = fmt.Sprintf("%s (%d):", header, n) + renderBookmarks(bookmarks, 0) + "\n\nPress 📦 to archive a bookmark."
+		if len([]rune(text)) <= maxMessageLength {
+			break
+		}
+	}
+
+	var rows [][]models.InlineKeyboardButton
+	for i, bookmark := range bookmarks {
+		if i%archiveButtonsPerRow == 0 {
+			rows = append(rows, nil)
+		}
+		button := models.InlineKeyboardButton{Text: fmt.Sprintf("📦 %d", i+1), CallbackData: archiveCallbackData + bookmark.Id}
+		rows[len(rows)-1] = append(rows[len(rows)-1], button)
+	}
+	return text, &models.InlineKeyboardMarkup{InlineKeyboard: rows}
+}
+
+// handleArchiveCallback archives the bookmark of a digest button pressed by a
+// contributor, and removes the button.
+func (kb *KarakeepBot) handleArchiveCallback(ctx context.Context, query *models.CallbackQuery, msg *TelegramMessage, answer func(string)) {
+	bookmarkID := strings.TrimPrefix(query.Data, archiveCallbackData)
+	attrs := append(msg.Attrs(), "bookmark_id", bookmarkID)
+
+	if role := kb.roleOf(query.From.ID); role < RoleContributor {
+		kb.logger.Warn("Denied archiving bookmark: insufficient role", append(attrs, "role", role, "required_role", RoleContributor)...)
+		answer(fmt.Sprintf("⛔ This requires the %s role.", RoleContributor))
+		return
+	}
+
+	if err := kb.karakeep.ArchiveBookmark(ctx, bookmarkID); err != nil {
+		kb.logger.Error("Failed to archive bookmark", append(attrs, "error", err)...)
+		answer("⚠️ Failed to archive the bookmark, try again later")
+		return
+	}
+	kb.logger.Info("Archived bookmark", attrs...)
+
+	if err := kb.telegram.EditKeyboard(ctx, msg, withoutButton(msg.ReplyMarkup, query.Data)); err != nil {
+		kb.logger.Error("Failed to remove archive button", append(attrs, "error", err)...)
+	}
+	answer("📦 Archived")
+}
+
+// withoutButton returns the keyboard without the button with the given
+// callback data, or nil if no buttons are left.
+func withoutButton(keyboard *models.InlineKeyboardMarkup, data string) *models.InlineKeyboardMarkup {
+	if keyboard == nil {
+		return nil
+	}
+	var rows [][]models.InlineKeyboardButton
+	for _, row := range keyboard.InlineKeyboard {
+		var buttons []models.InlineKeyboardButton
+		for _, button := range row {
+			if button.CallbackData != data {
+				buttons = append(buttons, button)
+			}
+		}
+		if len(buttons) > 0 {
+			rows = append(rows, buttons)
+		}
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	return &models.InlineKeyboardMarkup{InlineKeyboard: rows}
+}
@@ -0,0 +1,390 @@
+package karakeepbot
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Madh93/karakeepbot/internal/config"
+	"github.com/Madh93/karakeepbot/internal/logging"
+	"github.com/Madh93/karakeepbot/internal/scheduler"
+	"github.com/Madh93/karakeepbot/internal/state"
+	"github.com/go-telegram/bot/models"
+)
+
+// testNow is the current time of the digest tests.
+var testNow = time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)
+
+// fixedClock is a clock stopped at a time.
+type fixedClock time.Time
+
+func (c fixedClock) Now() time.Time                         { return time.Time(c) }
+func (c fixedClock) After(d time.Duration) <-chan time.Time { return make(chan time.Time) }
+
+// testDigestBookmark returns a bookmark saved the given days (and an hour)
+// before testNow.
+func testDigestBookmark(id string, days int, archived bool, tags ...string) map[string]any {
+	rawTags := []any{}
+	for _, tag := range tags {
+		rawTags = append(rawTags, map[string]any{"id": "t-" + tag, "name": tag, "attachedBy": "human"})
+	}
+	return map[string]any{
+		"id":        id,
+		"archived":  archived,
+		"createdAt": testNow.AddDate(0, 0, -days).Add(-time.Hour).Format(time.RFC3339),
+		"tags":      rawTags,
+		"content":   map[string]any{"type": "link", "url": "https://example.com/" + id, "title": "Bookmark " + id},
+	}
+}
+
+// newTestDigestBot returns a bot connected to a fake Karakeep server with a
+// few bookmarks, a "Reading" list and a "golang" tag, and the archived
+// bookmark IDs.
+func newTestDigestBot(t *testing.T) (*KarakeepBot, func() []string) {
+	t.Helper()
+
+	all := []map[string]any{
+		testDigestBookmark("b1", 1, false, "golang"),
+		testDigestBookmark("b2", 2, true, "golang"),
+		testDigestBookmark("b3", 3, false, "rust"),
+		testDigestBookmark("b4", 4, false, "golang"),
+		testDigestBookmark("b5", 10, false, "golang"),
+	}
+	lists := map[string][]map[string]any{"reading": {all[0], all[1], all[2]}}
+	tags := map[string][]map[string]any{"t-golang": {all[0], all[1], all[3], all[4]}}
+
+	var mu sync.Mutex
+	var archived []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/v1/"), "/")
+		w.Header().Set("Content-Type", "application/json")
+		page := func(bookmarks []map[string]any) {
+			if r.URL.Query().Get("sortOrder") == "asc" {
+				bookmarks = slices.Clone(bookmarks)
+				slices.Reverse(bookmarks)
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{"bookmarks": bookmarks, "nextCursor": nil})
+		}
+
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v1/bookmarks":
+			archived := r.URL.Query().Get("archived")
+			page(slices.DeleteFunc(slices.Clone(all), func(b map[string]any) bool { return archived == "false" && b["archived"].(bool) }))
+		case r.URL.Path == "/api/v1/users/me/stats":
+			_ = json.NewEncoder(w).Encode(map[string]any{"numBookmarks": len(all)})
+		case r.Method == http.MethodGet && len(path) == 2 && path[0] == "bookmarks":
+			i := slices.IndexFunc(all, func(b map[string]any) bool { return b["id"] == path[1] })
+			if i < 0 {
+				http.NotFound(w, r)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(all[i])
+		case r.Method == http.MethodPatch && len(path) == 2 && path[0] == "bookmarks":
+			body, _ := io.ReadAll(r.Body)
+			if string(body) != `{"archived":true}` {
+				http.Error(w, "unexpected body "+string(body), http.StatusBadRequest)
+				return
+			}
+			mu.Lock()
+			archived = append(archived, path[1])
+			mu.Unlock()
+			_ = json.NewEncoder(w).Encode(map[string]any{"id": path[1]})
+		case r.URL.Path == "/api/v1/lists":
+			_ = json.NewEncoder(w).Encode(map[string]any{"lists": []any{map[string]any{"id": "reading", "name": "Reading", "icon": "📚", "type": "manual"}}})
+		case len(path) == 3 && path[0] == "lists":
+			page(lists[path[1]])
+		case r.URL.Path == "/api/v1/tags":
+			_ = json.NewEncoder(w).Encode(map[string]any{"tags": []any{map[string]any{"id": "t-golang", "name": "golang", "numBookmarks": 4, "numBookmarksByAttachedType": map[string]any{}}}})
+		case len(path) == 3 && path[0] == "tags":
+			page(tags[path[1]])
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	store, err := state.New("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	digests, err := newDigestRuns(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kb := newTestRolesBot(t)
+	cfg := newTestConfig()
+	cfg.Karakeep.URL = server.URL
+	kb.config = cfg
+	kb.logger = logging.New(&cfg.Logging)
+	kb.karakeep = createKarakeep(kb.logger, &cfg.Karakeep)
+	kb.digests = digests
+	kb.clock = fixedClock(testNow)
+	kb.scheduler = scheduler.New(kb.clock)
+	return kb, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return archived
+	}
+}
+
+func TestDigestRuns_persistence(t *testing.T) {
+	store, err := state.New(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	runs, err := newDigestRuns(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := runs.record("digest", testNow); err != nil {
+		t.Fatalf("record() returned an unexpected error: %v", err)
+	}
+
+	reloaded, err := newDigestRuns(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last, ok := reloaded.last("digest"); !ok || !last.Equal(testNow) {
+		t.Errorf("Expected the last run to be %v, got %v (%v)", testNow, last, ok)
+	}
+	if _, ok := reloaded.last("other"); ok {
+		t.Error("Expected no last run for an unknown digest")
+	}
+}
+
+func TestKarakeepBot_digestBookmarks(t *testing.T) {
+	kb, _ := newTestDigestBot(t)
+
+	tests := []struct {
+		name     string
+		digest   config.DigestConfig
+		days     int
+		limit    int
+		expected []string
+	}{
+		{"Recent bookmarks", config.DigestConfig{}, 7, 10, []string{"b1", "b3", "b4"}},
+		{"Past days", config.DigestConfig{}, 2, 10, []string{"b1"}},
+		{"Limit", config.DigestConfig{}, 7, 2, []string{"b1", "b3"}},
+		{"Tag", config.DigestConfig{Tag: "#golang"}, 7, 10, []string{"b1", "b4"}},
+		{"List", config.DigestConfig{List: "reading"}, 7, 10, []string{"b1", "b3"}},
+		{"List and tag", config.DigestConfig{List: "reading", Tag: "rust"}, 7, 10, []string{"b3"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bookmarks, err := kb.digestBookmarks(context.Background(), tt.digest, testNow.AddDate(0, 0, -tt.days), tt.limit)
+			if err != nil {
+				t.Fatalf("digestBookmarks() returned an unexpected error: %v", err)
+			}
+			var ids []string
+			for _, bookmark := range bookmarks {
+				ids = append(ids, bookmark.Id)
+			}
+			if !slices.Equal(ids, tt.expected) {
+				t.Errorf("Expected bookmarks %v, got %v", tt.expected, ids)
+			}
+		})
+	}
+
+	t.Run("Unknown list", func(t *testing.T) {
+		if _, err := kb.digestBookmarks(context.Background(), config.DigestConfig{List: "unknown"}, testNow, 10); err == nil {
+			t.Error("Expected an error for an unknown list")
+		}
+	})
+}
+
+func TestKarakeepBot_postDigest(t *testing.T) {
+	tests := []struct {
+		name          string
+		digest        config.DigestConfig
+		expectedCalls []string
+	}{
+		{"Digest", config.DigestConfig{Chat: 1, Cron: "@daily", Days: 4}, []string{"sendMessage: 📬 Unread bookmarks from the past 4 days (2):\n\n1. Bookmark b1\nhttps://example.com/b1\n\n2. Bookmark b3\nhttps://example.com/b3\n\nPress 📦 to archive a bookmark."}},
+		{"Nothing to read", config.DigestConfig{Chat: 1, Cron: "@daily", Tag: "golang", Days: 1}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kb, _ := newTestDigestBot(t)
+			telegram, calls := newTestTelegram(t)
+			kb.telegram = telegram
+
+			kb.postDigest(context.Background(), tt.digest)
+
+			if got := calls(); !slices.Equal(got, tt.expectedCalls) {
+				t.Errorf("Expected calls %q, got %q", tt.expectedCalls, got)
+			}
+			if last, ok := kb.digests.last(digestKey(tt.digest)); !ok || !last.Equal(testNow) {
+				t.Errorf("Expected the run to be recorded at %v, got %v (%v)", testNow, last, ok)
+			}
+		})
+	}
+}
+
+func TestKarakeepBot_scheduleDigests(t *testing.T) {
+	kb, _ := newTestDigestBot(t)
+	missed := config.DigestConfig{Chat: 1, Cron: "0 8 * * *"}
+	upcoming := config.DigestConfig{Chat: 1, Cron: "0 10 * * *"}
+	kb.config.Digests = config.DigestsConfig{missed, upcoming}
+	kb.config.Scheduler.Timezone = "UTC"
+
+	// The missed digest last ran yesterday, before its run today at 8:00
+	if err := kb.digests.record(digestKey(missed), testNow.AddDate(0, 0, -1)); err != nil {
+		t.Fatal(err)
+	}
+
+	kb.scheduleDigests()
+
+	tests := []struct {
+		name     string
+		digest   config.DigestConfig
+		expected time.Time
+	}{
+		{"Missed digest is due", missed, testNow.Add(-time.Hour)},
+		{"New digest", upcoming, testNow.Add(time.Hour)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if next, ok := kb.scheduler.Next(digestKey(tt.digest)); !ok || !next.Equal(tt.expected) {
+				t.Errorf("Expected the next run at %v, got %v (%v)", tt.expected, next, ok)
+			}
+		})
+	}
+
+	if _, ok := kb.digests.last(digestKey(upcoming)); !ok {
+		t.Error("Expected the first schedule of the new digest to be recorded")
+	}
+}
+
+func TestRenderDigest(t *testing.T) {
+	var bookmarks []KarakeepBookmark
+	for i := range 7 {
+		bookmarks = append(bookmarks, KarakeepBookmark{Id: "b" + string(rune('1'+i))})
+	}
+
+	text, keyboard := renderDigest(config.DigestConfig{List: "Reading", Tag: "#golang"}, 7, bookmarks)
+	if !strings.HasPrefix(text, `📬 Unread bookmarks from the past 7 days in "Reading" tagged "golang" (7):`) {
+		t.Errorf("Unexpected header in %q", text)
+	}
+
+	var rows []int
+	for _, row := range keyboard.InlineKeyboard {
+		rows = append(rows, len(row))
+	}
+	if !slices.Equal(rows, []int{5, 2}) {
+		t.Errorf("Expected rows of 5 and 2 buttons, got %v", rows)
+	}
+	if button := keyboard.InlineKeyboard[1][1]; button.Text != "📦 7" || button.CallbackData != "archive:b7" {
+		t.Errorf("Unexpected last button %+v", button)
+	}
+
+	t.Run("Long digests are shortened", func(t *testing.T) {
+		long := make([]KarakeepBookmark, 20)
+		data, _ := json.Marshal(map[string]any{"id": "b", "tags": []any{}, "content": map[string]any{"type": "link", "url": "https://example.com/" + strings.Repeat("x", 280), "title": "Title"}})
+		for i := range long {
+			if err := json.Unmarshal(data, &long[i]); err != nil {
+				t.Fatal(err)
+			}
+		}
+		text, keyboard := renderDigest(config.DigestConfig{}, 7, long)
+		if n := len([]rune(text)); n > maxMessageLength {
+			t.Errorf("Expected at most %d characters, got %d", maxMessageLength, n)
+		}
+		buttons := 0
+		for _, row := range keyboard.InlineKeyboard {
+			buttons += len(row)
+		}
+		if buttons >= len(long) || !strings.Contains(text, "(12):") {
+			t.Errorf("Expected 12 bookmarks, got %d buttons and text %q", buttons, text[:60])
+		}
+	})
+}
+
+func TestKarakeepBot_handleArchiveCallback(t *testing.T) {
+	keyboard := &models.InlineKeyboardMarkup{InlineKeyboard: [][]models.InlineKeyboardButton{
+		{{Text: "📦 1", CallbackData: "archive:b1"}, {Text: "📦 2", CallbackData: "archive:b3"}},
+	}}
+
+	tests := []struct {
+		name             string
+		userID           int64
+		data             string
+		expectedArchived []string
+		expectedCalls    []string
+	}{
+		{"Contributor", 2, "archive:b1", []string{"b1"}, []string{"editMessageReplyMarkup: ", "answerCallbackQuery: 📦 Archived"}},
+		{"Reader", 3, "archive:b1", nil, []string{"answerCallbackQuery: ⛔ This requires the contributor role."}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kb, archived := newTestDigestBot(t)
+			telegram, calls := newTestTelegram(t)
+			kb.telegram = telegram
+			cfg := newTestConfig()
+			cfg.Karakeep.URL = kb.config.Karakeep.URL
+			cfg.Roles.Admins = []int64{1}
+			cfg.Roles.Contributors = []int64{2}
+			cfg.Roles.Readers = []int64{3}
+			kb.settings.Store(newSettings(cfg))
+
+			query := &models.CallbackQuery{
+				ID:      "query",
+				From:    models.User{ID: tt.userID},
+				Data:    tt.data,
+				Message: models.MaybeInaccessibleMessage{Message: &models.Message{ID: 10, Chat: models.Chat{ID: 1}, ReplyMarkup: keyboard}},
+			}
+			kb.handleCallback(context.Background(), query)
+
+			if got := archived(); !slices.Equal(got, tt.expectedArchived) {
+				t.Errorf("Expected archived bookmarks %v, got %v", tt.expectedArchived, got)
+			}
+			if got := calls(); !slices.Equal(got, tt.expectedCalls) {
+				t.Errorf("Expected calls %q, got %q", tt.expectedCalls, got)
+			}
+		})
+	}
+}
+
+func TestWithoutButton(t *testing.T) {
+	keyboard := &models.InlineKeyboardMarkup{InlineKeyboard: [][]models.InlineKeyboardButton{
+		{{CallbackData: "archive:b1"}, {CallbackData: "archive:b2"}},
+		{{CallbackData: "archive:b3"}},
+	}}
+
+	tests := []struct {
+		name     string
+		keyboard *models.InlineKeyboardMarkup
+		data     string
+		expected []int // Number of buttons in each row, nil if the keyboard is removed.
+	}{
+		{"Button in a row", keyboard, "archive:b1", []int{1, 1}},
+		{"Last button of a row", keyboard, "archive:b3", []int{2}},
+		{"Unknown button", keyboard, "archive:b4", []int{2, 1}},
+		{"Last button", &models.InlineKeyboardMarkup{InlineKeyboard: [][]models.InlineKeyboardButton{{{CallbackData: "archive:b1"}}}}, "archive:b1", nil},
+		{"No keyboard", nil, "archive:b1", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var rows []int
+			if got := withoutButton(tt.keyboard, tt.data); got != nil {
+				for _, row := range got.InlineKeyboard {
+					rows = append(rows, len(row))
+				}
+			}
+			if !slices.Equal(rows, tt.expected) {
+				t.Errorf("Expected rows %v, got %v", tt.expected, rows)
+			}
+		})
+	}
+}
@@ -0,0 +1,264 @@
+package karakeepbot
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/Madh93/karakeepbot/internal/config"
+	"github.com/Madh93/karakeepbot/internal/logging"
+	tgbotapi "github.com/go-telegram/bot"
+)
+
+// Clock skew thresholds reported by Doctor.
+const (
+	clockSkewWarning = 30 * time.Second
+	clockSkewFailure = 5 * time.Minute
+)
+
+// doctorTimeout is the maximum time to wait for each network check.
+const doctorTimeout = 10 * time.Second
+
+// doctorReport prints the result of each check and keeps track of failures.
+type doctorReport struct {
+	out    io.Writer
+	failed bool
+}
+
+// ok reports a successful check.
+func (r *doctorReport) ok(check, format string, args ...any) {
+	_, _ = fmt.Fprintf(r.out, "✅ %s: %s\n", check, fmt.Sprintf(format, args...))
+}
+
+// warn reports a check that passed with warnings.
+func (r *doctorReport) warn(check, format string, args ...any) {
+	_, _ = fmt.Fprintf(r.out, "⚠️ %s: %s\n", check, fmt.Sprintf(format, args...))
+}
+
+// fail reports a failed check.
+func (r *doctorReport) fail(check, format string, args ...any) {
+	r.failed = true
+	_, _ = fmt.Fprintf(r.out, "❌ %s: %s\n", check, fmt.Sprintf(format, args...))
+}
+
+// Doctor checks the connectivity with Karakeep and Telegram as well as the
+// local environment, printing the result of every check to out. The
+// configuration must be valid. It returns false if any check failed.
+func Doctor(ctx context.Context, logger *logging.Logger, config *config.Config, out io.Writer) bool {
+	report := &doctorReport{out: out}
+
+	checkKarakeep(ctx, report, logger, &config.Karakeep)
+	checkProxy(report, &config.Telegram)
+	checkTelegram(ctx, report, &config.Telegram)
+	checkTempdir(report, &config.FileProcessor)
+
+	return !report.failed
+}
+
+// checkKarakeep verifies the Karakeep URL and token, and the local clock
+// against the Karakeep server.
+func checkKarakeep(ctx context.Context, report *doctorReport, logger *logging.Logger, config *config.KarakeepConfig) {
+	ctx, cancel := context.WithTimeout(ctx, doctorTimeout)
+	defer cancel()
+
+	karakeep := createKarakeep(logger, config)
+	response, err := karakeep.GetUsersMeWithResponse(ctx)
+	if err != nil {
+		report.fail("Karakeep", "couldn't connect to %s: %v", config.URL, err)
+		return
+	}
+
+	switch response.StatusCode() {
+	case http.StatusOK:
+		user := response.JSON200
+		name := user.Id
+		if user.Name != nil {
+			name = *user.Name
+		}
+		report.ok("Karakeep", "connected to %s as %s", config.URL, name)
+	case http.StatusUnauthorized, http.StatusForbidden:
+		report.fail("Karakeep", "token was rejected by %s (%s)", config.URL, response.Status())
+		return
+	default:
+		report.fail("Karakeep", "unexpected response from %s: %s", config.URL, response.Status())
+		return
+	}
+
+	checkClockSkew(report, "Clock (Karakeep)", response.HTTPResponse)
+}
+
+// checkTelegram verifies the bot token, the webhook status and the local clock
+// against Telegram servers.
+func checkTelegram(ctx context.Context, report *doctorReport, config *config.TelegramConfig) {
+	ctx, cancel := context.WithTimeout(ctx, doctorTimeout)
+	defer cancel()
+
+	opts, err := telegramOptions(config)
+	if err != nil {
+		report.fail("Telegram", "%v", err)
+		return
+	}
+
+	bot, err := tgbotapi.New(config.Token.Value(), append(opts, tgbotapi.WithSkipGetMe())...)
+	if err != nil {
+		report.fail("Telegram", "couldn't create client: %v", err)
+		return
+	}
+
+	me, err := bot.GetMe(ctx)
+	if err != nil {
+		report.fail("Telegram", "getMe failed: %v", err)
+		return
+	}
+	report.ok("Telegram", "authenticated as @%s (ID %d)", me.Username, me.ID)
+
+	info, err := bot.GetWebhookInfo(ctx)
+	switch {
+	case err != nil:
+		report.fail("Webhook", "getWebhookInfo failed: %v", err)
+	case info.URL != "":
+		report.fail("Webhook", "a webhook is set to %s, so the bot won't receive updates through polling", info.URL)
+	case info.LastErrorMessage != "":
+		report.warn("Webhook", "no webhook set, but Telegram reported: %s", info.LastErrorMessage)
+	default:
+		report.ok("Webhook", "no webhook set, %d pending updates", info.PendingUpdateCount)
+	}
+
+	client, err := telegramHTTPClient(config)
+	if err != nil {
+		return
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, "https://api.telegram.org", nil)
+	if err != nil {
+		return
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		report.warn("Clock (Telegram)", "couldn't reach Telegram servers: %v", err)
+		return
+	}
+	_ = resp.Body.Close()
+	checkClockSkew(report, "Clock (Telegram)", resp)
+}
+
+// checkProxy verifies that the Telegram proxy is reachable, if enabled.
+func checkProxy(report *doctorReport, config *config.TelegramConfig) {
+	if !config.ProxyEnabled {
+		return
+	}
+
+	proxyURL, err := url.Parse(config.ProxyURL.Value())
+	if err != nil {
+		report.fail("Proxy", "invalid proxy URL %s", config.ProxyURL)
+		return
+	}
+
+	host := proxyURL.Host
+	if proxyURL.Port() == "" {
+		port := map[string]string{"http": "80", "https": "443", "socks5": "1080"}[proxyURL.Scheme]
+		host = net.JoinHostPort(proxyURL.Hostname(), port)
+	}
+
+	conn, err := net.DialTimeout("tcp", host, doctorTimeout)
+	if err != nil {
+		report.fail("Proxy", "couldn't connect to %s: %v", host, err)
+		return
+	}
+	_ = conn.Close()
+	report.ok("Proxy", "%s is reachable", host)
+}
+
+// checkTempdir verifies that the file processor temporary directory is
+// writable.
+func checkTempdir(report *doctorReport, config *config.FileProcessorConfig) {
+	dir := config.Tempdir
+	if dir == "" {
+		dir = os.TempDir()
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		report.fail("Temporary directory", "couldn't create %s: %v", dir, err)
+		return
+	}
+
+	file, err := os.CreateTemp(dir, "karakeepbot-doctor-*.tmp")
+	if err != nil {
+		report.fail("Temporary directory", "%s is not writable: %v", dir, err)
+		return
+	}
+	_ = file.Close()
+	_ = os.Remove(file.Name())
+
+	report.ok("Temporary directory", "%s is writable", dir)
+}
+
+// checkClockSkew compares the local clock with the Date header of resp.
+func checkClockSkew(report *doctorReport, check string, resp *http.Response) {
+	if resp == nil {
+		return
+	}
+
+	date, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		report.warn(check, "server didn't return a valid Date header")
+		return
+	}
+
+	skew := time.Since(date).Round(time.Second)
+	if skew < 0 {
+		skew = -skew
+	}
+
+	switch {
+	case skew >= clockSkewFailure:
+		report.fail(check, "local clock is off by %s", skew)
+	case skew >= clockSkewWarning:
+		report.warn(check, "local clock is off by %s", skew)
+	default:
+		report.ok(check, "local clock is in sync (skew %s)", skew)
+	}
+}
+
+// SendTest sends a test message to the given chat (and thread, if not zero)
+// and deletes it afterwards, reporting each step to out. It returns an error
+// if the bot can't post in the chat.
+func SendTest(ctx context.Context, logger *logging.Logger, config *config.Config, chatID int64, threadID int, out io.Writer) error {
+	report := &doctorReport{out: out}
+
+	if len(config.Telegram.Allowlist) > 0 && !slices.Contains(config.Telegram.Allowlist, chatID) {
+		report.warn("Allowlist", "chat %d is not in the allowlist, the bot will ignore its messages", chatID)
+	}
+	if threadID != 0 && len(config.Telegram.Threads) > 0 && !slices.Contains(config.Telegram.Threads, threadID) {
+		report.warn("Threads", "thread %d is not in the allowed threads, the bot will ignore its messages", threadID)
+	}
+
+	telegram := createTelegram(logger, &config.Telegram)
+
+	sent, err := telegram.SendMessage(ctx, &tgbotapi.SendMessageParams{
+		ChatID:          chatID,
+		MessageThreadID: threadID,
+		Text:            "🧪 Karakeepbot test message. It will be deleted right away.",
+	})
+	if err != nil {
+		report.fail("Send", "couldn't send a message to chat %d: %v", chatID, err)
+		return fmt.Errorf("couldn't send a message to chat %d: %w", chatID, err)
+	}
+	report.ok("Send", "sent message %d to chat %d", sent.ID, chatID)
+
+	if _, err := telegram.DeleteMessage(ctx, &tgbotapi.DeleteMessageParams{ChatID: chatID, MessageID: sent.ID}); err != nil {
+		report.warn("Delete", "couldn't delete the test message, the bot won't be able to delete original messages: %v", err)
+		return nil
+	}
+	report.ok("Delete", "deleted message %d", sent.ID)
+
+	return nil
+}
@@ -1,14 +1,18 @@
 package karakeepbot
 
-import "context"
+import (
+	"context"
+	"slices"
+)
 
 // enrichBookmark adds Telegram origin metadata to a newly created bookmark.
 // It attaches the #telegram tag plus any hashtags found in the message text or
-// photo caption. Non-fatal on failure.
-func (kb *KarakeepBot) enrichBookmark(ctx context.Context, msg TelegramMessage, bookmark *KarakeepBookmark) {
+// photo caption, normalized with the hashtags rules, and the extra tags
+// configured for the chat. Non-fatal on failure.
+func (kb *KarakeepBot) enrichBookmark(ctx context.Context, msg TelegramMessage, bookmark *KarakeepBookmark, extraTags []string) {
 	tags := []string{"telegram"}
-	for _, tag := range msg.Hashtags() {
-		if tag != "telegram" {
+	for _, tag := range append(kb.settings.Load().hashtags.Tags(msg.Hashtags()), extraTags...) {
+		if !slices.Contains(tags, tag) {
 			tags = append(tags, tag)
 		}
 	}
@@ -0,0 +1,423 @@
+package karakeepbot
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/Madh93/go-karakeep"
+)
+
+const (
+	exportPartSize = 45 << 20 // Maximum size of an export file, below the 50 MB Telegram bots can upload.
+	exportUsage    = "/export [tag|list] [markdown|json|html]"
+)
+
+// exportBookmark is a bookmark as written in exports.
+type exportBookmark struct {
+	ID         string    `json:"id"`
+	Created    time.Time `json:"createdAt"`
+	Type       string    `json:"type"`
+	Title      string    `json:"title,omitempty"`
+	URL        string    `json:"url,omitempty"`
+	AssetURL   string    `json:"assetUrl,omitempty"` // File stored in Karakeep, which requires logging in.
+	Text       string    `json:"text,omitempty"`
+	Summary    string    `json:"summary,omitempty"`
+	Note       string    `json:"note,omitempty"`
+	Tags       []string  `json:"tags"`
+	Archived   bool      `json:"archived"`
+	Favourited bool      `json:"favourited"`
+}
+
+// exportBookmark returns the bookmark as written in exports.
+func (kb *KarakeepBot) exportBookmark(b KarakeepBookmark) exportBookmark {
+	e := exportBookmark{
+		ID:         b.Id,
+		Created:    b.Created(),
+		Text:       b.Text(),
+		Tags:       []string{},
+		Archived:   b.Archived,
+		Favourited: b.Favourited,
+	}
+	if b.Title != nil {
+		e.Title = *b.Title
+	}
+	if b.Summary != nil {
+		e.Summary = *b.Summary
+	}
+	if b.Note != nil {
+		e.Note = *b.Note
+	}
+	for _, tag := range b.Tags {
+		e.Tags = append(e.Tags, tag.Name)
+	}
+
+	content, _ := b.Content.AsBookmarkContent3()
+	e.Type = string(content.Type)
+	if link, ok := b.link(); ok {
+		e.URL = link.Url
+		if e.Title == "" && link.Title != nil {
+			e.Title = *link.Title
+		}
+	} else if e.Type == string(karakeep.BookmarkContent2TypeAsset) {
+		asset, _ := b.Content.AsBookmarkContent2()
+		e.AssetURL = kb.karakeep.AssetURL(asset.AssetId)
+		if e.Title == "" && asset.FileName != nil {
+			e.Title = *asset.FileName
+		}
+	}
+	return e
+}
+
+// heading returns the title of the bookmark or, if it has none, its URL or
+// the beginning of its text.
+func (e exportBookmark) heading() string {
+	for _, heading := range []string{e.Title, e.URL, e.Text} {
+		if heading = strings.Join(strings.Fields(heading), " "); heading == "" {
+			continue
+		}
+		if runes := []rune(heading); len(runes) > maxHeadlineLength {
+			return string(runes[:maxHeadlineLength-1]) + "…"
+		}
+		return heading
+	}
+	return "(untitled)"
+}
+
+// exportFormat writes bookmarks in a file format. Files are written as the
+// header, the entries and the footer, so they can be split at any entry.
+type exportFormat interface {
+	extension() string
+	header(title string) string
+	entry(b exportBookmark, first bool) string // Empty to skip the bookmark.
+	footer() string
+}
+
+// exportFormats are the formats of the exports, by name.
+var exportFormats = map[string]exportFormat{
+	"markdown": markdownExport{},
+	"md":       markdownExport{},
+	"json":     jsonExport{},
+	"html":     netscapeExport{},
+	"netscape": netscapeExport{},
+}
+
+// markdownExport writes a section for every bookmark, with its link, when it
+// was saved, its tags, its text and its summary or note.
+type markdownExport struct{}
+
+func (markdownExport) extension() string { return ".md" }
+
+func (markdownExport) header(title string) string {
+	return "# Karakeep: " + title + "\n"
+}
+
+func (markdownExport) entry(b exportBookmark, _ bool) string {
+	var s strings.Builder
+	heading := markdownEscaper.Replace(b.heading())
+	if b.URL != "" {
+		heading = "[" + heading + "](<" + b.URL + ">)"
+	}
+	s.WriteString("\n## " + heading + "\n\n")
+
+	var details []string
+	if !b.Created.IsZero() {
+		details = append(details, "Saved on "+b.Created.Format(time.DateOnly))
+	}
+	if b.Archived {
+		details = append(details, "archived")
+	}
+	if b.Favourited {
+		details = append(details, "favourite")
+	}
+	if len(b.Tags) > 0 {
+		tags := make([]string, len(b.Tags))
+		for i, tag := range b.Tags {
+			tags[i] = "`" + strings.ReplaceAll(tag, "`", "'") + "`"
+		}
+		details = append(details, "tags: "+strings.Join(tags, " "))
+	}
+	if len(details) > 0 {
+		s.WriteString(strings.Join(details, " · ") + "\n")
+	}
+	if b.AssetURL != "" {
+		s.WriteString("\nFile stored in Karakeep (requires logging in): <" + b.AssetURL + ">\n")
+	}
+
+	if text := strings.TrimSpace(b.Text); text != "" {
+		s.WriteString("\n> " + strings.ReplaceAll(text, "\n", "\n> ") + "\n")
+	}
+	for _, paragraph := range []string{b.Summary, b.Note} {
+		if paragraph = strings.TrimSpace(paragraph); paragraph != "" {
+			s.WriteString("\n" + paragraph + "\n")
+		}
+	}
+	return s.String()
+}
+
+func (markdownExport) footer() string { return "" }
+
+// markdownEscaper escapes the characters with a meaning in Markdown headings
+// and link texts.
+var markdownEscaper = strings.NewReplacer(`\`, `\\`, "[", `\[`, "]", `\]`, "*", `\*`, "_", `\_`, "`", "\\`", "#", `\#`)
+
+// jsonExport writes an object with the array of bookmarks.
+type jsonExport struct{}
+
+func (jsonExport) extension() string { return ".json" }
+
+func (jsonExport) header(string) string { return "{\"bookmarks\": [\n" }
+
+func (jsonExport) entry(b exportBookmark, first bool) string {
+	var data strings.Builder
+	encoder := json.NewEncoder(&data)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("  ", "  ")
+	_ = encoder.Encode(b) // Can't fail with strings and times.
+	entry := "  " + strings.TrimSuffix(data.String(), "\n")
+	if !first {
+		entry = ",\n" + entry
+	}
+	return entry
+}
+
+func (jsonExport) footer() string { return "\n]}\n" }
+
+// netscapeExport writes a Netscape bookmark file, which browsers and most
+// bookmark managers import. It only holds links, so text bookmarks and assets
+// are skipped.
+type netscapeExport struct{}
+
+func (netscapeExport) extension() string { return ".html" }
+
+func (netscapeExport) header(title string) string {
+	return "<!DOCTYPE NETSCAPE-Bookmark-file-1>\n" +
+		"<META HTTP-EQUIV=\"Content-Type\" CONTENT=\"text/html; charset=UTF-8\">\n" +
+		"<TITLE>Bookmarks</TITLE>\n" +
+		"<H1>Karakeep: " + html.EscapeString(title) + "</H1>\n" +
+		"<DL><p>\n"
+}
+
+func (netscapeExport) entry(b exportBookmark, _ bool) string {
+	if b.URL == "" {
+		return ""
+	}
+
+	attrs := `HREF="` + html.EscapeString(b.URL) + `"`
+	if !b.Created.IsZero() {
+		attrs += ` ADD_DATE="` + strconv.FormatInt(b.Created.Unix(), 10) + `"`
+	}
+	if len(b.Tags) > 0 {
+		attrs += ` TAGS="` + html.EscapeString(strings.Join(b.Tags, ",")) + `"`
+	}
+	entry := "    <DT><A " + attrs + ">" + html.EscapeString(b.heading()) + "</A>\n"
+
+	description := b.Summary
+	if description == "" {
+		description = b.Note
+	}
+	if description = strings.Join(strings.Fields(description), " "); description != "" {
+		entry += "    <DD>" + html.EscapeString(description) + "\n"
+	}
+	return entry
+}
+
+func (netscapeExport) footer() string { return "</DL><p>\n" }
+
+// exportWriter writes bookmarks into temporary files of up to maxSize bytes,
+// calling send with each file once it's complete. Only the file being written
+// is kept, so exports of any size use little memory and disk.
+type exportWriter struct {
+	format  exportFormat
+	title   string // Title of the files.
+	dir     string // Directory of the temporary files.
+	maxSize int
+	send    func(path string, part, count int) error
+
+	file  *os.File
+	buf   *bufio.Writer
+	size  int // Bytes written to the current file.
+	count int // Bookmarks written to the current file.
+
+	parts   int // Files started.
+	total   int // Bookmarks written.
+	skipped int // Bookmarks the format can't hold.
+}
+
+// add writes the bookmark, sending the current file first if the bookmark
+// doesn't fit in it. A bookmark larger than maxSize is written alone.
+func (w *exportWriter) add(b exportBookmark) error {
+	entry := w.format.entry(b, w.count == 0)
+	if entry == "" {
+		w.skipped++
+		return nil
+	}
+
+	if w.file != nil && w.size+len(entry)+len(w.format.footer()) > w.maxSize {
+		if err := w.flush(); err != nil {
+			return err
+		}
+		entry = w.format.entry(b, true)
+	}
+	if w.file == nil {
+		if err := w.create(); err != nil {
+			return err
+		}
+	}
+
+	if _, err := w.buf.WriteString(entry); err != nil {
+		return fmt.Errorf("failed to write export file: %w", err)
+	}
+	w.size += len(entry)
+	w.count++
+	w.total++
+	return nil
+}
+
+// create starts a new file with the header of the format.
+func (w *exportWriter) create() error {
+	file, err := os.CreateTemp(w.dir, "karakeepbot-export-*"+w.format.extension())
+	if err != nil {
+		return fmt.Errorf("failed to create export file: %w", err)
+	}
+	w.file, w.buf = file, bufio.NewWriter(file)
+	w.size, w.count = 0, 0
+	w.parts++
+
+	header := w.format.header(w.title)
+	if _, err := w.buf.WriteString(header); err != nil {
+		return fmt.Errorf("failed to write export file: %w", err)
+	}
+	w.size += len(header)
+	return nil
+}
+
+// flush writes the footer of the current file, sends it and removes it.
+func (w *exportWriter) flush() error {
+	defer w.abort()
+
+	if _, err := w.buf.WriteString(w.format.footer()); err != nil {
+		return fmt.Errorf("failed to write export file: %w", err)
+	}
+	if err := w.buf.Flush(); err != nil {
+		return fmt.Errorf("failed to write export file: %w", err)
+	}
+	if err := w.file.Close(); err != nil {
+		return fmt.Errorf("failed to close export file: %w", err)
+	}
+	return w.send(w.file.Name(), w.parts, w.count)
+}
+
+// close sends the current file, if any.
+func (w *exportWriter) close() error {
+	if w.file == nil {
+		return nil
+	}
+	return w.flush()
+}
+
+// abort removes the current file, if any, without sending it.
+func (w *exportWriter) abort() {
+	if w.file == nil {
+		return
+	}
+	_ = w.file.Close()
+	_ = os.Remove(w.file.Name())
+	w.file, w.buf = nil, nil
+}
+
+// exportFileName returns the name of a part of the export of a collection,
+// e.g. "karakeep-golang-2.md". The first part has no number, as the number of
+// parts isn't known when it's sent.
+func exportFileName(collection string, part int, extension string) string {
+	slug := strings.Join(strings.FieldsFunc(strings.ToLower(collection), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), "-")
+	name := "karakeep"
+	if slug != "" {
+		name += "-" + slug
+	}
+	if part > 1 {
+		name += "-" + strconv.Itoa(part)
+	}
+	return name + extension
+}
+
+// handleExportCommand replies with files with all the bookmarks, or those in
+// a list or with a tag (as in /random), in Markdown, JSON or Netscape HTML. The
+// bookmarks are written as they are fetched, and split into several files if
+// they don't fit in one.
+func (kb *KarakeepBot) handleExportCommand(ctx context.Context, msg *TelegramMessage, args []string) {
+	format := exportFormats["markdown"]
+	if n := len(args); n > 0 {
+		if f, ok := exportFormats[strings.ToLower(args[n-1])]; ok {
+			format, args = f, args[:n-1]
+		}
+	}
+
+	name := strings.Join(args, " ")
+	src, err := kb.bookmarkSourceNamed(ctx, name)
+	if err != nil {
+		kb.logger.Error("Failed to get bookmarks to export", msg.AttrsWithError(err)...)
+		kb.reply(ctx, msg, "⚠️ Failed to get the results from Karakeep, try again later")
+		return
+	}
+	if src == nil {
+		kb.reply(ctx, msg, fmt.Sprintf("🔍 No list or tag named %q. Usage: %s", strings.TrimLeft(name, "#@"), exportUsage))
+		return
+	}
+
+	dir := kb.config.FileProcessor.Tempdir
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	w := &exportWriter{
+		format:  format,
+		title:   src.name,
+		dir:     dir,
+		maxSize: exportPartSize,
+		send: func(path string, part, count int) error {
+			caption := fmt.Sprintf("📦 Export of %s (%d bookmarks)", src.name, count)
+			if part > 1 {
+				caption = fmt.Sprintf("📦 Export of %s, part %d (%d bookmarks)", src.name, part, count)
+			}
+			_, err := kb.telegram.SendDocumentReply(ctx, msg, path, exportFileName(src.name, part, format.extension()), caption)
+			return err
+		},
+	}
+	defer w.abort()
+
+	var writeErr error
+	err = walkBookmarks(src.fetch(newestFirst), func(bookmark KarakeepBookmark) bool {
+		writeErr = w.add(kb.exportBookmark(bookmark))
+		return writeErr == nil
+	})
+	if err == nil && writeErr == nil {
+		writeErr = w.close()
+	}
+	if err != nil {
+		kb.logger.Error("Failed to get bookmarks to export", msg.AttrsWithError(err)...)
+		kb.reply(ctx, msg, "⚠️ Failed to get the results from Karakeep, try again later")
+		return
+	}
+	if writeErr != nil {
+		kb.logger.Error("Failed to send export", msg.AttrsWithError(writeErr)...)
+		kb.reply(ctx, msg, "⚠️ Failed to send the export, try again later")
+		return
+	}
+	kb.logger.Info("Exported bookmarks", append(msg.Attrs(), "collection", src.name, "bookmarks", w.total, "skipped", w.skipped, "files", w.parts)...)
+
+	switch {
+	case w.total == 0 && w.skipped == 0:
+		kb.reply(ctx, msg, "🔍 No bookmarks found.")
+	case w.skipped > 0:
+		kb.reply(ctx, msg, fmt.Sprintf("ℹ️ Skipped %d bookmarks without a link, which bookmark files can't hold.", w.skipped))
+	}
+}
@@ -0,0 +1,336 @@
+package karakeepbot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-telegram/bot/models"
+)
+
+// testExportBookmarks returns a link, a text and an asset bookmark.
+func testExportBookmarks() []exportBookmark {
+	created := time.Date(2024, 3, 5, 10, 0, 0, 0, time.UTC)
+	return []exportBookmark{
+		{
+			ID:       "b1",
+			Created:  created,
+			Type:     "link",
+			Title:    "Go [1.23] & more",
+			URL:      "https://go.dev/?a=1&b=2",
+			Summary:  "A summary.",
+			Tags:     []string{"golang", "open source"},
+			Archived: true,
+		},
+		{
+			ID:      "b2",
+			Created: created,
+			Type:    "text",
+			Text:    "First line\nsecond line",
+			Note:    "A note.",
+			Tags:    []string{},
+		},
+		{
+			ID:       "b3",
+			Created:  created,
+			Type:     "asset",
+			Title:    "paper.pdf",
+			AssetURL: "https://karakeep.example.com/api/assets/a1",
+			Tags:     []string{},
+		},
+	}
+}
+
+func TestExportFormats(t *testing.T) {
+	tests := []struct {
+		format   string
+		expected string
+	}{
+		{
+			"markdown",
+			"# Karakeep: #golang\n" +
+				"\n## [Go \\[1.23\\] & more](<https://go.dev/?a=1&b=2>)\n\n" +
+				"Saved on 2024-03-05 · archived · tags: `golang` `open source`\n" +
+				"\nA summary.\n" +
+				"\n## First line second line\n\n" +
+				"Saved on 2024-03-05\n" +
+				"\n> First line\n> second line\n" +
+				"\nA note.\n" +
+				"\n## paper.pdf\n\n" +
+				"Saved on 2024-03-05\n" +
+				"\nFile stored in Karakeep (requires logging in): <https://karakeep.example.com/api/assets/a1>\n",
+		},
+		{
+			"json",
+			`{"bookmarks": [
+  {
+    "id": "b1",
+    "createdAt": "2024-03-05T10:00:00Z",
+    "type": "link",
+    "title": "Go [1.23] & more",
+    "url": "https://go.dev/?a=1&b=2",
+    "summary": "A summary.",
+    "tags": [
+      "golang",
+      "open source"
+    ],
+    "archived": true,
+    "favourited": false
+  },
+  {
+    "id": "b2",
+    "createdAt": "2024-03-05T10:00:00Z",
+    "type": "text",
+    "text": "First line\nsecond line",
+    "note": "A note.",
+    "tags": [],
+    "archived": false,
+    "favourited": false
+  },
+  {
+    "id": "b3",
+    "createdAt": "2024-03-05T10:00:00Z",
+    "type": "asset",
+    "title": "paper.pdf",
+    "assetUrl": "https://karakeep.example.com/api/assets/a1",
+    "tags": [],
+    "archived": false,
+    "favourited": false
+  }
+]}
+`,
+		},
+		{
+			"html",
+			"<!DOCTYPE NETSCAPE-Bookmark-file-1>\n" +
+				"<META HTTP-EQUIV=\"Content-Type\" CONTENT=\"text/html; charset=UTF-8\">\n" +
+				"<TITLE>Bookmarks</TITLE>\n" +
+				"<H1>Karakeep: #golang</H1>\n" +
+				"<DL><p>\n" +
+				"    <DT><A HREF=\"https://go.dev/?a=1&amp;b=2\" ADD_DATE=\"1709632800\" TAGS=\"golang,open source\">Go [1.23] &amp; more</A>\n" +
+				"    <DD>A summary.\n" +
+				"</DL><p>\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			format := exportFormats[tt.format]
+			got := format.header("#golang")
+			for i, b := range testExportBookmarks() {
+				got += format.entry(b, i == 0)
+			}
+			got += format.footer()
+
+			if got != tt.expected {
+				t.Errorf("Expected:\n%s\ngot:\n%s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestExportFileName(t *testing.T) {
+	tests := []struct {
+		collection string
+		part       int
+		expected   string
+	}{
+		{"all bookmarks", 1, "karakeep-all-bookmarks.md"},
+		{"#golang", 2, "karakeep-golang-2.md"},
+		{"@Read Later 📚", 1, "karakeep-read-later.md"},
+		{"📚", 3, "karakeep-3.md"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.collection, func(t *testing.T) {
+			if got := exportFileName(tt.collection, tt.part, ".md"); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestExportWriter(t *testing.T) {
+	var bookmarks []exportBookmark
+	for i := range 10 {
+		bookmarks = append(bookmarks, exportBookmark{ID: fmt.Sprintf("b%d", i), Type: "link", URL: "https://example.com", Tags: []string{}})
+	}
+	entrySize := len(jsonExport{}.entry(bookmarks[0], false))
+	overhead := len(jsonExport{}.header("")) + len(jsonExport{}.footer())
+
+	tests := []struct {
+		name    string
+		maxSize int
+		parts   []int // Bookmarks in each file.
+	}{
+		{"One file", exportPartSize, []int{10}},
+		{"Split", overhead + 3*entrySize, []int{3, 3, 3, 1}},
+		{"Larger than a file", 10, []int{1, 1, 1, 1, 1, 1, 1, 1, 1, 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			var parts []int
+			var ids []string
+			w := &exportWriter{format: jsonExport{}, dir: dir, maxSize: tt.maxSize, send: func(path string, part, count int) error {
+				data, err := os.ReadFile(path)
+				if err != nil {
+					return err
+				}
+				if len(data) > max(tt.maxSize, overhead+entrySize) {
+					t.Errorf("Part %d has %d bytes, more than %d", part, len(data), tt.maxSize)
+				}
+				var file struct {
+					Bookmarks []exportBookmark `json:"bookmarks"`
+				}
+				if err := json.Unmarshal(data, &file); err != nil {
+					t.Fatalf("Part %d isn't valid JSON: %v\n%s", part, err, data)
+				}
+				if len(file.Bookmarks) != count {
+					t.Errorf("Part %d reported %d bookmarks, has %d", part, count, len(file.Bookmarks))
+				}
+				parts = append(parts, count)
+				for _, b := range file.Bookmarks {
+					ids = append(ids, b.ID)
+				}
+				return nil
+			}}
+
+			for _, b := range bookmarks {
+				if err := w.add(b); err != nil {
+					t.Fatalf("add() returned an unexpected error: %v", err)
+				}
+			}
+			if err := w.close(); err != nil {
+				t.Fatalf("close() returned an unexpected error: %v", err)
+			}
+
+			if !slices.Equal(parts, tt.parts) {
+				t.Errorf("Expected files with %v bookmarks, got %v", tt.parts, parts)
+			}
+			if expected := []string{"b0", "b1", "b2", "b3", "b4", "b5", "b6", "b7", "b8", "b9"}; !slices.Equal(ids, expected) {
+				t.Errorf("Expected bookmarks %v, got %v", expected, ids)
+			}
+			if files, _ := os.ReadDir(dir); len(files) != 0 {
+				t.Errorf("Expected the files to be removed, got %v", files)
+			}
+		})
+	}
+
+	t.Run("Skipped and failed", func(t *testing.T) {
+		dir := t.TempDir()
+		failure := errors.New("failure")
+		w := &exportWriter{format: netscapeExport{}, dir: dir, maxSize: exportPartSize, send: func(string, int, int) error { return failure }}
+
+		for _, b := range testExportBookmarks() {
+			if err := w.add(b); err != nil {
+				t.Fatalf("add() returned an unexpected error: %v", err)
+			}
+		}
+		if w.total != 1 || w.skipped != 2 {
+			t.Errorf("Expected 1 bookmark written and 2 skipped, got %d and %d", w.total, w.skipped)
+		}
+		if err := w.close(); !errors.Is(err, failure) {
+			t.Errorf("Expected close() to return %v, got %v", failure, err)
+		}
+		if files, _ := os.ReadDir(dir); len(files) != 0 {
+			t.Errorf("Expected the files to be removed, got %v", files)
+		}
+	})
+}
+
+func TestKarakeepBot_handleExportCommand(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		expected []string
+	}{
+		{"All bookmarks", nil, []string{"sendDocument: 📦 Export of all bookmarks (5 bookmarks) (karakeep-all-bookmarks.md)"}},
+		{"Tag", []string{"#golang", "JSON"}, []string{"sendDocument: 📦 Export of #golang (4 bookmarks) (karakeep-golang.json)"}},
+		{"List", []string{"reading", "html"}, []string{"sendDocument: 📦 Export of @Reading (3 bookmarks) (karakeep-reading.html)"}},
+		{"Format only", []string{"netscape"}, []string{"sendDocument: 📦 Export of all bookmarks (5 bookmarks) (karakeep-all-bookmarks.html)"}},
+		{"Unknown", []string{"unknown", "md"}, []string{`sendMessage: 🔍 No list or tag named "unknown". Usage: ` + exportUsage}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kb, calls := newTestRemindersBot(t)
+			dir := t.TempDir()
+			kb.config.FileProcessor.Tempdir = dir
+			kb.handleExportCommand(context.Background(), &TelegramMessage{ID: 10, Chat: models.Chat{ID: 1}, From: &models.User{ID: 2}}, tt.args)
+
+			if !slices.Equal(calls(), tt.expected) {
+				t.Errorf("Expected calls %q, got %q", tt.expected, calls())
+			}
+			if files, _ := os.ReadDir(dir); len(files) != 0 {
+				t.Errorf("Expected the export files to be removed, got %v", files)
+			}
+		})
+	}
+}
+
+func TestKarakeepBot_exportBookmark(t *testing.T) {
+	tests := []struct {
+		name     string
+		bookmark string
+		expected exportBookmark
+	}{
+		{
+			"Link",
+			`{"id": "b1", "createdAt": "2024-03-05T10:00:00Z", "favourited": true, "summary": "A summary.", "tags": [{"id": "t1", "name": "golang", "attachedBy": "ai"}], "content": {"type": "link", "url": "https://go.dev", "title": "Go"}}`,
+			exportBookmark{ID: "b1", Created: time.Date(2024, 3, 5, 10, 0, 0, 0, time.UTC), Type: "link", Title: "Go", URL: "https://go.dev", Summary: "A summary.", Tags: []string{"golang"}, Favourited: true},
+		},
+		{
+			"Asset",
+			`{"id": "b2", "createdAt": "2024-03-05T10:00:00Z", "title": "Paper", "content": {"type": "asset", "assetType": "pdf", "assetId": "a1", "fileName": "paper.pdf"}}`,
+			exportBookmark{ID: "b2", Created: time.Date(2024, 3, 5, 10, 0, 0, 0, time.UTC), Type: "asset", Title: "Paper", AssetURL: "https://karakeep.example.com/api/assets/a1", Tags: []string{}},
+		},
+		{
+			"Text",
+			`{"id": "b3", "archived": true, "note": "A note.", "content": {"type": "text", "text": "Some notes"}}`,
+			exportBookmark{ID: "b3", Type: "text", Text: "Some notes", Note: "A note.", Tags: []string{}, Archived: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kb := &KarakeepBot{karakeep: &Karakeep{url: "https://karakeep.example.com"}}
+			var bookmark KarakeepBookmark
+			if err := json.Unmarshal([]byte(tt.bookmark), &bookmark); err != nil {
+				t.Fatal(err)
+			}
+
+			got := kb.exportBookmark(bookmark)
+			if fmt.Sprintf("%+v", got) != fmt.Sprintf("%+v", tt.expected) {
+				t.Errorf("Expected %+v, got %+v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestExportBookmark_heading(t *testing.T) {
+	tests := []struct {
+		bookmark exportBookmark
+		expected string
+	}{
+		{exportBookmark{Title: " Go\n", URL: "https://go.dev"}, "Go"},
+		{exportBookmark{URL: "https://go.dev"}, "https://go.dev"},
+		{exportBookmark{Text: strings.Repeat("a", maxHeadlineLength+1)}, strings.Repeat("a", maxHeadlineLength-1) + "…"},
+		{exportBookmark{}, "(untitled)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expected, func(t *testing.T) {
+			if got := tt.bookmark.heading(); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
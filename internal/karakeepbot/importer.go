@@ -0,0 +1,290 @@
+package karakeepbot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/Madh93/go-karakeep"
+	"github.com/Madh93/karakeepbot/internal/config"
+	"github.com/Madh93/karakeepbot/internal/filevalidator"
+	"github.com/Madh93/karakeepbot/internal/logging"
+	"github.com/Madh93/karakeepbot/internal/urlcleaner"
+)
+
+// DefaultCheckpointFile is the name of the checkpoint file created in the
+// export directory when no other path is provided.
+const DefaultCheckpointFile = ".karakeepbot-import.json"
+
+// errSkipMessage is returned when an exported message can't be imported.
+var errSkipMessage = errors.New("unsupported message")
+
+// ImportOptions configures how an import is performed.
+type ImportOptions struct {
+	DryRun     bool          // Map messages without uploading anything.
+	Interval   time.Duration // Minimum time between uploads to Karakeep.
+	Checkpoint string        // Path to the checkpoint file used to resume imports.
+	Output     io.Writer     // Destination for progress output.
+}
+
+// Importer imports bookmarks from Telegram exports into Karakeep, reusing the
+// same message parsing logic as the bot.
+type Importer struct {
+	kb        *KarakeepBot
+	maxsize   int64
+	mimetypes []string
+}
+
+// importCheckpoint stores the last imported message ID for each chat.
+type importCheckpoint struct {
+	Chats map[int64]int64 `json:"chats"`
+}
+
+// NewImporter creates a new Importer. Unlike New, it doesn't connect to
+// Telegram.
+func NewImporter(logger *logging.Logger, config *config.Config) *Importer {
+	urlCleaner, err := urlcleaner.New(&config.URLCleaner)
+	if err != nil {
+		logger.Fatal("Failed to create URL cleaner", "error", err)
+	}
+
+	kb := &KarakeepBot{
+		karakeep:   createKarakeep(logger, &config.Karakeep),
+		urlCleaner: urlCleaner,
+		logger:     logger,
+	}
+	kb.settings.Store(newSettings(config))
+
+	return &Importer{
+		kb:        kb,
+		maxsize:   config.FileProcessor.Maxsize,
+		mimetypes: config.FileProcessor.Mimetypes,
+	}
+}
+
+// ImportTelegramExport imports the messages of the Telegram Desktop export
+// found in dir. Progress is saved to the checkpoint file after every message,
+// so an interrupted import can be resumed by running it again.
+func (im *Importer) ImportTelegramExport(ctx context.Context, dir string, opts ImportOptions) error {
+	export, err := LoadTelegramExport(dir)
+	if err != nil {
+		return err
+	}
+
+	if opts.Output == nil {
+		opts.Output = io.Discard
+	}
+	if opts.Checkpoint == "" {
+		opts.Checkpoint = filepath.Join(dir, DefaultCheckpointFile)
+	}
+
+	checkpoint, err := loadCheckpoint(opts.Checkpoint)
+	if err != nil {
+		return err
+	}
+
+	total := 0
+	for _, chat := range export.Chats {
+		total += len(chat.Messages)
+	}
+
+	var imported, skipped, current int
+	var lastUpload time.Time
+	for _, chat := range export.Chats {
+		for _, m := range chat.Messages {
+			current++
+			progress := fmt.Sprintf("[%d/%d]", current, total)
+
+			if m.ID <= checkpoint.Chats[chat.ID] {
+				continue
+			}
+
+			if m.Type != "message" {
+				skipped++
+				if err := im.advance(opts, checkpoint, chat.ID, m.ID); err != nil {
+					return err
+				}
+				continue
+			}
+
+			if !opts.DryRun {
+				if err := waitUntil(ctx, lastUpload.Add(opts.Interval)); err != nil {
+					return err
+				}
+				lastUpload = time.Now()
+			}
+
+			description, err := im.importMessage(ctx, export.Dir, chat, m, opts.DryRun)
+			switch {
+			case errors.Is(err, errSkipMessage):
+				skipped++
+				_, _ = fmt.Fprintf(opts.Output, "%s Skipped message %d: %v\n", progress, m.ID, err)
+			case err != nil:
+				return fmt.Errorf("failed to import message %d: %w", m.ID, err)
+			default:
+				imported++
+				_, _ = fmt.Fprintf(opts.Output, "%s %s\n", progress, description)
+			}
+
+			if err := im.advance(opts, checkpoint, chat.ID, m.ID); err != nil {
+				return err
+			}
+		}
+	}
+
+	_, _ = fmt.Fprintf(opts.Output, "Done: %d imported, %d skipped, %d total\n", imported, skipped, total)
+	return nil
+}
+
+// importMessage creates a bookmark for a single exported message and returns
+// a description of it.
+func (im *Importer) importMessage(ctx context.Context, dir string, chat TelegramExportChat, m TelegramExportMessage, dryRun bool) (string, error) {
+	msg := m.ToTelegramMessage(chat)
+
+	var b BookmarkType
+	var list *karakeep.List
+	switch {
+	case m.HasPhoto():
+		path := m.PhotoPath(dir)
+		mimeType, err := im.checkPhoto(path)
+		if err != nil {
+			return "", err
+		}
+		if dryRun {
+			return fmt.Sprintf("Would upload photo %s", path), nil
+		}
+		asset, err := im.kb.karakeep.CreateAsset(ctx, path, mimeType)
+		if err != nil {
+			return "", err
+		}
+		b = newPhotoBookmark(asset.AssetId, msg)
+	case m.File != "":
+		return "", fmt.Errorf("%w: files are not supported", errSkipMessage)
+	case msg.Text == "":
+		return "", fmt.Errorf("%w: empty message", errSkipMessage)
+	default:
+		var err error
+		if b, list, err = im.kb.parseMessage(ctx, msg); err != nil {
+			return "", fmt.Errorf("%w: %w", errSkipMessage, err)
+		}
+	}
+
+	if dryRun {
+		return fmt.Sprintf("Would create %s", b), nil
+	}
+
+	bookmark, err := im.kb.karakeep.CreateBookmark(ctx, b)
+	if err != nil {
+		return "", err
+	}
+	im.kb.logger.Debug("Created bookmark", bookmark.Attrs()...)
+	im.kb.enrichBookmark(ctx, msg, bookmark, nil)
+	im.kb.addToList(ctx, bookmark, list)
+
+	return fmt.Sprintf("Created %s", b), nil
+}
+
+// checkPhoto validates an exported photo against the file processor limits
+// and returns its MIME type.
+func (im *Importer) checkPhoto(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", errSkipMessage, err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	info, err := file.Stat()
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", errSkipMessage, err)
+	}
+	if info.Size() > im.maxsize {
+		return "", fmt.Errorf("%w: photo exceeds %d bytes", errSkipMessage, im.maxsize)
+	}
+
+	mimeType, err := filevalidator.ImageValidator(file)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", errSkipMessage, err)
+	}
+	if len(im.mimetypes) > 0 && !slices.Contains(im.mimetypes, mimeType) {
+		return "", fmt.Errorf("%w: MIME type %s is not allowed", errSkipMessage, mimeType)
+	}
+
+	return mimeType, nil
+}
+
+// advance records messageID as the last processed message of chatID and saves
+// the checkpoint, unless running in dry-run mode.
+func (im *Importer) advance(opts ImportOptions, checkpoint *importCheckpoint, chatID, messageID int64) error {
+	if opts.DryRun {
+		return nil
+	}
+	checkpoint.Chats[chatID] = messageID
+	return saveCheckpoint(opts.Checkpoint, checkpoint)
+}
+
+// loadCheckpoint reads the checkpoint file at path. A missing file results in
+// an empty checkpoint.
+func loadCheckpoint(path string) (*importCheckpoint, error) {
+	checkpoint := &importCheckpoint{Chats: make(map[int64]int64)}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return checkpoint, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+
+	if err := json.Unmarshal(data, checkpoint); err != nil {
+		return nil, fmt.Errorf("failed to parse checkpoint: %w", err)
+	}
+	if checkpoint.Chats == nil {
+		checkpoint.Chats = make(map[int64]int64)
+	}
+
+	return checkpoint, nil
+}
+
+// saveCheckpoint atomically writes the checkpoint file at path.
+func saveCheckpoint(path string, checkpoint *importCheckpoint) error {
+	data, err := json.Marshal(checkpoint)
+	if err != nil {
+		return fmt.Errorf("failed to marshal checkpoint: %w", err)
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+
+	return nil
+}
+
+// waitUntil blocks until t or until ctx is done.
+func waitUntil(ctx context.Context, t time.Time) error {
+	delay := time.Until(t)
+	if delay <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,221 @@
+package karakeepbot
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Madh93/karakeepbot/internal/hashtag"
+	"github.com/go-telegram/bot/models"
+)
+
+// maxInlineTextLength is the maximum number of characters of the text of a
+// shared text bookmark, below the 4096 characters allowed by Telegram to leave
+// room for the hashtags.
+const maxInlineTextLength = 3500
+
+// searchCache caches the pages of search results for a short time, so
+// scrolling the results or typing the same query again doesn't search
+// Karakeep again. The zero value is ready to use.
+type searchCache struct {
+	mu      sync.Mutex
+	entries map[searchKey]searchEntry
+}
+
+// searchKey identifies a page of search results.
+type searchKey struct {
+	query string
+	page  int
+	size  int
+}
+
+// searchEntry is a cached page of search results.
+type searchEntry struct {
+	bookmarks []KarakeepBookmark
+	next      *string // Cursor of the next page. Nil if there are no more results.
+	expires   time.Time
+}
+
+// get returns the cached page, if it hasn't expired.
+func (c *searchCache) get(key searchKey) (searchEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok || time.Now().After(entry.expires) {
+		return searchEntry{}, false
+	}
+	return entry, true
+}
+
+// put caches a page for the given time, removing the expired pages.
+func (c *searchCache) put(key searchKey, entry searchEntry, ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	for k, e := range c.entries {
+		if now.After(e.expires) {
+			delete(c.entries, k)
+		}
+	}
+	if c.entries == nil {
+		c.entries = make(map[searchKey]searchEntry)
+	}
+	entry.expires = now.Add(ttl)
+	c.entries[key] = entry
+}
+
+// handleInlineQuery answers inline queries (e.g. "@karakeepbot golang") with
+// the bookmarks found in Karakeep, to share them in any chat. Results are
+// paginated with the query offset, which holds the number of the next page.
+func (kb *KarakeepBot) handleInlineQuery(ctx context.Context, query *models.InlineQuery) {
+	s := kb.settings.Load()
+	attrs := []any{"scope", "telegram", "user_id", query.From.ID, "username", query.From.Username, "inline_query", query.Query, "offset", query.Offset}
+	answer := func(results []models.InlineQueryResult, nextOffset string) {
+		if err := kb.telegram.AnswerInline(ctx, query.ID, results, nextOffset, s.inline.Cache); err != nil {
+			kb.logger.Error("Failed to answer inline query", append(attrs, "error", err)...)
+		}
+	}
+
+	if !s.inline.Enabled {
+		kb.logger.Debug("Ignoring inline query: inline mode is disabled", attrs...)
+		return
+	}
+
+	if role := kb.inlineRole(query.From.ID); role < RoleReader {
+		kb.logger.Warn("Denied inline query: insufficient role", append(attrs, "role", role, "required_role", RoleReader)...)
+		answer(nil, "")
+		return
+	}
+
+	text := strings.Join(strings.Fields(query.Query), " ")
+	page, err := strconv.Atoi(query.Offset)
+	if query.Offset == "" {
+		page, err = 0, nil
+	}
+	if text == "" || err != nil || page < 0 {
+		answer(nil, "")
+		return
+	}
+
+	bookmarks, more, err := kb.searchPage(ctx, text, page, s.inline.Results, time.Duration(s.inline.Cache)*time.Second)
+	if err != nil {
+		kb.logger.Error("Failed to search bookmarks", append(attrs, "error", err)...)
+		answer(nil, "")
+		return
+	}
+
+	results := make([]models.InlineQueryResult, len(bookmarks))
+	for i, bookmark := range bookmarks {
+		results[i] = inlineResult(bookmark, s.hashtags)
+	}
+	nextOffset := ""
+	if more {
+		nextOffset = strconv.Itoa(page + 1)
+	}
+
+	kb.logger.Debug("Answering inline query", append(attrs, "results", len(results))...)
+	answer(results, nextOffset)
+}
+
+// inlineRole returns the role of a user for inline queries, which don't come
+// from a chat the allowlist can be checked against. Users need a role assigned
+// in the configuration or granted with /allow, or their private chat with the
+// bot to be in the allowlist. Otherwise anyone could search the bookmarks
+// when no roles are configured.
+func (kb *KarakeepBot) inlineRole(userID int64) Role {
+	s := kb.settings.Load()
+	role := configuredRole(&s.roles, userID)
+	if granted, ok := kb.grants.get(userID); ok && granted > role {
+		role = granted
+	}
+	if role == RoleNone && slices.Contains(s.allowlist, userID) {
+		role = kb.roleOf(userID)
+	}
+	return role
+}
+
+// searchPage returns the given page (starting at 0) of the bookmarks matching
+// the query, with size bookmarks per page, and whether there are more pages.
+// Pages are cached for ttl, and the cursors of the cached pages are reused to
+// fetch the next ones.
+func (kb *KarakeepBot) searchPage(ctx context.Context, query string, page, size int, ttl time.Duration) ([]KarakeepBookmark, bool, error) {
+	var cursor *string
+	for i := 0; i <= page; i++ {
+		key := searchKey{query: strings.ToLower(query), page: i, size: size}
+		entry, ok := kb.searches.get(key)
+		if !ok {
+			bookmarks, next, err := kb.karakeep.SearchBookmarks(ctx, query, cursor, size)
+			if err != nil {
+				return nil, false, err
+			}
+			entry = searchEntry{bookmarks: bookmarks, next: next}
+			kb.searches.put(key, entry, ttl)
+		}
+
+		if i == page {
+			return entry.bookmarks, entry.next != nil, nil
+		}
+		if entry.next == nil {
+			return nil, false, nil
+		}
+		cursor = entry.next
+	}
+	return nil, false, nil
+}
+
+// inlineResult returns the inline query result sharing the bookmark: its
+// headline, URL or text, and hashtags.
+func inlineResult(bookmark KarakeepBookmark, n *hashtag.Normalizer) *models.InlineQueryResultArticle {
+	headline := bookmark.Headline()
+	hashtags := bookmark.Hashtags(n)
+
+	var description []string
+	if d := bookmark.Description(); d != "" {
+		description = append(description, d)
+	}
+	if hashtags != "" {
+		description = append(description, strings.ReplaceAll(hashtags, "\n", " "))
+	}
+
+	result := &models.InlineQueryResultArticle{
+		ID:                  bookmark.Id,
+		Title:               headline,
+		Description:         strings.Join(description, "\n"),
+		URL:                 bookmark.URL(),
+		InputMessageContent: &models.InputTextMessageContent{MessageText: shareText(bookmark, hashtags)},
+	}
+
+	// Only the original image of links is used, as Telegram can't
+	// authenticate to download the assets stored in Karakeep
+	if imageURL, _ := bookmark.Image(); strings.HasPrefix(imageURL, "https://") || strings.HasPrefix(imageURL, "http://") {
+		result.ThumbnailURL = imageURL
+	}
+
+	return result
+}
+
+// shareText returns the text sharing a bookmark: its headline and URL, or its
+// text, followed by the hashtags.
+func shareText(bookmark KarakeepBookmark, hashtags string) string {
+	headline := bookmark.Headline()
+	text := headline
+	if content := bookmark.Text(); content != "" {
+		text = strings.TrimSpace(content)
+		if runes := []rune(text); len(runes) > maxInlineTextLength {
+			text = string(runes[:maxInlineTextLength-1]) + "…"
+		}
+	} else if url := bookmark.URL(); url != "" && url != headline {
+		text += "\n" + url
+	}
+	if hashtags != "" {
+		text += "\n\n" + hashtags
+	}
+	return text
+}
@@ -0,0 +1,265 @@
+package karakeepbot
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Madh93/karakeepbot/internal/config"
+	"github.com/Madh93/karakeepbot/internal/hashtag"
+	"github.com/Madh93/karakeepbot/internal/logging"
+	"github.com/go-telegram/bot/models"
+)
+
+// newTestSearchBot returns a bot connected to a fake Karakeep server whose
+// search finds n bookmarks for any query, and the number of searches. User 1
+// is an admin.
+func newTestSearchBot(t *testing.T, n int) (*KarakeepBot, *atomic.Int32) {
+	t.Helper()
+
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/bookmarks/search" {
+			http.NotFound(w, r)
+			return
+		}
+		calls.Add(1)
+		if r.URL.Query().Get("q") == "fail" {
+			http.Error(w, "invalid query", http.StatusBadRequest)
+			return
+		}
+		start, _ := strconv.Atoi(r.URL.Query().Get("cursor"))
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		end := min(start+limit, n)
+
+		bookmarks := []any{}
+		for i := start; i < end; i++ {
+			id := "b" + strconv.Itoa(i+1)
+			bookmarks = append(bookmarks, map[string]any{"id": id, "tags": []any{}, "content": map[string]any{"type": "text", "text": "Bookmark " + id}})
+		}
+		var next any
+		if end < n {
+			next = strconv.Itoa(end)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"bookmarks": bookmarks, "nextCursor": next})
+	}))
+	t.Cleanup(server.Close)
+
+	kb := newTestRolesBot(t, 1)
+	cfg := newTestConfig()
+	cfg.Karakeep.URL = server.URL
+	cfg.Roles.Admins = []int64{1}
+	cfg.Inline.Enabled = true
+	kb.settings.Store(newSettings(cfg))
+	kb.logger = logging.New(&cfg.Logging)
+	kb.karakeep = createKarakeep(kb.logger, &cfg.Karakeep)
+	return kb, &calls
+}
+
+func TestKarakeepBot_inlineRole(t *testing.T) {
+	tests := []struct {
+		name      string
+		admins    []int64
+		allowlist []int64
+		granted   Role
+		userID    int64
+		expected  Role
+	}{
+		{"Configured role", []int64{1}, []int64{-100}, RoleNone, 1, RoleAdmin},
+		{"Granted role", []int64{1}, []int64{-100}, RoleReader, 42, RoleReader},
+		{"Allowed private chat without roles", nil, []int64{42}, RoleNone, 42, RoleContributor},
+		{"Allowed private chat without a role", []int64{1}, []int64{42}, RoleNone, 42, RoleNone},
+		{"Unknown user without roles", nil, []int64{-100}, RoleNone, 42, RoleNone},
+		{"Unknown user without allowlist", nil, nil, RoleNone, 42, RoleNone},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kb := newTestRolesBot(t)
+			cfg := newTestConfig()
+			cfg.Roles.Admins = tt.admins
+			cfg.Telegram.Allowlist = tt.allowlist
+			kb.settings.Store(newSettings(cfg))
+			if tt.granted != RoleNone {
+				if err := kb.grants.grant(tt.userID, tt.granted); err != nil {
+					t.Fatalf("grant() returned an unexpected error: %v", err)
+				}
+			}
+
+			if role := kb.inlineRole(tt.userID); role != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, role)
+			}
+		})
+	}
+}
+
+func TestKarakeepBot_searchPage(t *testing.T) {
+	kb, calls := newTestSearchBot(t, 7)
+	ctx := context.Background()
+
+	tests := []struct {
+		name          string
+		query         string
+		page          int
+		expectedFirst string
+		expectedMore  bool
+		expectedCalls int32
+	}{
+		{"First page", "golang", 0, "b1", true, 1},
+		{"Second page reuses the cursor", "golang", 1, "b4", true, 2},
+		{"Cached page", "GoLang", 0, "b1", true, 2},
+		{"Last page", "golang", 2, "b7", false, 3},
+		{"Page without cached cursor", "rust", 1, "b4", true, 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bookmarks, more, err := kb.searchPage(ctx, tt.query, tt.page, 3, time.Minute)
+			if err != nil {
+				t.Fatalf("searchPage() returned an unexpected error: %v", err)
+			}
+			if len(bookmarks) == 0 || bookmarks[0].Id != tt.expectedFirst {
+				t.Errorf("Expected page starting with %s, got %v", tt.expectedFirst, bookmarks)
+			}
+			if more != tt.expectedMore {
+				t.Errorf("Expected more: %v, got %v", tt.expectedMore, more)
+			}
+			if got := calls.Load(); got != tt.expectedCalls {
+				t.Errorf("Expected %d searches, got %d", tt.expectedCalls, got)
+			}
+		})
+	}
+
+	t.Run("Without cache", func(t *testing.T) {
+		before := calls.Load()
+		for range 2 {
+			if _, _, err := kb.searchPage(ctx, "uncached", 0, 3, 0); err != nil {
+				t.Fatalf("searchPage() returned an unexpected error: %v", err)
+			}
+		}
+		if got := calls.Load() - before; got != 2 {
+			t.Errorf("Expected 2 searches, got %d", got)
+		}
+	})
+}
+
+func TestKarakeepBot_handleInlineQuery(t *testing.T) {
+	tests := []struct {
+		name             string
+		userID           int64
+		query            string
+		offset           string
+		enabled          bool
+		expectedAnswers  int
+		expectedSearches int32
+	}{
+		{"Search", 1, "golang", "", true, 1, 1},
+		{"Next page", 1, "golang", "1", true, 1, 2},
+		{"Failed search", 1, "fail", "", true, 1, 1},
+		{"Empty query", 1, "  ", "", true, 1, 0},
+		{"Invalid offset", 1, "golang", "x", true, 1, 0},
+		{"Denied user", 42, "golang", "", true, 1, 0},
+		{"Disabled", 1, "golang", "", false, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kb, searches := newTestSearchBot(t, 20)
+			telegram, calls := newTestTelegram(t)
+			kb.telegram = telegram
+			if !tt.enabled {
+				cfg := newTestConfig()
+				cfg.Roles.Admins = []int64{1}
+				kb.settings.Store(newSettings(cfg))
+			}
+
+			kb.handleInlineQuery(context.Background(), &models.InlineQuery{ID: "query", From: &models.User{ID: tt.userID}, Query: tt.query, Offset: tt.offset})
+
+			if got := len(calls()); got != tt.expectedAnswers {
+				t.Errorf("Expected %d answers, got %v", tt.expectedAnswers, calls())
+			}
+			if got := searches.Load(); got != tt.expectedSearches {
+				t.Errorf("Expected %d searches, got %d", tt.expectedSearches, got)
+			}
+		})
+	}
+}
+
+func TestInlineResult(t *testing.T) {
+	n := hashtag.New(&config.DefaultConfig.Hashtags)
+
+	tests := []struct {
+		name                string
+		bookmark            map[string]any
+		expectedText        string
+		expectedDescription string
+		expectedThumbnail   string
+	}{
+		{
+			"Link",
+			map[string]any{
+				"id":      "link",
+				"summary": "A summary",
+				"tags":    []any{map[string]any{"id": "t1", "name": "golang", "attachedBy": "human"}, map[string]any{"id": "t2", "name": "release", "attachedBy": "ai"}},
+				"content": map[string]any{"type": "link", "url": "https://go.dev/blog", "title": "Go blog", "imageUrl": "https://go.dev/image.png"},
+			},
+			"Go blog\nhttps://go.dev/blog\n\n#golang\n🤖 #release",
+			"A summary\n#golang 🤖 #release",
+			"https://go.dev/image.png",
+		},
+		{
+			"Image",
+			map[string]any{
+				"id":      "image",
+				"note":    "  A   diagram ",
+				"tags":    []any{},
+				"content": map[string]any{"type": "asset", "assetType": "image", "assetId": "asset1", "fileName": "diagram.png"},
+			},
+			"diagram.png",
+			"A diagram",
+			"",
+		},
+		{
+			"Text",
+			map[string]any{
+				"id":      "text",
+				"tags":    []any{},
+				"content": map[string]any{"type": "text", "text": "First line\nSecond line"},
+			},
+			"First line\nSecond line",
+			"",
+			"",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var bookmark KarakeepBookmark
+			data, _ := json.Marshal(tt.bookmark)
+			if err := json.Unmarshal(data, &bookmark); err != nil {
+				t.Fatal(err)
+			}
+
+			result := inlineResult(bookmark, n)
+			if text := result.InputMessageContent.(*models.InputTextMessageContent).MessageText; text != tt.expectedText {
+				t.Errorf("Expected text %q, got %q", tt.expectedText, text)
+			}
+			if result.Description != tt.expectedDescription {
+				t.Errorf("Expected description %q, got %q", tt.expectedDescription, result.Description)
+			}
+			if result.ThumbnailURL != tt.expectedThumbnail {
+				t.Errorf("Expected thumbnail %q, got %q", tt.expectedThumbnail, result.ThumbnailURL)
+			}
+			if result.ID != bookmark.Id || strings.TrimSpace(result.Title) == "" {
+				t.Errorf("Unexpected result %+v", result)
+			}
+		})
+	}
+}
@@ -12,15 +12,20 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/Madh93/go-karakeep"
 	"github.com/Madh93/karakeepbot/internal/config"
+	"github.com/Madh93/karakeepbot/internal/httpclient"
 	"github.com/Madh93/karakeepbot/internal/logging"
 )
 
 // Karakeep embeds the Karakeep API Client to add high level functionality.
 type Karakeep struct {
 	*karakeep.ClientWithResponses
+	url   string     // URL of the Karakeep instance, used to link to its assets.
+	lists *listCache // Cached lists, to resolve list names into IDs.
 }
 
 // createKarakeep initializes the Karakeep API Client.
@@ -43,12 +48,36 @@ func createKarakeep(logger *logging.Logger, config *config.KarakeepConfig) *Kara
 		return nil
 	}
 
-	karakeepClient, err := karakeep.NewClientWithResponses(parsedURL.String(), karakeep.WithRequestEditorFn(auth))
+	// Setup TLS, proxy and extra headers
+	if config.InsecureSkipVerify {
+		logger.Warn("TLS CERTIFICATE VERIFICATION IS DISABLED FOR KARAKEEP. Connections can be intercepted; use karakeep.cacert instead of karakeep.insecureskipverify.")
+	}
+	headers := make(map[string]string, len(config.Headers))
+	for name, value := range config.Headers {
+		headers[name] = value.Value()
+	}
+	base, err := httpclient.NewTransport(httpclient.TransportOptions{
+		CACert:             config.CACert,
+		ClientCert:         config.ClientCert,
+		ClientKey:          config.ClientKey,
+		InsecureSkipVerify: config.InsecureSkipVerify,
+		ProxyURL:           config.ProxyURL.Value(),
+		Headers:            headers,
+	})
+	if err != nil {
+		logger.Fatal("Error creating Karakeep HTTP transport.", "error", err)
+	}
+
+	// Setup HTTP client retrying transient failures
+	transport := httpclient.NewRetryTransport(base, config.Retries, time.Duration(config.Timeout)*time.Second, logger)
+	httpClient := &http.Client{Transport: transport}
+
+	karakeepClient, err := karakeep.NewClientWithResponses(parsedURL.String(), karakeep.WithHTTPClient(httpClient), karakeep.WithRequestEditorFn(auth))
 	if err != nil {
 		logger.Fatal("Error creating Karakeep API client.", "error", err)
 	}
 
-	return &Karakeep{ClientWithResponses: karakeepClient}
+	return &Karakeep{ClientWithResponses: karakeepClient, url: config.URL, lists: &listCache{}}
 }
 
 // CreateBookmark creates a new bookmark in Karakeep.
@@ -210,3 +239,284 @@ func (k Karakeep) AddTags(ctx context.Context, bookmarkID string, tagNames []str
 
 	return nil
 }
+
+// Tags returns all the tags in Karakeep.
+func (k Karakeep) Tags(ctx context.Context) ([]karakeep.Tag, error) {
+	response, err := k.GetTagsWithResponse(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tags: %w", err)
+	}
+	if response.StatusCode() != http.StatusOK {
+		return nil, fmt.Errorf("failed to get tags, received HTTP status: %s", response.Status())
+	}
+	return response.JSON200.Tags, nil
+}
+
+// Tag returns the tag with the given ID.
+func (k Karakeep) Tag(ctx context.Context, tagID string) (*karakeep.Tag, error) {
+	response, err := k.GetTagsTagIdWithResponse(ctx, tagID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tag: %w", err)
+	}
+	if response.StatusCode() != http.StatusOK {
+		return nil, fmt.Errorf("failed to get tag, received HTTP status: %s", response.Status())
+	}
+	return response.JSON200, nil
+}
+
+// TagBookmarks returns the given page (starting at 0) of the most recent
+// bookmarks with the tag, with size bookmarks per page, and whether there are
+// more pages.
+func (k Karakeep) TagBookmarks(ctx context.Context, tagID string, page, size int) ([]KarakeepBookmark, bool, error) {
+	return bookmarkPage(page, size, k.tagBookmarksFetcher(ctx, tagID, newestFirst))
+}
+
+// tagBookmarksFetcher returns a function fetching the bookmarks with the tag
+// in the given order, starting at a cursor.
+func (k Karakeep) tagBookmarksFetcher(ctx context.Context, tagID, order string) func(cursor *string, limit float32) (*karakeep.PaginatedBookmarks, error) {
+	return func(cursor *string, limit float32) (*karakeep.PaginatedBookmarks, error) {
+		sortOrder := karakeep.GetTagsTagIdBookmarksParamsSortOrder(order)
+		params := &karakeep.GetTagsTagIdBookmarksParams{SortOrder: &sortOrder, Limit: &limit, Cursor: cursor}
+		response, err := k.GetTagsTagIdBookmarksWithResponse(ctx, tagID, params)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get tag bookmarks: %w", err)
+		}
+		if response.StatusCode() != http.StatusOK {
+			return nil, fmt.Errorf("failed to get tag bookmarks, received HTTP status: %s", response.Status())
+		}
+		return response.JSON200, nil
+	}
+}
+
+// RenameTag changes the name of a tag.
+func (k Karakeep) RenameTag(ctx context.Context, tagID, name string) error {
+	response, err := k.PatchTagsTagIdWithResponse(ctx, tagID, karakeep.PatchTagsTagIdJSONRequestBody{Name: &name})
+	if err != nil {
+		return fmt.Errorf("failed to rename tag: %w", err)
+	}
+	if response.StatusCode() != http.StatusOK {
+		return fmt.Errorf("failed to rename tag, received HTTP status: %s, body: %s", response.Status(), string(response.Body))
+	}
+	return nil
+}
+
+// MergeTags attaches the tag into to every bookmark with the tag from, and
+// deletes the tag from. It returns the number of bookmarks retagged.
+func (k Karakeep) MergeTags(ctx context.Context, from, into string) (int, error) {
+	// Collect the bookmarks first, as retagging them changes the pages
+	var bookmarkIDs []string
+	err := walkBookmarks(k.tagBookmarksFetcher(ctx, from, newestFirst), func(bookmark KarakeepBookmark) bool {
+		bookmarkIDs = append(bookmarkIDs, bookmark.Id)
+		return true
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for i, bookmarkID := range bookmarkIDs {
+		if err := k.attachTag(ctx, bookmarkID, into); err != nil {
+			return i, err
+		}
+		if err := k.detachTag(ctx, bookmarkID, from); err != nil {
+			return i, err
+		}
+	}
+
+	response, err := k.DeleteTagsTagIdWithResponse(ctx, from)
+	if err != nil {
+		return len(bookmarkIDs), fmt.Errorf("failed to delete tag: %w", err)
+	}
+	if response.StatusCode() != http.StatusNoContent && response.StatusCode() != http.StatusOK {
+		return len(bookmarkIDs), fmt.Errorf("failed to delete tag, received HTTP status: %s", response.Status())
+	}
+
+	return len(bookmarkIDs), nil
+}
+
+// attachTag attaches an existing tag to a bookmark.
+func (k Karakeep) attachTag(ctx context.Context, bookmarkID, tagID string) error {
+	response, err := k.PostBookmarksBookmarkIdTagsWithBodyWithResponse(ctx, bookmarkID, "application/json", tagIDsBody(tagID))
+	if err != nil {
+		return fmt.Errorf("failed to attach tag: %w", err)
+	}
+	if response.StatusCode() != http.StatusOK {
+		return fmt.Errorf("failed to attach tag, received HTTP status: %s", response.Status())
+	}
+	return nil
+}
+
+// detachTag detaches a tag from a bookmark.
+func (k Karakeep) detachTag(ctx context.Context, bookmarkID, tagID string) error {
+	response, err := k.DeleteBookmarksBookmarkIdTagsWithBodyWithResponse(ctx, bookmarkID, "application/json", tagIDsBody(tagID))
+	if err != nil {
+		return fmt.Errorf("failed to detach tag: %w", err)
+	}
+	if response.StatusCode() != http.StatusOK {
+		return fmt.Errorf("failed to detach tag, received HTTP status: %s", response.Status())
+	}
+	return nil
+}
+
+// tagIDsBody returns the request body to attach or detach the tags with the
+// given IDs.
+func tagIDsBody(tagIDs ...string) io.Reader {
+	type tag struct {
+		TagID string `json:"tagId"`
+	}
+	payload := struct {
+		Tags []tag `json:"tags"`
+	}{}
+	for _, id := range tagIDs {
+		payload.Tags = append(payload.Tags, tag{TagID: id})
+	}
+
+	data, _ := json.Marshal(payload) // Can't fail with strings.
+	return bytes.NewReader(data)
+}
+
+// bookmarksFetcher returns a function fetching the most recent bookmarks that
+// aren't archived, starting at a cursor.
+func (k Karakeep) bookmarksFetcher(ctx context.Context) func(cursor *string, limit float32) (*karakeep.PaginatedBookmarks, error) {
+	archived := false
+	return k.fetchBookmarks(ctx, &archived, newestFirst)
+}
+
+// allBookmarksFetcher returns a function fetching all the bookmarks, including
+// the archived ones, in the given order, starting at a cursor.
+func (k Karakeep) allBookmarksFetcher(ctx context.Context, order string) func(cursor *string, limit float32) (*karakeep.PaginatedBookmarks, error) {
+	return k.fetchBookmarks(ctx, nil, order)
+}
+
+// fetchBookmarks returns a function fetching the bookmarks in the given order,
+// starting at a cursor. If archived is set, only the bookmarks that are (or
+// aren't) archived are fetched.
+func (k Karakeep) fetchBookmarks(ctx context.Context, archived *bool, order string) func(cursor *string, limit float32) (*karakeep.PaginatedBookmarks, error) {
+	return func(cursor *string, limit float32) (*karakeep.PaginatedBookmarks, error) {
+		sortOrder := karakeep.GetBookmarksParamsSortOrder(order)
+		params := &karakeep.GetBookmarksParams{Archived: archived, SortOrder: &sortOrder, Limit: &limit, Cursor: cursor}
+		response, err := k.GetBookmarksWithResponse(ctx, params)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get bookmarks: %w", err)
+		}
+		if response.StatusCode() != http.StatusOK {
+			return nil, fmt.Errorf("failed to get bookmarks, received HTTP status: %s", response.Status())
+		}
+		return response.JSON200, nil
+	}
+}
+
+// CountBookmarks returns the number of bookmarks, including the archived ones.
+func (k Karakeep) CountBookmarks(ctx context.Context) (int, error) {
+	response, err := k.GetUsersMeStatsWithResponse(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get stats: %w", err)
+	}
+	if response.StatusCode() != http.StatusOK {
+		return 0, fmt.Errorf("failed to get stats, received HTTP status: %s", response.Status())
+	}
+	return int(response.JSON200.NumBookmarks), nil
+}
+
+// ArchiveBookmark archives a bookmark.
+func (k Karakeep) ArchiveBookmark(ctx context.Context, bookmarkID string) error {
+	// The typed request body would also clear the title, note and summary, as
+	// their null values aren't omitted
+	body := strings.NewReader(`{"archived":true}`)
+	response, err := k.PatchBookmarksBookmarkIdWithBodyWithResponse(ctx, bookmarkID, "application/json", body)
+	if err != nil {
+		return fmt.Errorf("failed to archive bookmark: %w", err)
+	}
+	if response.StatusCode() != http.StatusOK {
+		return fmt.Errorf("failed to archive bookmark, received HTTP status: %s, body: %s", response.Status(), string(response.Body))
+	}
+	return nil
+}
+
+// SearchBookmarks returns up to size bookmarks matching the query, starting at
+// the cursor (nil for the first page), and the cursor of the next page, or nil
+// if there are no more bookmarks.
+func (k Karakeep) SearchBookmarks(ctx context.Context, query string, cursor *string, size int) ([]KarakeepBookmark, *string, error) {
+	limit := float32(size)
+	params := &karakeep.GetBookmarksSearchParams{Q: query, Limit: &limit, Cursor: cursor}
+	response, err := k.GetBookmarksSearchWithResponse(ctx, params)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to search bookmarks: %w", err)
+	}
+	if response.StatusCode() != http.StatusOK {
+		return nil, nil, fmt.Errorf("failed to search bookmarks, received HTTP status: %s", response.Status())
+	}
+
+	bookmarks := make([]KarakeepBookmark, len(response.JSON200.Bookmarks))
+	for i, bookmark := range response.JSON200.Bookmarks {
+		bookmarks[i] = KarakeepBookmark(bookmark)
+	}
+	next := response.JSON200.NextCursor
+	if next != nil && *next == "" {
+		next = nil
+	}
+	return bookmarks, next, nil
+}
+
+// AssetURL returns the URL of an asset in the Karakeep web interface.
+func (k Karakeep) AssetURL(assetID string) string {
+	assetURL, err := url.JoinPath(k.url, "/api/assets", assetID)
+	if err != nil {
+		return ""
+	}
+	return assetURL
+}
+
+// maxPageSize is the maximum number of bookmarks requested to Karakeep at once.
+const maxPageSize = 100
+
+// Orders of the bookmarks fetched from Karakeep, by creation time.
+const (
+	newestFirst = string(karakeep.GetBookmarksParamsSortOrderDesc)
+	oldestFirst = string(karakeep.GetBookmarksParamsSortOrderAsc)
+)
+
+// bookmarkPage returns the given page (starting at 0) of bookmarks, with size
+// bookmarks per page, and whether there are more pages. Karakeep pages are
+// cursor based, so fetch is called with the cursor of every previous page.
+func bookmarkPage(page, size int, fetch func(cursor *string, limit float32) (*karakeep.PaginatedBookmarks, error)) ([]KarakeepBookmark, bool, error) {
+	var cursor *string
+	for i := 0; ; i++ {
+		result, err := fetch(cursor, float32(size))
+		if err != nil {
+			return nil, false, err
+		}
+		more := result.NextCursor != nil && *result.NextCursor != ""
+		if i == page {
+			bookmarks := make([]KarakeepBookmark, len(result.Bookmarks))
+			for j, bookmark := range result.Bookmarks {
+				bookmarks[j] = KarakeepBookmark(bookmark)
+			}
+			return bookmarks, more, nil
+		}
+		if !more {
+			return nil, false, nil
+		}
+		cursor = result.NextCursor
+	}
+}
+
+// walkBookmarks calls fn with the bookmarks returned by fetch, fetching the
+// pages as needed, until fn returns false or there are no more bookmarks.
+func walkBookmarks(fetch func(cursor *string, limit float32) (*karakeep.PaginatedBookmarks, error), fn func(KarakeepBookmark) bool) error {
+	var cursor *string
+	for {
+		result, err := fetch(cursor, maxPageSize)
+		if err != nil {
+			return err
+		}
+		for _, bookmark := range result.Bookmarks {
+			if !fn(KarakeepBookmark(bookmark)) {
+				return nil
+			}
+		}
+		if result.NextCursor == nil || *result.NextCursor == "" {
+			return nil
+		}
+		cursor = result.NextCursor
+	}
+}
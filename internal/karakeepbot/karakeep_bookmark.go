@@ -2,8 +2,10 @@ package karakeepbot
 
 import (
 	"strings"
+	"time"
 
 	"github.com/Madh93/go-karakeep"
+	"github.com/Madh93/karakeepbot/internal/hashtag"
 )
 
 // KarakeepBookmark represents a bookmark received from the karakeep API.
@@ -14,28 +16,170 @@ func (kb KarakeepBookmark) Attrs() []any {
 	return []any{
 		"scope", "karakeep",
 		"bookmark_id", kb.Id,
-		"tagging_status", *kb.TaggingStatus,
+		"tagging_status", kb.taggingStatus(),
 	}
 }
 
+// taggingStatus returns the tagging status of the bookmark, or "unknown" if
+// Karakeep didn't report it.
+func (kb KarakeepBookmark) taggingStatus() karakeep.BookmarkTaggingStatus {
+	if kb.TaggingStatus == nil {
+		return "unknown"
+	}
+	return *kb.TaggingStatus
+}
+
 // AttrsWithError returns a slice of logging attributes for the bookmark with an
 // error.
 func (kb KarakeepBookmark) AttrsWithError(err error) []any {
 	return append(kb.Attrs(), "error", err)
 }
 
-// Hashtags returns a string of hashtags associated with the bookmark.
-func (kb KarakeepBookmark) Hashtags() string {
-	var tags []string
+// Hashtags returns a string of hashtags associated with the bookmark,
+// normalized with n. Tags attached by the user and by the AI are rendered as
+// configured in n.
+func (kb KarakeepBookmark) Hashtags(n *hashtag.Normalizer) string {
+	var userTags, aiTags []string
 	for _, tag := range kb.Tags {
-		name := sanitizeTag(tag.Name)
-		tags = append(tags, "#"+name)
+		if tag.AttachedBy == karakeep.Ai {
+			aiTags = append(aiTags, tag.Name)
+		} else {
+			userTags = append(userTags, tag.Name)
+		}
+	}
+	return n.Render(userTags, aiTags)
+}
+
+// maxHeadlineLength is the maximum number of characters of a bookmark headline.
+const maxHeadlineLength = 80
+
+// Created returns when the bookmark was saved, or the zero time if unknown.
+func (kb KarakeepBookmark) Created() time.Time {
+	created, _ := time.Parse(time.RFC3339, kb.CreatedAt)
+	return created
+}
+
+// Headline returns a one-line description of the bookmark: its title or, if it
+// has none, the title or URL of the link, the beginning of the text or the
+// file name of the asset.
+func (kb KarakeepBookmark) Headline() string {
+	headline := strings.Join(strings.Fields(kb.contentHeadline()), " ")
+	if headline == "" {
+		return "(untitled)"
+	}
+	if runes := []rune(headline); len(runes) > maxHeadlineLength {
+		return string(runes[:maxHeadlineLength-1]) + "…"
+	}
+	return headline
+}
+
+// contentHeadline returns the title of the bookmark or, if it has none, the
+// most descriptive field of its content.
+func (kb KarakeepBookmark) contentHeadline() string {
+	if kb.Title != nil && *kb.Title != "" {
+		return *kb.Title
+	}
+
+	content, _ := kb.Content.AsBookmarkContent3()
+	switch string(content.Type) {
+	case string(karakeep.BookmarkContent0TypeLink):
+		link, _ := kb.Content.AsBookmarkContent0()
+		if link.Title != nil && *link.Title != "" {
+			return *link.Title
+		}
+		return link.Url
+	case string(karakeep.BookmarkContent1TypeText):
+		text, _ := kb.Content.AsBookmarkContent1()
+		return text.Text
+	case string(karakeep.BookmarkContent2TypeAsset):
+		asset, _ := kb.Content.AsBookmarkContent2()
+		if asset.FileName != nil {
+			return *asset.FileName
+		}
+	}
+	return ""
+}
+
+// URL returns the URL of a link bookmark, or an empty string for other types.
+func (kb KarakeepBookmark) URL() string {
+	if link, ok := kb.link(); ok {
+		return link.Url
 	}
-	return strings.Join(tags, " ")
+	return ""
 }
 
-// sanitizeTag removes any spaces or hyphens from the tag name.
-func sanitizeTag(tag string) string {
-	tag = strings.ReplaceAll(tag, " ", "")
-	return strings.ReplaceAll(tag, "-", "")
+// Text returns the text of a text bookmark, or an empty string for other
+// types.
+func (kb KarakeepBookmark) Text() string {
+	content, _ := kb.Content.AsBookmarkContent3()
+	if string(content.Type) != string(karakeep.BookmarkContent1TypeText) {
+		return ""
+	}
+	text, _ := kb.Content.AsBookmarkContent1()
+	return text.Text
+}
+
+// maxDescriptionLength is the maximum number of characters of a bookmark
+// description.
+const maxDescriptionLength = 200
+
+// Description returns a short description of the bookmark: its summary, the
+// description of the link or its note, in that order.
+func (kb KarakeepBookmark) Description() string {
+	candidates := []*string{kb.Summary}
+	if link, ok := kb.link(); ok {
+		candidates = append(candidates, link.Description)
+	}
+	candidates = append(candidates, kb.Note)
+
+	for _, candidate := range candidates {
+		if candidate == nil {
+			continue
+		}
+		if description := strings.Join(strings.Fields(*candidate), " "); description != "" {
+			if runes := []rune(description); len(runes) > maxDescriptionLength {
+				return string(runes[:maxDescriptionLength-1]) + "…"
+			}
+			return description
+		}
+	}
+	return ""
+}
+
+// Image returns the URL of the preview image of a link bookmark and the ID of
+// the image asset of the bookmark: the image of an image bookmark, or the
+// banner image stored by Karakeep for a link. Both may be empty.
+func (kb KarakeepBookmark) Image() (imageURL, assetID string) {
+	if link, ok := kb.link(); ok {
+		if link.ImageUrl != nil {
+			imageURL = *link.ImageUrl
+		}
+		if link.ImageAssetId != nil {
+			assetID = *link.ImageAssetId
+		}
+	}
+
+	content, _ := kb.Content.AsBookmarkContent3()
+	if string(content.Type) == string(karakeep.BookmarkContent2TypeAsset) {
+		if asset, _ := kb.Content.AsBookmarkContent2(); asset.AssetType == karakeep.BookmarkContent2AssetTypeImage {
+			assetID = asset.AssetId
+		}
+	}
+
+	for _, asset := range kb.Assets {
+		if assetID == "" && asset.AssetType == karakeep.BookmarkAssetsAssetTypeBannerImage {
+			assetID = asset.Id
+		}
+	}
+	return imageURL, assetID
+}
+
+// link returns the content of a link bookmark, and whether it's a link.
+func (kb KarakeepBookmark) link() (karakeep.BookmarkContent0, bool) {
+	content, _ := kb.Content.AsBookmarkContent3()
+	if string(content.Type) != string(karakeep.BookmarkContent0TypeLink) {
+		return karakeep.BookmarkContent0{}, false
+	}
+	link, _ := kb.Content.AsBookmarkContent0()
+	return link, true
 }
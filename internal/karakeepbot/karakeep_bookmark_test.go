@@ -1,30 +1,104 @@
 package karakeepbot
 
 import (
+	"encoding/json"
+	"strings"
 	"testing"
+
+	"github.com/Madh93/go-karakeep"
+	"github.com/Madh93/karakeepbot/internal/config"
+	"github.com/Madh93/karakeepbot/internal/hashtag"
 )
 
-func TestSanitizeTag(t *testing.T) {
-	// Test the sanitizeTag() method for different cases
+// newTestBookmark returns a bookmark with the given tags, each one written as
+// "name" for AI tags or "name:human" for tags attached by the user.
+func newTestBookmark(t *testing.T, tags ...string) KarakeepBookmark {
+	t.Helper()
+
+	var raw []map[string]string
+	for _, tag := range tags {
+		name, attachedBy, found := strings.Cut(tag, ":")
+		if !found {
+			attachedBy = string(karakeep.Ai)
+		}
+		raw = append(raw, map[string]string{"id": name, "name": name, "attachedBy": attachedBy})
+	}
+
+	var bookmark KarakeepBookmark
+	data, _ := json.Marshal(map[string]any{"tags": raw})
+	if err := json.Unmarshal(data, &bookmark); err != nil {
+		t.Fatal(err)
+	}
+	return bookmark
+}
+
+func TestKarakeepBookmark_Hashtags(t *testing.T) {
 	tests := []struct {
-		input    string
+		name     string
+		config   config.HashtagsConfig
+		tags     []string
 		expected string
 	}{
-		{"", ""},
-		{"golang", "golang"},
-		{"go programming", "goprogramming"},
-		{"go-programming", "goprogramming"},
-		{" spaces ", "spaces"},
-		{"-hyphens-", "hyphens"},
-		{"  multiple   spaces  ", "multiplespaces"},
-		{"--multiple---hyphens--", "multiplehyphens"},
-		{"--spaces and hyphens--", "spacesandhyphens"},
-	}
-
-	for _, test := range tests {
-		got := sanitizeTag(test.input)
-		if got != test.expected {
-			t.Errorf("For input %q to sanitizeTag(), expected %q, but got %q", test.input, test.expected, got)
-		}
+		{"No tags", config.DefaultConfig.Hashtags, nil, ""},
+		{"Default rules", config.DefaultConfig.Hashtags, []string{"golang:human", "Machine Learning:human", "go-programming:human", "Node.js:human"}, "#golang #MachineLearning #goprogramming #Nodejs"},
+		{"Tags without valid characters", config.DefaultConfig.Hashtags, []string{"🚀:human", "golang:human"}, "#golang"},
+		{"Custom rules", config.HashtagsConfig{
+			Case:      config.HashtagCaseSnake,
+			Aliases:   []config.HashtagAlias{{Tag: "Machine Learning", Synonyms: []string{"ML"}}},
+			Blocklist: []string{"misc"},
+			Max:       2,
+			AITags:    config.AITagsSeparate,
+		}, []string{"ml:human", "misc:human", "Machine Learning:human", "CI/CD:human", "golang:human"}, "#machine_learning #ci_cd"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := newTestBookmark(t, tt.tags...).Hashtags(hashtag.New(&tt.config))
+			if got != tt.expected {
+				t.Errorf("Hashtags() = %q, expected %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestKarakeepBookmark_HashtagsWithAITags(t *testing.T) {
+	// with returns the default hashtags configuration with the given changes.
+	with := func(change func(c *config.HashtagsConfig)) config.HashtagsConfig {
+		c := config.DefaultConfig.Hashtags
+		change(&c)
+		return c
+	}
+	mixed := []string{"telegram:human", "Reading", "reading:human", "programming", "concurrency"}
+
+	tests := []struct {
+		name     string
+		config   config.HashtagsConfig
+		tags     []string
+		expected string
+	}{
+		{"Separate lines", config.DefaultConfig.Hashtags, mixed, "#telegram #reading\n🤖 #programming #concurrency"},
+		{"Only AI tags", config.DefaultConfig.Hashtags, []string{"golang", "programming"}, "🤖 #golang #programming"},
+		{"Only user tags", config.DefaultConfig.Hashtags, []string{"telegram:human", "golang:human"}, "#telegram #golang"},
+		{"Inline", with(func(c *config.HashtagsConfig) { c.AITags = config.AITagsInline }), mixed, "#telegram #reading #programming #concurrency"},
+		{"Hide AI tags", with(func(c *config.HashtagsConfig) { c.AITags = config.AITagsHide }), mixed, "#telegram #reading"},
+		{"Top AI tags", with(func(c *config.HashtagsConfig) { c.MaxAI = 1 }), mixed, "#telegram #reading\n🤖 #programming"},
+		{"Maximum number of hashtags", with(func(c *config.HashtagsConfig) { c.Max = 3 }), mixed, "#telegram #reading\n🤖 #programming"},
+		{"Maximum reached by user tags", with(func(c *config.HashtagsConfig) { c.Max, c.MaxAI = 2, 1 }), mixed, "#telegram #reading"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := newTestBookmark(t, tt.tags...).Hashtags(hashtag.New(&tt.config))
+			if got != tt.expected {
+				t.Errorf("Hashtags() = %q, expected %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestKarakeepBookmark_AttrsWithoutTaggingStatus(t *testing.T) {
+	attrs := KarakeepBookmark{Id: "bookmark"}.Attrs()
+	if got := attrs[len(attrs)-1]; got != karakeep.BookmarkTaggingStatus("unknown") {
+		t.Errorf("Expected unknown tagging status, got %v", got)
 	}
 }
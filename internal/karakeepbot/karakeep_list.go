@@ -0,0 +1,131 @@
+package karakeepbot
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Madh93/go-karakeep"
+)
+
+const (
+	listCacheTTL     = 5 * time.Minute  // How long the lists are cached.
+	listCacheRefresh = 30 * time.Second // Minimum age of the cache to fetch the lists again when a name isn't found.
+)
+
+// listCache caches the Karakeep lists, so resolving list names doesn't fetch
+// them for every message.
+type listCache struct {
+	mu      sync.Mutex
+	lists   []karakeep.List
+	fetched time.Time
+}
+
+// Lists returns all the lists in Karakeep, including smart lists, and
+// refreshes the cached lists.
+func (k Karakeep) Lists(ctx context.Context) ([]karakeep.List, error) {
+	response, err := k.GetListsWithResponse(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get lists: %w", err)
+	}
+	if response.StatusCode() != http.StatusOK {
+		return nil, fmt.Errorf("failed to get lists, received HTTP status: %s", response.Status())
+	}
+
+	k.lists.mu.Lock()
+	k.lists.lists, k.lists.fetched = response.JSON200.Lists, time.Now()
+	k.lists.mu.Unlock()
+
+	return response.JSON200.Lists, nil
+}
+
+// ResolveList returns the list with the given name, or nil if it doesn't
+// exist. Lists are cached, and fetched again when they expire or when the name
+// isn't found in a cache that isn't fresh, e.g. after creating a list.
+func (k Karakeep) ResolveList(ctx context.Context, name string) (*karakeep.List, error) {
+	k.lists.mu.Lock()
+	lists, age := k.lists.lists, time.Since(k.lists.fetched)
+	k.lists.mu.Unlock()
+
+	if list := matchList(lists, name); list != nil && age < listCacheTTL {
+		return list, nil
+	} else if list == nil && age < listCacheRefresh {
+		return nil, nil
+	}
+
+	lists, err := k.Lists(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return matchList(lists, name), nil
+}
+
+// List returns the list with the given ID.
+func (k Karakeep) List(ctx context.Context, listID string) (*karakeep.List, error) {
+	response, err := k.GetListsListIdWithResponse(ctx, listID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get list: %w", err)
+	}
+	if response.StatusCode() != http.StatusOK {
+		return nil, fmt.Errorf("failed to get list, received HTTP status: %s", response.Status())
+	}
+	return response.JSON200, nil
+}
+
+// ListBookmarks returns the given page (starting at 0) of the most recent
+// bookmarks in the list, with size bookmarks per page, and whether there are
+// more pages.
+func (k Karakeep) ListBookmarks(ctx context.Context, listID string, page, size int) ([]KarakeepBookmark, bool, error) {
+	return bookmarkPage(page, size, k.listBookmarksFetcher(ctx, listID, newestFirst))
+}
+
+// listBookmarksFetcher returns a function fetching the bookmarks in the list
+// in the given order, starting at a cursor.
+func (k Karakeep) listBookmarksFetcher(ctx context.Context, listID, order string) func(cursor *string, limit float32) (*karakeep.PaginatedBookmarks, error) {
+	return func(cursor *string, limit float32) (*karakeep.PaginatedBookmarks, error) {
+		sortOrder := karakeep.GetListsListIdBookmarksParamsSortOrder(order)
+		params := &karakeep.GetListsListIdBookmarksParams{SortOrder: &sortOrder, Limit: &limit, Cursor: cursor}
+		response, err := k.GetListsListIdBookmarksWithResponse(ctx, listID, params)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get list bookmarks: %w", err)
+		}
+		if response.StatusCode() != http.StatusOK {
+			return nil, fmt.Errorf("failed to get list bookmarks, received HTTP status: %s", response.Status())
+		}
+		return response.JSON200, nil
+	}
+}
+
+// AddToList adds a bookmark to a manual list.
+func (k Karakeep) AddToList(ctx context.Context, listID, bookmarkID string) error {
+	response, err := k.PutListsListIdBookmarksBookmarkIdWithResponse(ctx, listID, bookmarkID)
+	if err != nil {
+		return fmt.Errorf("failed to add bookmark to list: %w", err)
+	}
+	if response.StatusCode() != http.StatusNoContent && response.StatusCode() != http.StatusOK {
+		return fmt.Errorf("failed to add bookmark to list, received HTTP status: %s, body: %s", response.Status(), string(response.Body))
+	}
+	return nil
+}
+
+// matchList returns the list with the given name, ignoring case, or nil if
+// none matches. Underscores in the name match spaces, so single-word names
+// such as "to_read" can refer to "To read".
+func matchList(lists []karakeep.List, name string) *karakeep.List {
+	spaced := strings.ReplaceAll(name, "_", " ")
+	for i, list := range lists {
+		if strings.EqualFold(list.Name, name) || strings.EqualFold(list.Name, spaced) {
+			return &lists[i]
+		}
+	}
+	return nil
+}
+
+// isSmartList reports whether the list is a smart list, whose bookmarks are
+// selected by a search query instead of added manually.
+func isSmartList(list *karakeep.List) bool {
+	return list.Type != nil && *list.Type == karakeep.ListTypeSmart
+}
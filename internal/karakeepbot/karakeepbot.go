@@ -8,24 +8,27 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math/rand/v2"
 	"os"
 	"os/signal"
 	"slices"
 	"strings"
+	"sync/atomic"
 	"time"
+	"unicode"
 
 	"github.com/Madh93/go-karakeep"
 	"github.com/Madh93/karakeepbot/internal/config"
 	"github.com/Madh93/karakeepbot/internal/fileprocessor"
 	"github.com/Madh93/karakeepbot/internal/filevalidator"
 	"github.com/Madh93/karakeepbot/internal/logging"
+	"github.com/Madh93/karakeepbot/internal/ratelimit"
+	"github.com/Madh93/karakeepbot/internal/scheduler"
+	"github.com/Madh93/karakeepbot/internal/state"
+	"github.com/Madh93/karakeepbot/internal/urlcleaner"
 	"github.com/Madh93/karakeepbot/internal/validation"
 )
 
-// maxTagRetries is the number of times to wait for Karakeep AI tagging to
-// complete before proceeding. At 5s per retry, 6 retries = ~30s timeout.
-const maxTagRetries = 6
-
 // KarakeepBot represents the bot with its dependencies, including the Karakeep
 // client, Telegram bot, logger and other options.
 type KarakeepBot struct {
@@ -34,16 +37,32 @@ type KarakeepBot struct {
 	logger         *logging.Logger
 	fileProcessor  *fileprocessor.Processor
 	fileValidators map[string]fileprocessor.Validator
-	allowlist      []int64
-	threads        []int
-	waitInterval   int
+	urlCleaner     *urlcleaner.Cleaner
+	state          *state.Store
+	grants         *roleGrants
+	watches        *tagWatches
+	digests        *digestRuns
+	reminders      *reminders
+	confirmations  *confirmations
+	picks          *randomPicks
+	random         func(n int) int // Returns a random number in [0, n).
+	clock          scheduler.Clock
+	scheduler      *scheduler.Scheduler
+	tagEvents      *tagEvents                // Karakeep webhook events. Nil if the webhook is disabled.
+	userLimiter    *ratelimit.Limiter[int64] // Limits saves per user. Nil if rate limiting is disabled.
+	chatLimiter    *ratelimit.Limiter[int64] // Limits saves per chat. Nil if rate limiting is disabled.
+	crawls         chan struct{}             // Limits bookmarks processed by Karakeep at once. Nil if unlimited.
+	searches       searchCache               // Cached results of inline queries.
+	config         *config.Config            // Configuration in use since startup. Reloads only change the settings.
+	loaded         *config.Config            // Last configuration loaded, only used by the reload goroutine.
+	settings       atomic.Pointer[settings]  // Settings that can be reloaded at runtime.
 }
 
 // New creates a new KarakeepBot instance, initializing the Karakeep and Telegram
 // clients.
 func New(logger *logging.Logger, config *config.Config) *KarakeepBot {
 	// Initialize FileProcessor
-	fileProcessor, err := fileprocessor.New(&config.FileProcessor, config.Telegram.ProxyEnabled, config.Telegram.ProxyURL)
+	fileProcessor, err := fileprocessor.New(&config.FileProcessor, config.Telegram.ProxyEnabled, config.Telegram.ProxyURL.Value())
 	if err != nil {
 		logger.Fatal("Failed to create file processor", "error", err)
 	}
@@ -55,24 +74,109 @@ func New(logger *logging.Logger, config *config.Config) *KarakeepBot {
 	fileValidators["image/webp"] = filevalidator.ImageValidator
 
 	// Check if the validators passed in the configuration are supported
-	if len(config.FileProcessor.Mimetypes) > 0 {
-		for _, mimetype := range config.FileProcessor.Mimetypes {
-			if _, supported := fileValidators[mimetype]; !supported {
-				logger.Fatal("Configuration error: unsupported MIME type configured", "mime_type", mimetype)
-			}
-		}
+	if err := checkMimetypes(fileValidators, config.FileProcessor.Mimetypes); err != nil {
+		logger.Fatal("Configuration error: unsupported MIME type configured", "error", err)
+	}
+
+	// Initialize URL Cleaner
+	urlCleaner, err := urlcleaner.New(&config.URLCleaner)
+	if err != nil {
+		logger.Fatal("Failed to create URL cleaner", "error", err)
+	}
+
+	// Initialize state store
+	store, err := state.New(config.State.Dir)
+	if err != nil {
+		logger.Fatal("Failed to create state store", "error", err)
+	}
+	if !store.Persistent() {
+		logger.Warn("No state directory configured, the state is kept in memory and lost on restart: " +
+			"granted roles, pending reminders, the last runs of digests and random posts, the random bookmarks posted, " +
+			"messages waiting for tags and the events of /stats. Digests and reminders missed while the bot is down won't be caught up")
+	}
+
+	// Load granted roles
+	grants, err := newRoleGrants(store)
+	if err != nil {
+		logger.Fatal("Failed to load granted roles", "error", err)
+	}
+
+	// Load messages waiting for AI tags
+	watches, err := newTagWatches(store)
+	if err != nil {
+		logger.Fatal("Failed to load messages waiting for tags", "error", err)
+	}
+
+	// Load the last runs of the digests
+	digests, err := newDigestRuns(store)
+	if err != nil {
+		logger.Fatal("Failed to load digest runs", "error", err)
+	}
+
+	// Load the pending reminders and the bookmarks of the sent messages
+	reminders, err := newReminders(store)
+	if err != nil {
+		logger.Fatal("Failed to load reminders", "error", err)
+	}
+	confirmations, err := newConfirmations(store)
+	if err != nil {
+		logger.Fatal("Failed to load sent messages", "error", err)
+	}
+
+	// Load the random bookmarks posted to each chat
+	picks, err := newRandomPicks(store)
+	if err != nil {
+		logger.Fatal("Failed to load random bookmarks", "error", err)
 	}
 
-	return &KarakeepBot{
+	kb := &KarakeepBot{
 		karakeep:       createKarakeep(logger, &config.Karakeep),
 		telegram:       createTelegram(logger, &config.Telegram),
-		allowlist:      config.Telegram.Allowlist,
-		threads:        config.Telegram.Threads,
-		waitInterval:   config.Karakeep.Interval,
 		fileProcessor:  fileProcessor,
 		fileValidators: fileValidators,
+		urlCleaner:     urlCleaner,
+		state:          store,
+		grants:         grants,
+		watches:        watches,
+		digests:        digests,
+		reminders:      reminders,
+		confirmations:  confirmations,
+		picks:          picks,
+		random:         rand.IntN,
+		clock:          scheduler.SystemClock,
+		scheduler:      scheduler.New(scheduler.SystemClock),
+		config:         config,
+		loaded:         config,
 		logger:         logger,
 	}
+	kb.settings.Store(newSettings(config))
+
+	// Setup rate limiting
+	if config.RateLimit.Enabled {
+		kb.userLimiter = ratelimit.New[int64](config.RateLimit.UserRate, time.Minute, config.RateLimit.UserBurst)
+		kb.chatLimiter = ratelimit.New[int64](config.RateLimit.ChatRate, time.Minute, config.RateLimit.ChatBurst)
+	}
+	if config.RateLimit.Crawls > 0 {
+		kb.crawls = make(chan struct{}, config.RateLimit.Crawls)
+	}
+
+	// Setup Karakeep webhook events
+	if config.Webhook.Enabled {
+		kb.tagEvents = newTagEvents()
+	}
+
+	return kb
+}
+
+// checkMimetypes checks that there is a validator for every configured MIME
+// type.
+func checkMimetypes(validators map[string]fileprocessor.Validator, mimetypes []string) error {
+	for _, mimetype := range mimetypes {
+		if _, supported := validators[mimetype]; !supported {
+			return fmt.Errorf("unsupported MIME type %s", mimetype)
+		}
+	}
+	return nil
 }
 
 // Run starts the bot and handles incoming messages.
@@ -80,6 +184,27 @@ func (kb *KarakeepBot) Run() error {
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
 	defer cancel()
 
+	// Reload the configuration on SIGHUP or when the file changes
+	go kb.watchConfig(ctx)
+
+	// Listen for Karakeep webhook events
+	if kb.tagEvents != nil {
+		if err := kb.serveWebhook(ctx, &kb.config.Webhook); err != nil {
+			return err
+		}
+	}
+
+	// Resume watching the bookmarks still waiting for AI tags
+	kb.resumeWatches(ctx)
+
+	// Post the scheduled digests, reminders and random bookmarks, and remove
+	// the old events
+	kb.scheduleDigests()
+	kb.resumeReminders()
+	kb.scheduleRandomPosts()
+	kb.scheduleEventsCompaction()
+	go kb.scheduler.Run(ctx)
+
 	// Set default handler
 	kb.telegram.RegisterHandlerMatchFunc(func(*TelegramUpdate) bool { return true }, kb.handler)
 
@@ -89,37 +214,91 @@ func (kb *KarakeepBot) Run() error {
 	return nil
 }
 
-// handler is the main handler for incoming messages. It processes the message
-// and sends a response back to the user.
-func (kb KarakeepBot) handler(ctx context.Context, _ *Bot, update *TelegramUpdate) {
+// handler is the main handler for incoming messages, inline keyboard buttons
+// and inline queries. It processes the message and sends a response back to
+// the user.
+func (kb *KarakeepBot) handler(ctx context.Context, _ *Bot, update *TelegramUpdate) {
+	if update.CallbackQuery != nil {
+		kb.handleCallback(ctx, update.CallbackQuery)
+		return
+	}
+	if update.InlineQuery != nil {
+		kb.handleInlineQuery(ctx, update.InlineQuery)
+		return
+	}
 	if update.Message == nil {
 		return
 	}
 
 	msg := TelegramMessage(*update.Message)
 
-	// Check if the chat ID is allowed
-	if !kb.isChatIdAllowed(msg.Chat.ID) {
-		kb.logger.Warn(fmt.Sprintf("Received message from not allowed chat ID. Allowed chats IDs: %v", kb.allowlist), msg.Attrs()...)
+	// Check if the chat ID and the thread ID are allowed
+	if !kb.isMessageAllowed(&msg) {
 		return
 	}
 
-	// Check if the thread ID is allowed
-	if !kb.isThreadIdAllowed(msg.MessageThreadID) {
-		kb.logger.Warn(fmt.Sprintf("Received message from not allowed thread ID. Allowed thread IDs: %v", kb.threads), msg.Attrs()...)
+	kb.logger.Debug("Received message from allowed chat ID and allowed thread ID", msg.Attrs()...)
+
+	// Check the user role
+	role := kb.roleOf(msg.From.ID)
+	if role == RoleNone {
+		kb.logger.Warn("Denied message from user without a role", msg.Attrs()...)
+		if roles := kb.settings.Load().roles; roles.Unknown == config.ReplyUnknownPolicy {
+			kb.reply(ctx, &msg, roles.Reply)
+		}
 		return
 	}
 
-	kb.logger.Debug("Received message from allowed chat ID and allowed thread ID", msg.Attrs()...)
+	// Run commands
+	if name, args, ok := kb.parseCommand(&msg); ok {
+		kb.handleCommand(ctx, &msg, role, name, args)
+		return
+	}
+
+	// Check if the user can save bookmarks
+	if role < RoleContributor {
+		kb.logger.Warn("Denied bookmark: insufficient role", append(msg.Attrs(), "role", role, "required_role", RoleContributor)...)
+		kb.reply(ctx, &msg, fmt.Sprintf("⛔ Saving bookmarks requires the %s role.", RoleContributor))
+		return
+	}
+
+	// Check if the user or the chat is saving too fast
+	if !kb.allowSave(ctx, &msg) {
+		return
+	}
+
+	// Resolve the settings for this chat and thread
+	s := kb.settings.Load().settingsFor(msg.Chat.ID, msg.MessageThreadID)
+
+	// Check if photos are allowed before uploading them
+	if msg.Photo != nil && !s.allowsType(config.AssetBookmarkType) {
+		kb.logger.Info("Ignoring message with a bookmark type not allowed in this chat", append(msg.Attrs(), "type", config.AssetBookmarkType)...)
+		return
+	}
 
 	// Parse the message to get corresponding bookmark type
 	kb.logger.Debug("Parsing message to get corresponding bookmark type", msg.Attrs()...)
-	b, err := kb.parseMessage(ctx, msg)
+	b, list, err := kb.parseMessage(ctx, msg)
 	if err != nil {
 		kb.logger.Error("Failed to parse message", msg.AttrsWithError(err)...)
 		return
 	}
 
+	// Check if the bookmark type is allowed
+	bookmarkType := bookmarkTypeOf(b)
+	if !s.allowsType(bookmarkType) {
+		kb.logger.Info("Ignoring message with a bookmark type not allowed in this chat", append(msg.Attrs(), "type", bookmarkType)...)
+		return
+	}
+
+	// Wait for a free crawl slot
+	release, err := kb.acquireCrawl(ctx)
+	if err != nil {
+		kb.logger.Error("Failed to wait for a free crawl slot", msg.AttrsWithError(err)...)
+		return
+	}
+	defer release()
+
 	// Create the bookmark
 	kb.logger.Debug(fmt.Sprintf("Creating bookmark of type %s", b))
 	bookmark, err := kb.karakeep.CreateBookmark(ctx, b)
@@ -128,43 +307,83 @@ func (kb KarakeepBot) handler(ctx context.Context, _ *Bot, update *TelegramUpdat
 		return
 	}
 	kb.logger.Info("Created bookmark", bookmark.Attrs()...)
+	kb.recordSaved(msg, bookmark, bookmarkType)
 
 	// Enrich bookmark with Telegram origin metadata
 	kb.logger.Debug("Enriching bookmark with Telegram origin metadata", bookmark.Attrs()...)
-	kb.enrichBookmark(ctx, msg, bookmark)
+	kb.enrichBookmark(ctx, msg, bookmark, s.tags)
+	kb.addToList(ctx, bookmark, list)
+
+	// Nothing else to do if the bot doesn't reply
+	if s.replyMode == config.ReplyModeNone {
+		return
+	}
 
 	// Wait until bookmark tags are updated (with a timeout to avoid hanging on
-	// uncrawlable URLs)
+	// uncrawlable URLs), unless they aren't awaited for this bookmark type or
+	// the message is edited once they arrive
+	wait := s
+	if !s.waitsForTags(bookmarkType) || s.deferred {
+		wait.tagTimeout = 0
+	}
 	kb.logger.Debug("Waiting for bookmark tags to be updated", bookmark.Attrs()...)
-	bookmark, err = kb.waitForTagCompletion(ctx, bookmark)
+	bookmark, err = kb.waitForTagCompletion(ctx, bookmark, wait)
 	if err != nil {
 		kb.logger.Error("Failed to wait for bookmark tagging", "error", err)
 		return
 	}
+	kb.recordTagged(bookmark)
 
-	// Get hashtags
-	hashtags := bookmark.Hashtags()
+	// Send back the hashtags, and watch the bookmark to edit them later if AI
+	// tags are still pending
+	pending := s.watchesTags(bookmarkType) && bookmark.taggingStatus() != karakeep.BookmarkTaggingStatusSuccess
+	hashtags := bookmark.Hashtags(kb.settings.Load().hashtags)
+	sent, err := kb.sendHashtags(ctx, msg, hashtags, s.replyMode, pending)
+	if err != nil {
+		return
+	}
+	kb.confirmSent(sent, bookmark.Id)
+	if pending {
+		kb.watchTags(ctx, newTagWatch(msg, sent, bookmark.Id, hashtags, s))
+	}
+}
+
+// sendHashtags sends the hashtags back according to the reply mode: as a reply
+// to the original message, or as a new message replacing it. If pending is
+// true, AI tags are still expected. It returns the sent message.
+func (kb *KarakeepBot) sendHashtags(ctx context.Context, msg TelegramMessage, hashtags string, replyMode string, pending bool) (*TelegramMessage, error) {
+	text := hashtagsText(originalText(msg), hashtags, replyMode, pending)
+
+	// Reply to the original message with hashtags, keeping it
+	if replyMode == config.ReplyModeReply {
+		kb.logger.Debug("Replying to original message with hashtags", msg.Attrs()...)
+		sent, err := kb.telegram.SendReply(ctx, &msg, text)
+		if err != nil {
+			kb.logger.Error("Failed to send reply", msg.AttrsWithError(err)...)
+			return nil, err
+		}
+		kb.logger.Info("Replied to message", msg.Attrs()...)
+		return sent, nil
+	}
 
 	// Send back with hashtags
+	var sent *TelegramMessage
+	var err error
 	if msg.Photo != nil {
-		// Add hashtags
-		caption := msg.Caption + "\n\n" + hashtags
-
 		// Send back the original photo with hashtags as caption
 		kb.logger.Debug("Sending updated message with photo and hashtags", msg.Attrs()...)
-		if err := kb.telegram.SendPhotoWithCaption(ctx, &msg, msg.Photo[len(msg.Photo)-1].FileID, caption); err != nil {
+		if sent, err = kb.telegram.SendPhotoWithCaption(ctx, &msg, msg.Photo[len(msg.Photo)-1].FileID, text); err != nil {
 			kb.logger.Error("Failed to send photo with caption", msg.AttrsWithError(err)...)
-			return
+			return nil, err
 		}
 	} else {
-		// Add hashtags
-		msg.Text = msg.Text + "\n\n" + hashtags
-
 		// Send back new message with hashtags
 		kb.logger.Debug("Sending updated message with hashtags", msg.Attrs()...)
-		if err := kb.telegram.SendNewMessage(ctx, &msg); err != nil {
+		updated := msg
+		updated.Text = text
+		if sent, err = kb.telegram.SendNewMessage(ctx, &updated); err != nil {
 			kb.logger.Error("Failed to send new message", msg.AttrsWithError(err)...)
-			return
+			return nil, err
 		}
 	}
 
@@ -172,62 +391,233 @@ func (kb KarakeepBot) handler(ctx context.Context, _ *Bot, update *TelegramUpdat
 	kb.logger.Debug("Deleting original message", msg.Attrs()...)
 	if err := kb.telegram.DeleteOriginalMessage(ctx, &msg); err != nil {
 		kb.logger.Error("Failed to delete original message", msg.AttrsWithError(err)...)
-		return
+		return sent, nil
 	}
 
 	kb.logger.Info("Updated message", msg.Attrs()...)
+	return sent, nil
 }
 
-// isChatIdAllowed checks if the chat ID is allowed to receive messages.
-func (kb KarakeepBot) isChatIdAllowed(chatId int64) bool {
-	// When no allowlist is provided, all chat IDs are allowed
-	if len(kb.allowlist) == 0 {
+// hashtagsText returns the text sent back for a message with the given
+// hashtags: the hashtags alone when replying, or the original text (or
+// caption) followed by the hashtags when replacing it. If pending is true, AI
+// tags are still expected.
+func hashtagsText(original string, hashtags string, replyMode string, pending bool) string {
+	if replyMode == config.ReplyModeReply {
+		switch {
+		case hashtags != "":
+			return hashtags
+		case pending:
+			return "⏳ Waiting for tags..."
+		default:
+			return "🏷️ No tags"
+		}
+	}
+
+	return original + "\n\n" + hashtags
+}
+
+// originalText returns the caption of photos, or the text of other messages.
+func originalText(msg TelegramMessage) string {
+	if msg.Photo != nil {
+		return msg.Caption
+	}
+	return msg.Text
+}
+
+// allowSave checks the per-user and per-chat rate limits for a new bookmark.
+// The first time a limit is hit, it replies with a throttling notice; further
+// messages are dropped silently until the limit recovers.
+func (kb *KarakeepBot) allowSave(ctx context.Context, msg *TelegramMessage) bool {
+	if kb.userLimiter == nil || kb.chatLimiter == nil {
 		return true
 	}
 
-	// When the allowlist provided by environment variable is empty, it contains
-	// a single element with value 0.
-	if len(kb.allowlist) == 1 && kb.allowlist[0] == 0 {
+	allowed, notify := kb.userLimiter.Allow(msg.From.ID)
+	limit := "user"
+	if allowed {
+		allowed, notify = kb.chatLimiter.Allow(msg.Chat.ID)
+		limit = "chat"
+		if !allowed {
+			// Messages denied by the chat limit don't count for the user
+			kb.userLimiter.Cancel(msg.From.ID)
+		}
+	}
+	if allowed {
 		return true
 	}
 
-	return slices.Contains(kb.allowlist, chatId)
+	kb.logger.Warn("Denied bookmark: rate limit exceeded", append(msg.Attrs(), "limit", limit)...)
+	if notify {
+		kb.reply(ctx, msg, "🐢 Slow down! Too many messages, please wait a moment before sending more.")
+	}
+	return false
+}
+
+// acquireCrawl waits until fewer than the configured number of bookmarks are
+// being processed by Karakeep. The returned function releases the slot.
+func (kb *KarakeepBot) acquireCrawl(ctx context.Context) (release func(), err error) {
+	if kb.crawls == nil {
+		return func() {}, nil
+	}
+
+	select {
+	case kb.crawls <- struct{}{}:
+		return func() { <-kb.crawls }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// isMessageAllowed checks if the message comes from an allowed chat ID and
+// thread ID, logging a warning otherwise.
+func (kb *KarakeepBot) isMessageAllowed(msg *TelegramMessage) bool {
+	if !kb.isChatIdAllowed(msg.Chat.ID) {
+		kb.logger.Warn(fmt.Sprintf("Received message from not allowed chat ID. Allowed chats IDs: %v", kb.settings.Load().allowlist), msg.Attrs()...)
+		return false
+	}
+
+	if !kb.isThreadIdAllowed(msg.MessageThreadID) {
+		kb.logger.Warn(fmt.Sprintf("Received message from not allowed thread ID. Allowed thread IDs: %v", kb.settings.Load().threads), msg.Attrs()...)
+		return false
+	}
+
+	return true
+}
+
+// isChatIdAllowed checks if the chat ID is allowed to receive messages.
+func (kb *KarakeepBot) isChatIdAllowed(chatId int64) bool {
+	allowlist := kb.settings.Load().allowlist
+
+	// When no allowlist is provided, all chat IDs are allowed
+	return len(allowlist) == 0 || slices.Contains(allowlist, chatId)
 }
 
 // isThreadIdAllowed checks if the thread ID is allowed to receive messages.
-func (kb KarakeepBot) isThreadIdAllowed(threadId int) bool {
-	return len(kb.threads) == 0 || slices.Contains(kb.threads, threadId)
+func (kb *KarakeepBot) isThreadIdAllowed(threadId int) bool {
+	threads := kb.settings.Load().threads
+	return len(threads) == 0 || slices.Contains(threads, threadId)
 }
 
 // waitForTagCompletion polls the bookmark tagging status until it succeeds,
-// fails, or the retry timeout is reached. Returns the updated bookmark.
-func (kb *KarakeepBot) waitForTagCompletion(ctx context.Context, bookmark *KarakeepBookmark) (*KarakeepBookmark, error) {
-	retries := 0
+// fails, or the tag timeout of the chat settings is reached. The interval
+// between polls starts at the wait interval and doubles up to the maximum
+// interval. When the Karakeep webhook is enabled, the bookmark is checked again
+// as soon as an event for it is received, and otherwise every maximum interval,
+// in case an event is lost. A zero timeout retrieves the bookmark once without
+// waiting. Returns the updated bookmark.
+func (kb *KarakeepBot) waitForTagCompletion(ctx context.Context, bookmark *KarakeepBookmark, s chatSettings) (*KarakeepBookmark, error) {
+	// Subscribe before retrieving the bookmark, so no event is missed.
+	var events <-chan struct{}
+	if kb.tagEvents != nil && s.tagTimeout > 0 {
+		var unsubscribe func()
+		events, unsubscribe = kb.tagEvents.subscribe(bookmark.Id)
+		defer unsubscribe()
+	}
+
+	deadline := time.Now().Add(s.tagTimeout)
+	interval := s.waitInterval
 	for {
 		var err error
 		bookmark, err = kb.karakeep.RetrieveBookmarkById(ctx, bookmark.Id)
 		if err != nil {
 			return nil, err
 		}
-		if *bookmark.TaggingStatus == karakeep.BookmarkTaggingStatusSuccess {
+		// A missing tagging status is handled as pending.
+		switch bookmark.taggingStatus() {
+		case karakeep.BookmarkTaggingStatusSuccess:
 			return bookmark, nil
-		}
-		if *bookmark.TaggingStatus == karakeep.BookmarkTaggingStatusFailure {
+		case karakeep.BookmarkTaggingStatusFailure:
 			return nil, fmt.Errorf("bookmark tagging failed")
 		}
-		retries++
-		if retries >= maxTagRetries {
+		if s.tagTimeout == 0 {
+			kb.logger.Debug("Not waiting for bookmark tagging", bookmark.Attrs()...)
+			return bookmark, nil
+		}
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
 			kb.logger.Warn("Bookmark tagging did not complete within timeout, proceeding anyway", bookmark.Attrs()...)
 			return bookmark, nil
 		}
-		kb.logger.Debug(fmt.Sprintf("Bookmark is still pending, waiting %d seconds before retrying", kb.waitInterval), bookmark.Attrs()...)
-		time.Sleep(time.Duration(kb.waitInterval) * time.Second)
+		// With events, Karakeep is still polled every maximum interval in
+		// case an event is lost
+		wait := min(interval, remaining)
+		if events != nil {
+			wait = min(max(interval, s.maxInterval), remaining)
+		}
+		kb.logger.Debug(fmt.Sprintf("Bookmark is still pending, waiting up to %s before retrying", wait), bookmark.Attrs()...)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-events:
+		case <-time.After(wait):
+		}
+		interval = s.nextInterval(interval)
 	}
 }
 
 // parseMessage parses the incoming Telegram message and returns the
-// corresponding Bookmark type.
-func (kb KarakeepBot) parseMessage(ctx context.Context, msg TelegramMessage) (BookmarkType, error) {
+// corresponding Bookmark type, and the list to add it to if the message starts
+// with the list prefix and the name of a manual list (e.g. "@reading"). The
+// list name is removed from the saved text.
+func (kb *KarakeepBot) parseMessage(ctx context.Context, msg TelegramMessage) (BookmarkType, *karakeep.List, error) {
+	msg, list := kb.extractList(ctx, msg)
+	b, err := kb.parseBookmark(ctx, msg)
+	if err != nil {
+		return nil, nil, err
+	}
+	return b, list, nil
+}
+
+// extractList returns the list named at the start of the message text or
+// photo caption, and the message without it. If no manual list is named, the
+// message is returned as is.
+func (kb *KarakeepBot) extractList(ctx context.Context, msg TelegramMessage) (TelegramMessage, *karakeep.List) {
+	prefix := kb.settings.Load().listPrefix
+	text := &msg.Text
+	if msg.Photo != nil {
+		text = &msg.Caption
+	}
+
+	trimmed := strings.TrimLeftFunc(*text, unicode.IsSpace)
+	end := strings.IndexFunc(trimmed, unicode.IsSpace)
+	if end < 0 {
+		end = len(trimmed)
+	}
+	name, found := strings.CutPrefix(trimmed[:end], prefix)
+	if prefix == "" || !found || name == "" {
+		return msg, nil
+	}
+
+	list, err := kb.karakeep.ResolveList(ctx, name)
+	if err != nil {
+		kb.logger.Warn("Failed to resolve list, saving the message as is", append(msg.AttrsWithError(err), "list", name)...)
+		return msg, nil
+	}
+	if list == nil || isSmartList(list) {
+		kb.logger.Debug("Message doesn't start with the name of a manual list", append(msg.Attrs(), "list", name)...)
+		return msg, nil
+	}
+
+	*text = strings.TrimLeftFunc(trimmed[end:], unicode.IsSpace)
+	return msg, list
+}
+
+// addToList adds the bookmark to the list, if any. Non-fatal on failure.
+func (kb *KarakeepBot) addToList(ctx context.Context, bookmark *KarakeepBookmark, list *karakeep.List) {
+	if list == nil {
+		return
+	}
+
+	if err := kb.karakeep.AddToList(ctx, list.Id, bookmark.Id); err != nil {
+		kb.logger.Warn("Failed to add bookmark to list", append(bookmark.Attrs(), "list", list.Name, "error", err)...)
+		return
+	}
+	kb.logger.Info("Added bookmark to list", append(bookmark.Attrs(), "list", list.Name)...)
+}
+
+// parseBookmark returns the Bookmark type corresponding to the message.
+func (kb *KarakeepBot) parseBookmark(ctx context.Context, msg TelegramMessage) (BookmarkType, error) {
 	if msg.Photo != nil {
 		return kb.handlePhotoMessage(ctx, msg)
 	}
@@ -252,15 +642,15 @@ func (kb KarakeepBot) parseMessage(ctx context.Context, msg TelegramMessage) (Bo
 	}
 
 	if url := msg.ExtractURL(); url != "" {
-		return newSimpleLinkBookmark(url, msg), nil
+		return kb.newSimpleLinkBookmark(ctx, url, msg), nil
 	}
 
 	if validation.ValidateURL(msg.Text) == nil {
-		return newSimpleLinkBookmark(msg.Text, msg), nil
+		return kb.newSimpleLinkBookmark(ctx, msg.Text, msg), nil
 	}
 
 	if url := extractEmbeddedURL(msg.Text); url != "" {
-		return newSimpleLinkBookmark(url, msg), nil
+		return kb.newSimpleLinkBookmark(ctx, url, msg), nil
 	}
 
 	if msg.Text != "" {
@@ -276,8 +666,14 @@ func (kb KarakeepBot) parseMessage(ctx context.Context, msg TelegramMessage) (Bo
 
 // newSimpleLinkBookmark creates a LinkBookmark from a URL and message, with
 // title extracted from the first line and a note combining the message text
-// with Telegram origin context.
-func newSimpleLinkBookmark(url string, msg TelegramMessage) *LinkBookmark {
+// with Telegram origin context. The URL is cleaned of trackers and
+// redirectors before being saved.
+func (kb *KarakeepBot) newSimpleLinkBookmark(ctx context.Context, url string, msg TelegramMessage) *LinkBookmark {
+	if cleaned := kb.urlCleaner.Clean(ctx, url); cleaned != url {
+		kb.logger.Info("Rewrote URL", "original_url", url, "url", cleaned)
+		url = cleaned
+	}
+
 	lb := NewLinkBookmark(url)
 	lb.Title = extractTitle(msg.Text)
 	lb.Note = buildNote(msg.Text, msg.ContextNote())
@@ -335,7 +731,7 @@ func (kb *KarakeepBot) handlePhotoMessage(ctx context.Context, msg TelegramMessa
 	fileURL, err := kb.telegram.GetFileURL(ctx, photo.FileID)
 	if err != nil {
 		kb.logger.Error("Failed to get file URL", msg.AttrsWithError(err)...)
-		if replyErr := kb.telegram.SendReply(ctx, &msg, "⚠️ Failed to process image from Telegram servers, try again later"); replyErr != nil {
+		if _, replyErr := kb.telegram.SendReply(ctx, &msg, "⚠️ Failed to process image from Telegram servers, try again later"); replyErr != nil {
 			kb.logger.Error("Failed to send reply to user", "reply_error", replyErr)
 		}
 		return nil, errors.New("couldn't get file URL")
@@ -345,7 +741,7 @@ func (kb *KarakeepBot) handlePhotoMessage(ctx context.Context, msg TelegramMessa
 	filePath, mimeType, err := kb.fileProcessor.Process(fileURL, nil)
 	if err != nil {
 		kb.logger.Error("Failed to process image", msg.AttrsWithError(err)...)
-		if replyErr := kb.telegram.SendReply(ctx, &msg, "⚠️ Failed to process image"); replyErr != nil {
+		if _, replyErr := kb.telegram.SendReply(ctx, &msg, "⚠️ Failed to process image"); replyErr != nil {
 			kb.logger.Error("Failed to send reply to user", "reply_error", replyErr)
 		}
 		return nil, errors.New("couldn't process image")
@@ -365,7 +761,7 @@ func (kb *KarakeepBot) handlePhotoMessage(ctx context.Context, msg TelegramMessa
 	asset, err := kb.karakeep.CreateAsset(ctx, filePath, mimeType)
 	if err != nil {
 		kb.logger.Error("Failed to upload asset", msg.AttrsWithError(err)...)
-		if replyErr := kb.telegram.SendReply(ctx, &msg, "⚠️ Failed to upload asset to Karakeep"); replyErr != nil {
+		if _, replyErr := kb.telegram.SendReply(ctx, &msg, "⚠️ Failed to upload asset to Karakeep"); replyErr != nil {
 			kb.logger.Error("Failed to send reply to user", "reply_error", replyErr)
 		}
 		return nil, errors.New("couldn't upload asset")
@@ -373,9 +769,14 @@ func (kb *KarakeepBot) handlePhotoMessage(ctx context.Context, msg TelegramMessa
 
 	kb.logger.Debug("Asset uploaded successfully", "asset_id", asset.AssetId)
 
-	// Get note from caption and append Telegram origin context
+	return newPhotoBookmark(asset.AssetId, msg), nil
+}
+
+// newPhotoBookmark creates an AssetBookmark for an uploaded photo, using the
+// message caption as note and appending the Telegram origin context.
+func newPhotoBookmark(assetID string, msg TelegramMessage) *AssetBookmark {
 	note := strings.TrimSpace(msg.Caption)
-	ab := NewAssetBookmark(asset.AssetId, ImageAssetType, note)
+	ab := NewAssetBookmark(assetID, ImageAssetType, note)
 	if ctxNote := msg.ContextNote(); ctxNote != "" {
 		if ab.Note != "" {
 			ab.Note += "\n\n" + ctxNote
@@ -383,5 +784,5 @@ func (kb *KarakeepBot) handlePhotoMessage(ctx context.Context, msg TelegramMessa
 			ab.Note = ctxNote
 		}
 	}
-	return ab, nil
+	return ab
 }
@@ -0,0 +1,138 @@
+package karakeepbot
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Madh93/karakeepbot/internal/logging"
+	"github.com/go-telegram/bot/models"
+)
+
+// newTestKarakeep returns a bot connected to a Karakeep server that returns the
+// given tagging statuses for a bookmark, one per request, repeating the last
+// one. A nil status is left out of the response.
+func newTestKarakeep(t *testing.T, statuses ...*string) (*KarakeepBot, *atomic.Int32) {
+	t.Helper()
+
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(calls.Add(1))
+		status := statuses[min(n, len(statuses))-1]
+
+		bookmark := map[string]any{
+			"id":            "bookmark",
+			"createdAt":     "2026-01-01T00:00:00Z",
+			"modifiedAt":    nil,
+			"archived":      false,
+			"favourited":    false,
+			"taggingStatus": status,
+			"tags":          []any{},
+			"content":       map[string]any{"type": "text", "text": "text"},
+			"assets":        []any{},
+		}
+		if status != nil && *status == "success" {
+			bookmark["tags"] = []any{map[string]any{"id": "tag", "name": "golang", "attachedBy": "ai"}}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(bookmark)
+	}))
+	t.Cleanup(server.Close)
+
+	cfg := newTestConfig()
+	cfg.Karakeep.URL = server.URL
+	logger := logging.New(&cfg.Logging)
+
+	kb := &KarakeepBot{karakeep: createKarakeep(logger, &cfg.Karakeep), logger: logger}
+	kb.settings.Store(newSettings(cfg))
+	return kb, &calls
+}
+
+func TestKarakeepBot_waitForTagCompletion(t *testing.T) {
+	status := func(s string) *string { return &s }
+	s := chatSettings{waitInterval: time.Millisecond, maxInterval: 4 * time.Millisecond, tagTimeout: 100 * time.Millisecond}
+
+	tests := []struct {
+		name          string
+		statuses      []*string
+		settings      chatSettings
+		expectedTags  string
+		expectedCalls int32
+		expectError   bool
+	}{
+		{"Tagged after retries", []*string{status("pending"), status("pending"), status("success")}, s, "🤖 #golang", 3, false},
+		{"Missing status is pending", []*string{nil, status("success")}, s, "🤖 #golang", 2, false},
+		{"Tagging failed", []*string{status("pending"), status("failure")}, s, "", 2, true},
+		{"Not waiting", []*string{status("pending")}, chatSettings{}, "", 1, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kb, calls := newTestKarakeep(t, tt.statuses...)
+
+			bookmark, err := kb.waitForTagCompletion(context.Background(), &KarakeepBookmark{Id: "bookmark"}, tt.settings)
+			if (err != nil) != tt.expectError {
+				t.Fatalf("Expected error: %v, but got: %v", tt.expectError, err)
+			}
+			hashtags := kb.settings.Load().hashtags
+			if err == nil && bookmark.Hashtags(hashtags) != tt.expectedTags {
+				t.Errorf("Expected hashtags %q, got %q", tt.expectedTags, bookmark.Hashtags(hashtags))
+			}
+			if got := calls.Load(); got != tt.expectedCalls {
+				t.Errorf("Expected %d calls, got %d", tt.expectedCalls, got)
+			}
+		})
+	}
+
+	t.Run("Timeout", func(t *testing.T) {
+		kb, calls := newTestKarakeep(t, status("pending"))
+
+		start := time.Now()
+		bookmark, err := kb.waitForTagCompletion(context.Background(), &KarakeepBookmark{Id: "bookmark"}, chatSettings{waitInterval: 10 * time.Millisecond, maxInterval: 20 * time.Millisecond, tagTimeout: 50 * time.Millisecond})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if bookmark.taggingStatus() != "pending" {
+			t.Errorf("Expected the pending bookmark to be returned, got status %q", bookmark.taggingStatus())
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("Expected to give up after the timeout, took %s", elapsed)
+		}
+		// Polls at 0, 10, 30 and 50ms, with backoff.
+		if got := calls.Load(); got < 3 || got > 5 {
+			t.Errorf("Expected 3 to 5 calls, got %d", got)
+		}
+	})
+}
+
+func TestHashtagsText(t *testing.T) {
+	text := TelegramMessage{Text: "https://go.dev"}
+	photo := TelegramMessage{Caption: "A gopher", Photo: []models.PhotoSize{{FileID: "photo"}}}
+
+	tests := []struct {
+		name      string
+		msg       TelegramMessage
+		hashtags  string
+		replyMode string
+		pending   bool
+		expected  string
+	}{
+		{"Replace text", text, "#golang", "replace", false, "https://go.dev\n\n#golang"},
+		{"Replace photo", photo, "#gopher", "replace", false, "A gopher\n\n#gopher"},
+		{"Reply", text, "#golang", "reply", false, "#golang"},
+		{"Reply pending tags", text, "", "reply", true, "⏳ Waiting for tags..."},
+		{"Reply without tags", text, "", "reply", false, "🏷️ No tags"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hashtagsText(originalText(tt.msg), tt.hashtags, tt.replyMode, tt.pending); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
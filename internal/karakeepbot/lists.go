@@ -0,0 +1,127 @@
+package karakeepbot
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/Madh93/go-karakeep"
+)
+
+// listsPageSize is the number of lists per page of /lists.
+const listsPageSize = 20
+
+// handleListsCommand lists the Karakeep lists.
+func (kb *KarakeepBot) handleListsCommand(ctx context.Context, msg *TelegramMessage, _ []string) {
+	kb.sendPage(ctx, msg, "lists", "")
+}
+
+// handleListCommand lists the recent bookmarks in a list. The name can be
+// quoted, and written with the list prefix.
+func (kb *KarakeepBot) handleListCommand(ctx context.Context, msg *TelegramMessage, args []string) {
+	name := strings.Join(splitQuoted(strings.Join(args, " ")), " ")
+	if prefix := kb.settings.Load().listPrefix; prefix != "" {
+		name = strings.TrimPrefix(name, prefix)
+	}
+	if name == "" {
+		kb.reply(ctx, msg, "⚠️ Missing list name. Usage: /list <name>")
+		return
+	}
+
+	list, err := kb.karakeep.ResolveList(ctx, name)
+	if err != nil {
+		kb.logger.Error("Failed to resolve list", append(msg.AttrsWithError(err), "list", name)...)
+		kb.reply(ctx, msg, "⚠️ Failed to get the lists from Karakeep, try again later")
+		return
+	}
+	if list == nil {
+		kb.reply(ctx, msg, fmt.Sprintf("🔍 List %q not found. Use /lists to list them.", name))
+		return
+	}
+
+	kb.sendPage(ctx, msg, "list", list.Id)
+}
+
+// renderListsPage renders a page of the lists, with nested lists below their
+// parent.
+func (kb *KarakeepBot) renderListsPage(ctx context.Context, _ string, page int) (string, bool, error) {
+	lists, err := kb.karakeep.Lists(ctx)
+	if err != nil {
+		return "", false, err
+	}
+	if len(lists) == 0 {
+		return "📋 There are no lists yet.", false, nil
+	}
+
+	lines := listLines(lists)
+	pages := (len(lines) + listsPageSize - 1) / listsPageSize
+	page = min(page, pages-1)
+	var b strings.Builder
+	fmt.Fprintf(&b, "📋 Lists (page %d/%d):\n", page+1, pages)
+	for _, line := range lines[page*listsPageSize : min((page+1)*listsPageSize, len(lines))] {
+		b.WriteString("\n" + line)
+	}
+	b.WriteString("\n\nUse /list <name> to see its bookmarks.")
+	if prefix := kb.settings.Load().listPrefix; prefix != "" {
+		fmt.Fprintf(&b, " Start a message with %s<name> to save it into a list.", prefix)
+	}
+
+	return b.String(), page < pages-1, nil
+}
+
+// listLines returns a line for every list, sorted by name and indented below
+// their parent.
+func listLines(lists []karakeep.List) []string {
+	ids := make(map[string]bool, len(lists))
+	for _, list := range lists {
+		ids[list.Id] = true
+	}
+	children := make(map[string][]karakeep.List)
+	for _, list := range lists {
+		parent := ""
+		if list.ParentId != nil && ids[*list.ParentId] {
+			parent = *list.ParentId
+		}
+		children[parent] = append(children[parent], list)
+	}
+
+	var lines []string
+	var walk func(parent string, depth int)
+	walk = func(parent string, depth int) {
+		siblings := children[parent]
+		slices.SortFunc(siblings, func(a, b karakeep.List) int {
+			return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+		})
+		for _, list := range siblings {
+			line := fmt.Sprintf("%s• %s %s", strings.Repeat("    ", depth), list.Icon, list.Name)
+			if isSmartList(&list) {
+				line += " (smart)"
+			}
+			lines = append(lines, line)
+			walk(list.Id, depth+1)
+		}
+	}
+	walk("", 0)
+
+	return lines
+}
+
+// renderListPage renders a page of the most recent bookmarks in a list.
+func (kb *KarakeepBot) renderListPage(ctx context.Context, listID string, page int) (string, bool, error) {
+	list, err := kb.karakeep.List(ctx, listID)
+	if err != nil {
+		return "", false, err
+	}
+
+	bookmarks, more, err := kb.karakeep.ListBookmarks(ctx, listID, page, bookmarksPageSize)
+	if err != nil {
+		return "", false, err
+	}
+	if len(bookmarks) == 0 && page == 0 {
+		return fmt.Sprintf("%s There are no bookmarks in %q.", list.Icon, list.Name), false, nil
+	}
+
+	header := fmt.Sprintf("%s Bookmarks in %q (page %d):", list.Icon, list.Name, page+1)
+	return header + renderBookmarks(bookmarks, page*bookmarksPageSize), more, nil
+}
@@ -0,0 +1,167 @@
+package karakeepbot
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Madh93/go-karakeep"
+	"github.com/Madh93/karakeepbot/internal/logging"
+	"github.com/go-telegram/bot/models"
+)
+
+// testLists are the lists returned by the fake Karakeep server of
+// newTestListsBot.
+var testLists = []map[string]any{
+	{"id": "reading", "name": "Reading", "icon": "📚", "parentId": nil, "type": "manual"},
+	{"id": "toread", "name": "To read", "icon": "⏳", "parentId": "reading", "type": "manual"},
+	{"id": "golang", "name": "Golang", "icon": "🐹", "parentId": nil, "type": "smart", "query": "#golang"},
+	{"id": "archive", "name": "archive", "icon": "🗄️", "parentId": "deleted", "type": "manual"},
+}
+
+// newTestListsBot returns a bot connected to a fake Karakeep server with the
+// test lists, and the number of requests to get the lists.
+func newTestListsBot(t *testing.T) (*KarakeepBot, *atomic.Int32) {
+	t.Helper()
+
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/lists" {
+			http.NotFound(w, r)
+			return
+		}
+		calls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"lists": testLists})
+	}))
+	t.Cleanup(server.Close)
+
+	cfg := newTestConfig()
+	cfg.Karakeep.URL = server.URL
+	logger := logging.New(&cfg.Logging)
+
+	kb := &KarakeepBot{karakeep: createKarakeep(logger, &cfg.Karakeep), logger: logger}
+	kb.settings.Store(newSettings(cfg))
+	return kb, &calls
+}
+
+// listID returns the ID of the list, or an empty string if it's nil.
+func listID(list *karakeep.List) string {
+	if list == nil {
+		return ""
+	}
+	return list.Id
+}
+
+func TestKarakeep_ResolveList(t *testing.T) {
+	kb, calls := newTestListsBot(t)
+
+	tests := []struct {
+		name          string
+		expected      string
+		expectedCalls int32
+	}{
+		{"reading", "reading", 1},
+		{"TO_READ", "toread", 1},
+		{"To read", "toread", 1},
+		{"unknown", "", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list, err := kb.karakeep.ResolveList(context.Background(), tt.name)
+			if err != nil {
+				t.Fatalf("ResolveList() returned an unexpected error: %v", err)
+			}
+			if got := listID(list); got != tt.expected {
+				t.Errorf("Expected list %q, got %q", tt.expected, got)
+			}
+			if got := calls.Load(); got != tt.expectedCalls {
+				t.Errorf("Expected %d requests, got %d", tt.expectedCalls, got)
+			}
+		})
+	}
+
+	t.Run("Unknown names refresh a stale cache", func(t *testing.T) {
+		kb.karakeep.lists.fetched = time.Now().Add(-listCacheRefresh)
+		if _, err := kb.karakeep.ResolveList(context.Background(), "unknown"); err != nil {
+			t.Fatalf("ResolveList() returned an unexpected error: %v", err)
+		}
+		if got := calls.Load(); got != 2 {
+			t.Errorf("Expected 2 requests, got %d", got)
+		}
+	})
+}
+
+func TestKarakeepBot_extractList(t *testing.T) {
+	tests := []struct {
+		name         string
+		text         string
+		photo        bool
+		expectedText string
+		expectedList string
+	}{
+		{"Link into a list", "@reading https://go.dev", false, "https://go.dev", "reading"},
+		{"Text into a nested list", "  @to_read\nLearn Go", false, "Learn Go", "toread"},
+		{"Photo caption", "@Reading Nice diagram", true, "Nice diagram", "reading"},
+		{"Smart list", "@golang https://go.dev", false, "@golang https://go.dev", ""},
+		{"Unknown list", "@someone look at this", false, "@someone look at this", ""},
+		{"Prefix alone", "@ https://go.dev", false, "@ https://go.dev", ""},
+		{"No prefix", "https://go.dev @reading", false, "https://go.dev @reading", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kb, _ := newTestListsBot(t)
+			msg := TelegramMessage{Chat: models.Chat{ID: 1}, From: &models.User{ID: 1}}
+			if tt.photo {
+				msg.Photo, msg.Caption = []models.PhotoSize{{FileID: "photo"}}, tt.text
+			} else {
+				msg.Text = tt.text
+			}
+
+			got, list := kb.extractList(context.Background(), msg)
+			if text := got.Text + got.Caption; text != tt.expectedText {
+				t.Errorf("Expected text %q, got %q", tt.expectedText, text)
+			}
+			if id := listID(list); id != tt.expectedList {
+				t.Errorf("Expected list %q, got %q", tt.expectedList, id)
+			}
+		})
+	}
+
+	t.Run("Disabled prefix", func(t *testing.T) {
+		kb, calls := newTestListsBot(t)
+		cfg := newTestConfig()
+		cfg.Lists.Prefix = ""
+		kb.settings.Store(newSettings(cfg))
+
+		got, list := kb.extractList(context.Background(), TelegramMessage{Text: "@reading https://go.dev"})
+		if list != nil || got.Text != "@reading https://go.dev" || calls.Load() != 0 {
+			t.Errorf("Expected the message as is without requests, got %q, %v and %d requests", got.Text, list, calls.Load())
+		}
+	})
+}
+
+func TestListLines(t *testing.T) {
+	data, _ := json.Marshal(testLists)
+	var lists []karakeep.List
+	if err := json.Unmarshal(data, &lists); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"• 🗄️ archive",
+		"• 🐹 Golang (smart)",
+		"• 📚 Reading",
+		"    • ⏳ To read",
+	}
+	if got := listLines(lists); !slices.Equal(got, expected) {
+		t.Errorf("listLines() = %q, expected %q", got, expected)
+	}
+}
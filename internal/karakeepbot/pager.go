@@ -0,0 +1,138 @@
+package karakeepbot
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/go-telegram/bot/models"
+)
+
+// pager renders the pages of a paginated reply, which are browsed with inline
+// keyboard buttons.
+type pager struct {
+	role   Role // Minimum role required to browse the pages.
+	render func(ctx context.Context, arg string, page int) (text string, more bool, err error)
+}
+
+// pagers returns the paginated replies supported by the bot, indexed by name.
+func (kb *KarakeepBot) pagers() map[string]pager {
+	return map[string]pager{
+		"tags":  {role: RoleReader, render: kb.renderTagsPage},
+		"tag":   {role: RoleReader, render: kb.renderTagPage},
+		"lists": {role: RoleReader, render: kb.renderListsPage},
+		"list":  {role: RoleReader, render: kb.renderListPage},
+	}
+}
+
+// pageData returns the callback data of the button showing a page. Telegram
+// limits callback data to 64 bytes, so arg should be an ID rather than a name.
+func pageData(name, arg string, page int) string {
+	return fmt.Sprintf("%s:%d:%s", name, page, arg)
+}
+
+// parsePageData returns the pager name, argument and page of the callback data
+// of a button.
+func parsePageData(data string) (name, arg string, page int, err error) {
+	parts := strings.SplitN(data, ":", 3)
+	if len(parts) != 3 {
+		return "", "", 0, fmt.Errorf("invalid callback data %q", data)
+	}
+	page, err = strconv.Atoi(parts[1])
+	if err != nil || page < 0 {
+		return "", "", 0, fmt.Errorf("invalid page in callback data %q", data)
+	}
+	return parts[0], parts[2], page, nil
+}
+
+// pageKeyboard returns the buttons to browse to the previous and next pages,
+// or nil if there is a single page.
+func pageKeyboard(name, arg string, page int, more bool) *models.InlineKeyboardMarkup {
+	var buttons []models.InlineKeyboardButton
+	if page > 0 {
+		buttons = append(buttons, models.InlineKeyboardButton{Text: "◀️ Previous", CallbackData: pageData(name, arg, page-1)})
+	}
+	if more {
+		buttons = append(buttons, models.InlineKeyboardButton{Text: "Next ▶️", CallbackData: pageData(name, arg, page+1)})
+	}
+	if len(buttons) == 0 {
+		return nil
+	}
+	return &models.InlineKeyboardMarkup{InlineKeyboard: [][]models.InlineKeyboardButton{buttons}}
+}
+
+// sendPage replies to the message with the first page of a paginated reply.
+func (kb *KarakeepBot) sendPage(ctx context.Context, msg *TelegramMessage, name, arg string) {
+	text, more, err := kb.pagers()[name].render(ctx, arg, 0)
+	if err != nil {
+		kb.logger.Error("Failed to render page", append(msg.AttrsWithError(err), "pager", name)...)
+		kb.reply(ctx, msg, "⚠️ Failed to get the results from Karakeep, try again later")
+		return
+	}
+
+	if _, err := kb.telegram.SendReplyWithKeyboard(ctx, msg, text, pageKeyboard(name, arg, 0, more)); err != nil {
+		kb.logger.Error("Failed to send reply to user", msg.AttrsWithError(err)...)
+	}
+}
+
+// handleCallback handles the inline keyboard buttons to browse the pages of a
+// paginated reply, to archive the bookmarks of a digest and to cancel
+// reminders. Buttons are subject to the same allowlist and role checks as
+// messages.
+func (kb *KarakeepBot) handleCallback(ctx context.Context, query *models.CallbackQuery) {
+	answer := func(text string) {
+		if err := kb.telegram.AnswerCallback(ctx, query.ID, text); err != nil {
+			kb.logger.Error("Failed to answer callback query", "scope", "telegram", "user_id", query.From.ID, "error", err)
+		}
+	}
+
+	if query.Message.Message == nil {
+		answer("⌛ This message is too old, run the command again.")
+		return
+	}
+
+	// The message is the one sent by the bot, but the user is who pressed the
+	// button
+	msg := TelegramMessage(*query.Message.Message)
+	msg.From = &query.From
+	if !kb.isMessageAllowed(&msg) {
+		answer("")
+		return
+	}
+
+	if strings.HasPrefix(query.Data, archiveCallbackData) {
+		kb.handleArchiveCallback(ctx, query, &msg, answer)
+		return
+	}
+	if strings.HasPrefix(query.Data, cancelReminderCallbackData) {
+		kb.handleCancelReminderCallback(ctx, query, &msg, answer)
+		return
+	}
+
+	name, arg, page, err := parsePageData(query.Data)
+	p, ok := kb.pagers()[name]
+	if err != nil || !ok {
+		kb.logger.Debug("Received unknown callback query", append(msg.Attrs(), "data", query.Data)...)
+		answer("")
+		return
+	}
+
+	if role := kb.roleOf(query.From.ID); role < p.role {
+		kb.logger.Warn("Denied callback query: insufficient role", append(msg.Attrs(), "pager", name, "role", role, "required_role", p.role)...)
+		answer(fmt.Sprintf("⛔ This requires the %s role.", p.role))
+		return
+	}
+
+	text, more, err := p.render(ctx, arg, page)
+	if err != nil {
+		kb.logger.Error("Failed to render page", append(msg.AttrsWithError(err), "pager", name, "page", page)...)
+		answer("⚠️ Failed to get the results from Karakeep, try again later")
+		return
+	}
+
+	if err := kb.telegram.EditTextWithKeyboard(ctx, &msg, text, pageKeyboard(name, arg, page, more)); err != nil {
+		kb.logger.Error("Failed to edit page", append(msg.AttrsWithError(err), "pager", name, "page", page)...)
+	}
+	answer("")
+}
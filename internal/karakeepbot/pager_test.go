@@ -0,0 +1,72 @@
+package karakeepbot
+
+import (
+	"testing"
+)
+
+func TestParsePageData(t *testing.T) {
+	tests := []struct {
+		data         string
+		expectedName string
+		expectedArg  string
+		expectedPage int
+		expectError  bool
+	}{
+		{pageData("tags", "", 2), "tags", "", 2, false},
+		{pageData("tag", "tag:with:colons", 0), "tag", "tag:with:colons", 0, false},
+		{"tags:1", "", "", 0, true},
+		{"tags:x:", "", "", 0, true},
+		{"tags:-1:", "", "", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.data, func(t *testing.T) {
+			name, arg, page, err := parsePageData(tt.data)
+			if (err != nil) != tt.expectError {
+				t.Fatalf("parsePageData(%q) error = %v, expected error: %v", tt.data, err, tt.expectError)
+			}
+			if name != tt.expectedName || arg != tt.expectedArg || page != tt.expectedPage {
+				t.Errorf("parsePageData(%q) = %q, %q, %d, expected %q, %q, %d", tt.data, name, arg, page, tt.expectedName, tt.expectedArg, tt.expectedPage)
+			}
+		})
+	}
+}
+
+func TestPageKeyboard(t *testing.T) {
+	tests := []struct {
+		name     string
+		page     int
+		more     bool
+		expected []string
+	}{
+		{"Single page", 0, false, nil},
+		{"First page", 0, true, []string{"tag:1:id"}},
+		{"Middle page", 1, true, []string{"tag:0:id", "tag:2:id"}},
+		{"Last page", 2, false, []string{"tag:1:id"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keyboard := pageKeyboard("tag", "id", tt.page, tt.more)
+			if tt.expected == nil {
+				if keyboard != nil {
+					t.Errorf("Expected no keyboard, got %+v", keyboard)
+				}
+				return
+			}
+
+			var got []string
+			for _, button := range keyboard.InlineKeyboard[0] {
+				got = append(got, button.CallbackData)
+			}
+			if len(got) != len(tt.expected) {
+				t.Fatalf("Expected buttons %v, got %v", tt.expected, got)
+			}
+			for i := range got {
+				if got[i] != tt.expected[i] {
+					t.Errorf("Expected buttons %v, got %v", tt.expected, got)
+				}
+			}
+		})
+	}
+}
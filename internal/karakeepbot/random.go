@@ -0,0 +1,389 @@
+package karakeepbot
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Madh93/go-karakeep"
+	"github.com/Madh93/karakeepbot/internal/config"
+	"github.com/Madh93/karakeepbot/internal/cron"
+	"github.com/Madh93/karakeepbot/internal/scheduler"
+	"github.com/Madh93/karakeepbot/internal/state"
+	"github.com/go-telegram/bot/models"
+)
+
+const (
+	randomState     = "random"         // Name of the state document with the random bookmarks posted to each chat.
+	randomScanLimit = 10 * maxPageSize // Maximum number of bookmarks (10 pages) read from an end of a collection to pick one.
+)
+
+// randomPicks holds the bookmarks posted at random to each chat, so they
+// aren't posted again within the configured window, and when each scheduled
+// post was last made. They are persisted in the state store.
+type randomPicks struct {
+	mu    sync.Mutex
+	store *state.Store
+	data  randomPicksData
+}
+
+// randomPicksData is the state document of the random bookmarks.
+type randomPicksData struct {
+	Posted map[string]map[string]time.Time `json:"posted"` // When each bookmark was posted, by chat.
+	Runs   map[string]time.Time            `json:"runs"`   // Last run of each scheduled post.
+}
+
+// newRandomPicks loads the random bookmarks posted to each chat from the state
+// store.
+func newRandomPicks(store *state.Store) (*randomPicks, error) {
+	var data randomPicksData
+	if err := store.Load(randomState, &data); err != nil {
+		return nil, err
+	}
+	if data.Posted == nil {
+		data.Posted = make(map[string]map[string]time.Time)
+	}
+	if data.Runs == nil {
+		data.Runs = make(map[string]time.Time)
+	}
+	return &randomPicks{store: store, data: data}, nil
+}
+
+// postedSince returns the bookmarks posted to the chat since the given time.
+func (rp *randomPicks) postedSince(chatID int64, since time.Time) map[string]bool {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+
+	posted := make(map[string]bool)
+	for bookmarkID, at := range rp.data.Posted[strconv.FormatInt(chatID, 10)] {
+		if !at.Before(since) {
+			posted[bookmarkID] = true
+		}
+	}
+	return posted
+}
+
+// record sets when the bookmark was posted to the chat and persists it,
+// forgetting the bookmarks posted before the given time.
+func (rp *randomPicks) record(chatID int64, bookmarkID string, at, forgetBefore time.Time) error {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+
+	chat := strconv.FormatInt(chatID, 10)
+	if rp.data.Posted[chat] == nil {
+		rp.data.Posted[chat] = make(map[string]time.Time)
+	}
+	maps.DeleteFunc(rp.data.Posted[chat], func(_ string, posted time.Time) bool { return posted.Before(forgetBefore) })
+	rp.data.Posted[chat][bookmarkID] = at
+	return rp.store.Save(randomState, rp.data)
+}
+
+// lastRun returns when the scheduled post was last made, if ever.
+func (rp *randomPicks) lastRun(key string) (time.Time, bool) {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	last, ok := rp.data.Runs[key]
+	return last, ok
+}
+
+// recordRun sets when the scheduled post was last made and persists it.
+func (rp *randomPicks) recordRun(key string, at time.Time) error {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	rp.data.Runs[key] = at
+	return rp.store.Save(randomState, rp.data)
+}
+
+// bookmarkSource is a collection of bookmarks: all of them, or those in a
+// list or with a tag.
+type bookmarkSource struct {
+	name  string // Description of the collection, e.g. "#golang".
+	count int    // Number of bookmarks, or zero if unknown.
+	fetch func(order string) func(cursor *string, limit float32) (*karakeep.PaginatedBookmarks, error)
+}
+
+// bookmarkSourceFor returns all the bookmarks, or those in the list or with
+// the tag, or nil if the list or tag doesn't exist.
+func (kb *KarakeepBot) bookmarkSourceFor(ctx context.Context, list, tag string) (*bookmarkSource, error) {
+	switch {
+	case list != "":
+		l, err := kb.karakeep.ResolveList(ctx, list)
+		if err != nil || l == nil {
+			return nil, err
+		}
+		// Karakeep doesn't count the bookmarks of lists
+		fetch := func(order string) func(cursor *string, limit float32) (*karakeep.PaginatedBookmarks, error) {
+			return kb.karakeep.listBookmarksFetcher(ctx, l.Id, order)
+		}
+		return &bookmarkSource{name: "@" + l.Name, fetch: fetch}, nil
+	case tag != "":
+		t, err := kb.findTag(ctx, tag)
+		if err != nil || t == nil {
+			return nil, err
+		}
+		fetch := func(order string) func(cursor *string, limit float32) (*karakeep.PaginatedBookmarks, error) {
+			return kb.karakeep.tagBookmarksFetcher(ctx, t.Id, order)
+		}
+		return &bookmarkSource{name: "#" + t.Name, count: int(t.NumBookmarks), fetch: fetch}, nil
+	default:
+		count, err := kb.karakeep.CountBookmarks(ctx)
+		if err != nil {
+			return nil, err
+		}
+		fetch := func(order string) func(cursor *string, limit float32) (*karakeep.PaginatedBookmarks, error) {
+			return kb.karakeep.allBookmarksFetcher(ctx, order)
+		}
+		return &bookmarkSource{name: "all bookmarks", count: count, fetch: fetch}, nil
+	}
+}
+
+// bookmarkSourceNamed returns all the bookmarks if the name is empty, or those
+// in a list ("@name") or with a tag ("#name"). A name without prefix is looked
+// up as a list first, and then as a tag. It returns nil if nothing matches.
+func (kb *KarakeepBot) bookmarkSourceNamed(ctx context.Context, name string) (*bookmarkSource, error) {
+	switch {
+	case name == "":
+		return kb.bookmarkSourceFor(ctx, "", "")
+	case strings.HasPrefix(name, "#"):
+		return kb.bookmarkSourceFor(ctx, "", name)
+	case strings.HasPrefix(name, "@"):
+		return kb.bookmarkSourceFor(ctx, strings.TrimPrefix(name, "@"), "")
+	}
+	src, err := kb.bookmarkSourceFor(ctx, name, "")
+	if err == nil && src == nil {
+		return kb.bookmarkSourceFor(ctx, "", name)
+	}
+	return src, err
+}
+
+// pickRandom returns a random bookmark of the source, avoiding the excluded
+// ones unless there is nothing else, or nil if the source is empty.
+//
+// Karakeep pages are cursor based, so bookmarks can't be fetched by position.
+// To keep the number of requests bounded, a position is picked within the
+// newest and oldest randomScanLimit bookmarks, and the pages are read from the
+// nearest end up to it. When the source has up to twice randomScanLimit
+// bookmarks, every bookmark is equally likely. When the number of bookmarks is
+// unknown, one of the first randomScanLimit bookmarks read from a random end
+// is picked instead.
+func pickRandom(src *bookmarkSource, random func(n int) int, excluded map[string]bool) (*KarakeepBookmark, error) {
+	window := 2 * randomScanLimit
+	if src.count > 0 && src.count < window {
+		window = src.count
+	}
+	pos := random(window)
+	order, target := newestFirst, pos
+	if pos >= (window+1)/2 {
+		order, target = oldestFirst, window-1-pos
+	}
+	if src.count <= 0 {
+		target = -1 // Sample the bookmarks read instead.
+	}
+
+	var picked, fallback *KarakeepBookmark
+	read, candidates := 0, 0
+	err := walkBookmarks(src.fetch(order), func(bookmark KarakeepBookmark) bool {
+		read++
+		if fallback == nil || read-1 <= target {
+			fallback = &bookmark
+		}
+		if excluded[bookmark.Id] {
+			return read < randomScanLimit && (target < 0 || read <= target+maxPageSize)
+		}
+
+		if target < 0 {
+			// Reservoir sampling of the bookmarks read
+			candidates++
+			if random(candidates) == 0 {
+				picked = &bookmark
+			}
+			return read < randomScanLimit
+		}
+
+		// The first bookmark not excluded from the target on, or the last one
+		// before if there are none
+		picked = &bookmark
+		return read-1 < target
+	})
+	if err != nil {
+		return nil, err
+	}
+	if picked == nil {
+		picked = fallback
+	}
+	return picked, nil
+}
+
+// postRandom picks a random bookmark of the source that wasn't posted to the
+// chat within the configured window, and returns the message to post it. The
+// message is nil if the source is empty.
+func (kb *KarakeepBot) postRandom(src *bookmarkSource, chatID int64, threadID int) (*TelegramMessage, *KarakeepBookmark, error) {
+	window := kb.settings.Load().randomWindow
+	bookmark, err := pickRandom(src, kb.random, kb.picks.postedSince(chatID, kb.clock.Now().Add(-window)))
+	if err != nil || bookmark == nil {
+		return nil, nil, err
+	}
+
+	msg := &TelegramMessage{
+		Chat:            models.Chat{ID: chatID},
+		MessageThreadID: threadID,
+		Text:            randomText(*bookmark, bookmark.Hashtags(kb.settings.Load().hashtags)),
+	}
+	return msg, bookmark, nil
+}
+
+// recordRandom remembers that the bookmark was posted to the chat.
+func (kb *KarakeepBot) recordRandom(chatID int64, bookmark *KarakeepBookmark) {
+	now := kb.clock.Now()
+	if err := kb.picks.record(chatID, bookmark.Id, now, now.Add(-kb.settings.Load().randomWindow)); err != nil {
+		kb.logger.Error("Failed to persist random bookmark", "scope", "random", "chat_id", chatID, "bookmark_id", bookmark.Id, "error", err)
+	}
+}
+
+// randomText returns the text of a random bookmark: when it was saved, its
+// title and link (or text), its summary and its hashtags.
+func randomText(bookmark KarakeepBookmark, hashtags string) string {
+	text := "🎲 From the archive"
+	if created := bookmark.Created(); !created.IsZero() {
+		text += ", saved on " + created.Format("2 January 2006")
+	}
+	text += ":\n\n" + shareText(bookmark, "")
+	if description := bookmark.Description(); description != "" && bookmark.Text() == "" {
+		text += "\n\n" + description
+	}
+	if hashtags != "" {
+		text += "\n\n" + hashtags
+	}
+	return text
+}
+
+// handleRandomCommand replies with a random bookmark, among all of them or
+// those in a list ("@name") or with a tag ("#name"). A name without prefix is
+// looked up as a list first, and then as a tag.
+func (kb *KarakeepBot) handleRandomCommand(ctx context.Context, msg *TelegramMessage, args []string) {
+	name := strings.Join(args, " ")
+	src, err := kb.bookmarkSourceNamed(ctx, name)
+	if err != nil {
+		kb.logger.Error("Failed to get random bookmarks", msg.AttrsWithError(err)...)
+		kb.reply(ctx, msg, "⚠️ Failed to get the results from Karakeep, try again later")
+		return
+	}
+	if src == nil {
+		kb.reply(ctx, msg, fmt.Sprintf("🔍 No list or tag named %q.", strings.TrimLeft(name, "#@")))
+		return
+	}
+
+	post, bookmark, err := kb.postRandom(src, msg.Chat.ID, msg.MessageThreadID)
+	if err != nil {
+		kb.logger.Error("Failed to pick random bookmark", msg.AttrsWithError(err)...)
+		kb.reply(ctx, msg, "⚠️ Failed to get the results from Karakeep, try again later")
+		return
+	}
+	if post == nil {
+		kb.reply(ctx, msg, "🔍 No bookmarks found.")
+		return
+	}
+
+	sent, err := kb.telegram.SendReply(ctx, msg, post.Text)
+	if err != nil {
+		kb.logger.Error("Failed to send reply to user", msg.AttrsWithError(err)...)
+		return
+	}
+	kb.recordRandom(msg.Chat.ID, bookmark)
+	kb.confirmSent(sent, bookmark.Id)
+}
+
+// randomPostKey returns the key identifying a scheduled random post in the
+// state store and the scheduler.
+func randomPostKey(p config.RandomPostConfig) string {
+	thread := ""
+	if p.Thread != nil {
+		thread = strconv.Itoa(*p.Thread)
+	}
+	return strings.Join([]string{"random", strconv.FormatInt(p.Chat, 10), thread, p.Cron, p.List, p.Tag}, "/")
+}
+
+// randomPostAttrs returns a slice of logging attributes for the scheduled
+// random post.
+func randomPostAttrs(p config.RandomPostConfig) []any {
+	attrs := []any{"scope", "random", "chat_id", p.Chat, "cron", p.Cron}
+	if p.Thread != nil {
+		attrs = append(attrs, "thread_id", *p.Thread)
+	}
+	return attrs
+}
+
+// scheduleRandomPosts schedules the configured random posts in the configured
+// time zone. Like digests, a post missed while the bot was down is made right
+// away.
+func (kb *KarakeepBot) scheduleRandomPosts() {
+	loc := kb.config.Scheduler.Location()
+	for _, p := range kb.config.Random.Posts {
+		schedule, err := cron.Parse(p.Cron)
+		if err != nil {
+			kb.logger.Error("Skipping random post with invalid schedule", append(randomPostAttrs(p), "error", err)...)
+			continue
+		}
+
+		key := randomPostKey(p)
+		from, ok := kb.picks.lastRun(key)
+		if !ok {
+			from = kb.clock.Now()
+			if err := kb.picks.recordRun(key, from); err != nil {
+				kb.logger.Error("Failed to persist random post schedule", append(randomPostAttrs(p), "error", err)...)
+			}
+		}
+
+		if kb.scheduler.Add(key, scheduler.In(schedule, loc), from, func(ctx context.Context, _ time.Time) { kb.postScheduledRandom(ctx, p) }) {
+			next, _ := kb.scheduler.Next(key)
+			kb.logger.Info("Scheduled random post", append(randomPostAttrs(p), "next", next.In(loc))...)
+		}
+	}
+}
+
+// postScheduledRandom posts a random bookmark to the chat of the scheduled
+// post. Nothing is posted if there are no bookmarks.
+func (kb *KarakeepBot) postScheduledRandom(ctx context.Context, p config.RandomPostConfig) {
+	attrs := randomPostAttrs(p)
+	now := kb.clock.Now()
+
+	src, err := kb.bookmarkSourceFor(ctx, p.List, p.Tag)
+	if err == nil && src == nil {
+		err = fmt.Errorf("list %q or tag %q not found", p.List, p.Tag)
+	}
+	if err != nil {
+		kb.logger.Error("Failed to get random bookmarks", append(attrs, "error", err)...)
+		return
+	}
+
+	thread := 0
+	if p.Thread != nil {
+		thread = *p.Thread
+	}
+	post, bookmark, err := kb.postRandom(src, p.Chat, thread)
+	if err != nil {
+		kb.logger.Error("Failed to pick random bookmark", append(attrs, "error", err)...)
+		return
+	}
+
+	if post != nil {
+		sent, err := kb.telegram.SendNewMessage(ctx, post)
+		if err != nil {
+			kb.logger.Error("Failed to send random bookmark", append(attrs, "error", err)...)
+			return
+		}
+		kb.recordRandom(p.Chat, bookmark)
+		kb.confirmSent(sent, bookmark.Id)
+		kb.logger.Info("Posted random bookmark", append(attrs, "bookmark_id", bookmark.Id)...)
+	} else {
+		kb.logger.Info("Skipped random post: no bookmarks", attrs...)
+	}
+
+	if err := kb.picks.recordRun(randomPostKey(p), now); err != nil {
+		kb.logger.Error("Failed to persist random post run", append(attrs, "error", err)...)
+	}
+}
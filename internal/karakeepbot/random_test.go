@@ -0,0 +1,261 @@
+package karakeepbot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Madh93/go-karakeep"
+	"github.com/Madh93/karakeepbot/internal/config"
+	"github.com/Madh93/karakeepbot/internal/state"
+	"github.com/go-telegram/bot/models"
+)
+
+// newTestRandomBot returns a bot connected to the fake Karakeep server of the
+// digest tests whose random numbers are always n, and the Telegram calls.
+func newTestRandomBot(t *testing.T, n int) (*KarakeepBot, func() []string) {
+	t.Helper()
+
+	kb, calls := newTestRemindersBot(t)
+	var err error
+	if kb.picks, err = newRandomPicks(kb.state); err != nil {
+		t.Fatal(err)
+	}
+	kb.random = func(int) int { return n }
+	return kb, calls
+}
+
+// testRandomSource returns a source with the given number of bookmarks, b0
+// being the newest, split in pages of two bookmarks. The count is the one
+// reported, which can be wrong.
+func testRandomSource(bookmarks, count int) *bookmarkSource {
+	var all []karakeep.Bookmark
+	for i := range bookmarks {
+		all = append(all, karakeep.Bookmark{Id: "b" + strconv.Itoa(i)})
+	}
+
+	fetch := func(order string) func(cursor *string, limit float32) (*karakeep.PaginatedBookmarks, error) {
+		sorted := slices.Clone(all)
+		if order == oldestFirst {
+			slices.Reverse(sorted)
+		}
+		return func(cursor *string, _ float32) (*karakeep.PaginatedBookmarks, error) {
+			start := 0
+			if cursor != nil {
+				start, _ = strconv.Atoi(*cursor)
+			}
+			end := min(start+2, len(sorted))
+			page := &karakeep.PaginatedBookmarks{Bookmarks: sorted[start:end]}
+			if end < len(sorted) {
+				next := strconv.Itoa(end)
+				page.NextCursor = &next
+			}
+			return page, nil
+		}
+	}
+	return &bookmarkSource{count: count, fetch: fetch}
+}
+
+func TestRandomPicks_persistence(t *testing.T) {
+	store, err := state.New(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	picks, err := newRandomPicks(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	window := 30 * 24 * time.Hour
+	old := testNow.Add(-window - time.Hour)
+	for _, pick := range []struct {
+		bookmarkID string
+		at         time.Time
+	}{{"old", old}, {"recent", testNow.Add(-time.Hour)}, {"new", testNow}} {
+		if err := picks.record(1, pick.bookmarkID, pick.at, testNow.Add(-window)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := picks.recordRun("random/1", testNow); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded, err := newRandomPicks(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if posted, expected := reloaded.postedSince(1, old), map[string]bool{"recent": true, "new": true}; fmt.Sprint(posted) != fmt.Sprint(expected) {
+		t.Errorf("Expected posted bookmarks %v, got %v", expected, posted)
+	}
+	if posted := reloaded.postedSince(2, old); len(posted) != 0 {
+		t.Errorf("Expected no posted bookmarks in another chat, got %v", posted)
+	}
+	if last, ok := reloaded.lastRun("random/1"); !ok || !last.Equal(testNow) {
+		t.Errorf("Expected the last run to be %v, got %v (%v)", testNow, last, ok)
+	}
+}
+
+func TestPickRandom(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   *bookmarkSource
+		random   []int // Random numbers returned, the last one repeated.
+		excluded []string
+		expected string
+	}{
+		{"Newest", testRandomSource(5, 5), []int{0}, nil, "b0"},
+		{"From the newest end", testRandomSource(5, 5), []int{2}, nil, "b2"},
+		{"From the oldest end", testRandomSource(5, 5), []int{3}, nil, "b3"},
+		{"Oldest", testRandomSource(5, 5), []int{4}, nil, "b4"},
+		{"Excluded picks the next one", testRandomSource(5, 5), []int{1}, []string{"b1", "b2"}, "b3"},
+		{"Excluded until the end picks the previous one", testRandomSource(5, 5), []int{3}, []string{"b3", "b4"}, "b2"},
+		{"All excluded", testRandomSource(5, 5), []int{1}, []string{"b0", "b1", "b2", "b3", "b4"}, "b1"},
+		{"Fewer than counted", testRandomSource(3, 1000), []int{100}, nil, "b2"},
+		{"Many bookmarks from the newest end", testRandomSource(2*randomScanLimit+10, 2*randomScanLimit+10), []int{randomScanLimit - 1}, nil, "b" + strconv.Itoa(randomScanLimit-1)},
+		{"Many bookmarks from the oldest end", testRandomSource(2*randomScanLimit+10, 2*randomScanLimit+10), []int{randomScanLimit}, nil, "b" + strconv.Itoa(randomScanLimit+10)},
+		{"Unknown count", testRandomSource(5, 0), []int{0, 0, 1, 0, 1, 1}, nil, "b2"},
+		{"Unknown count from the oldest end", testRandomSource(5, 0), []int{randomScanLimit, 0}, []string{"b0"}, "b1"},
+		{"Unknown count reads up to the limit", testRandomSource(randomScanLimit+10, 0), []int{0}, nil, "b" + strconv.Itoa(randomScanLimit-1)},
+		{"Empty", testRandomSource(0, 0), []int{0}, nil, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			random := func(n int) int {
+				v := tt.random[min(calls, len(tt.random)-1)]
+				calls++
+				if v >= n {
+					t.Fatalf("random(%d) returning %d", n, v)
+				}
+				return v
+			}
+			excluded := make(map[string]bool)
+			for _, id := range tt.excluded {
+				excluded[id] = true
+			}
+
+			bookmark, err := pickRandom(tt.source, random, excluded)
+			if err != nil {
+				t.Fatalf("pickRandom() returned an unexpected error: %v", err)
+			}
+			got := ""
+			if bookmark != nil {
+				got = bookmark.Id
+			}
+			if got != tt.expected {
+				t.Errorf("Expected bookmark %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestRandomText(t *testing.T) {
+	summary := "A summary of the bookmark."
+	created := "2024-03-05T10:00:00Z"
+
+	tests := []struct {
+		name     string
+		bookmark string
+		hashtags string
+		expected string
+	}{
+		{
+			"Link with summary",
+			`{"id": "b1", "createdAt": "` + created + `", "summary": "` + summary + `", "content": {"type": "link", "url": "https://example.com", "title": "Example"}}`,
+			"#golang",
+			"🎲 From the archive, saved on 5 March 2024:\n\nExample\nhttps://example.com\n\n" + summary + "\n\n#golang",
+		},
+		{
+			"Text",
+			`{"id": "b1", "createdAt": "` + created + `", "content": {"type": "text", "text": "Some notes"}}`,
+			"",
+			"🎲 From the archive, saved on 5 March 2024:\n\nSome notes",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var bookmark KarakeepBookmark
+			if err := json.Unmarshal([]byte(tt.bookmark), &bookmark); err != nil {
+				t.Fatal(err)
+			}
+			if got := randomText(bookmark, tt.hashtags); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestKarakeepBot_handleRandomCommand(t *testing.T) {
+	saved := func(id string, days int, hashtags string) string {
+		date := testNow.AddDate(0, 0, -days).Add(-time.Hour).Format("2 January 2006")
+		text := "sendMessage: 🎲 From the archive, saved on " + date + ":\n\nBookmark " + id + "\nhttps://example.com/" + id
+		if hashtags != "" {
+			text += "\n\n" + hashtags
+		}
+		return text
+	}
+
+	tests := []struct {
+		name     string
+		random   int
+		args     []string
+		expected []string
+	}{
+		{"All bookmarks", 0, nil, []string{saved("b1", 1, "#golang")}},
+		{"Oldest bookmark", 4, nil, []string{saved("b5", 10, "#golang")}},
+		{"Tag", 3, []string{"#golang"}, []string{saved("b5", 10, "#golang")}},
+		{"List", 0, []string{"@reading"}, []string{saved("b3", 3, "#rust")}},
+		{"List or tag", 0, []string{"golang"}, []string{saved("b1", 1, "#golang")}},
+		{"Unknown", 0, []string{"unknown"}, []string{`sendMessage: 🔍 No list or tag named "unknown".`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kb, calls := newTestRandomBot(t, tt.random)
+			kb.handleRandomCommand(context.Background(), &TelegramMessage{ID: 10, Chat: models.Chat{ID: 1}}, tt.args)
+
+			if !slices.Equal(calls(), tt.expected) {
+				t.Errorf("Expected calls %q, got %q", tt.expected, calls())
+			}
+		})
+	}
+
+	t.Run("Avoids repeats", func(t *testing.T) {
+		kb, calls := newTestRandomBot(t, 0)
+		for range 2 {
+			kb.handleRandomCommand(context.Background(), &TelegramMessage{ID: 10, Chat: models.Chat{ID: 1}}, nil)
+		}
+
+		expected := []string{saved("b1", 1, "#golang"), saved("b2", 2, "#golang")}
+		if !slices.Equal(calls(), expected) {
+			t.Errorf("Expected calls %q, got %q", expected, calls())
+		}
+		if _, ok := kb.confirmations.bookmarkID(1, 1); !ok {
+			t.Error("Expected the random bookmark to be remindable")
+		}
+	})
+}
+
+func TestKarakeepBot_postScheduledRandom(t *testing.T) {
+	thread := 42
+	post := config.RandomPostConfig{Chat: 1, Thread: &thread, Cron: "@daily", Tag: "golang"}
+
+	kb, calls := newTestRandomBot(t, 0)
+	kb.postScheduledRandom(context.Background(), post)
+
+	if expected := []string{"sendMessage: 🎲 From the archive, saved on 17 October 2026:\n\nBookmark b1\nhttps://example.com/b1\n\n#golang"}; !slices.Equal(calls(), expected) {
+		t.Errorf("Expected calls %q, got %q", expected, calls())
+	}
+	if posted := kb.picks.postedSince(1, testNow); !posted["b1"] {
+		t.Errorf("Expected the bookmark to be recorded as posted, got %v", posted)
+	}
+	if last, ok := kb.picks.lastRun(randomPostKey(post)); !ok || !last.Equal(testNow) {
+		t.Errorf("Expected the run to be recorded at %v, got %v (%v)", testNow, last, ok)
+	}
+}
@@ -0,0 +1,154 @@
+package karakeepbot
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"slices"
+	"syscall"
+	"time"
+
+	"github.com/Madh93/karakeepbot/internal/config"
+)
+
+// reloadDebounce is the time to wait after a configuration file change before
+// reloading it, as editors usually trigger several events for a single save.
+const reloadDebounce = 500 * time.Millisecond
+
+// reloadableKeys are the configuration keys applied at runtime on reload.
+// Changes to any other key require a restart.
+var reloadableKeys = []string{
+	"telegram.allowlist",
+	"telegram.threads",
+	"telegram.replymode",
+	"karakeep.interval",
+	"tagging.interval",
+	"tagging.maxinterval",
+	"tagging.timeout",
+	"tagging.types",
+	"tagging.deferred",
+	"tagging.watch",
+	"hashtags.case",
+	"hashtags.transliterate",
+	"hashtags.aliases",
+	"hashtags.blocklist",
+	"hashtags.max",
+	"hashtags.aitags",
+	"hashtags.maxai",
+	"lists.prefix",
+	"inline.enabled",
+	"inline.results",
+	"inline.cache",
+	"random.window",
+	"logging.level",
+	"fileprocessor.maxsize",
+	"fileprocessor.timeout",
+	"fileprocessor.mimetypes",
+	"roles.admins",
+	"roles.contributors",
+	"roles.readers",
+	"roles.unknown",
+	"roles.reply",
+	"chats",
+}
+
+// watchConfig reloads the configuration when the process receives SIGHUP or
+// the configuration file changes, until ctx is done.
+func (kb *KarakeepBot) watchConfig(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	changed := make(chan struct{}, 1)
+	stop, err := kb.config.Watch(func(err error) {
+		if err != nil {
+			kb.logger.Warn("Stopped watching configuration file. Send SIGHUP to reload it", "error", err)
+			return
+		}
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	})
+	if err != nil {
+		kb.logger.Warn("Couldn't watch configuration file. Send SIGHUP to reload it", "error", err)
+	} else {
+		defer stop()
+	}
+
+	var debounce <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			kb.logger.Info("Received SIGHUP, reloading configuration")
+			kb.reload()
+		case <-changed:
+			debounce = time.After(reloadDebounce)
+		case <-debounce:
+			debounce = nil
+			kb.logger.Info("Configuration file changed, reloading configuration", "path", kb.config.Path)
+			kb.reload()
+		}
+	}
+}
+
+// reload loads the configuration again and applies it. Invalid configurations
+// are refused, keeping the current one.
+func (kb *KarakeepBot) reload() {
+	newConfig, err := kb.config.Reload()
+	if err == nil {
+		err = kb.applyConfig(newConfig)
+	}
+	if err != nil {
+		kb.logger.Error("Refusing to reload invalid configuration, keeping the current one", "error", err)
+	}
+}
+
+// applyConfig validates the configuration and swaps the reloadable settings,
+// logging every change. Messages being processed keep running with the
+// previous settings where they were already read. The configuration in use
+// isn't replaced, so changes to the other keys wait for a restart.
+func (kb *KarakeepBot) applyConfig(newConfig *config.Config) error {
+	if err := newConfig.Validate(); err != nil {
+		return err
+	}
+	if err := checkMimetypes(kb.fileValidators, newConfig.FileProcessor.Mimetypes); err != nil {
+		return err
+	}
+
+	// Reloadable keys are compared with the last configuration loaded, and the
+	// others with the one in use, so pending restarts keep being reported
+	var reloaded, pending []config.Change
+	for _, change := range config.Diff(kb.loaded, newConfig) {
+		if slices.Contains(reloadableKeys, change.Key) {
+			reloaded = append(reloaded, change)
+		}
+	}
+	for _, change := range config.Diff(kb.config, newConfig) {
+		if !slices.Contains(reloadableKeys, change.Key) {
+			pending = append(pending, change)
+		}
+	}
+	kb.loaded = newConfig
+
+	if len(reloaded) == 0 && len(pending) == 0 {
+		kb.logger.Info("Configuration reloaded, nothing changed")
+		return nil
+	}
+
+	kb.settings.Store(newSettings(newConfig))
+	kb.logger.SetLevel(newConfig.Logging.Level)
+	kb.fileProcessor.UpdateLimits(&newConfig.FileProcessor)
+
+	for _, change := range reloaded {
+		kb.logger.Info("Configuration changed", "key", change.Key, "old", change.Old, "new", change.New)
+	}
+	for _, change := range pending {
+		kb.logger.Warn("Configuration changed, but it requires a restart to take effect", "key", change.Key, "old", change.Old, "new", change.New)
+	}
+	kb.logger.Info("Configuration reloaded")
+
+	return nil
+}
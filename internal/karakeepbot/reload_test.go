@@ -0,0 +1,112 @@
+package karakeepbot
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/Madh93/karakeepbot/internal/config"
+	"github.com/Madh93/karakeepbot/internal/fileprocessor"
+	"github.com/Madh93/karakeepbot/internal/filevalidator"
+	"github.com/Madh93/karakeepbot/internal/logging"
+)
+
+// newTestConfig returns a valid configuration for tests.
+func newTestConfig() *config.Config {
+	cfg := config.DefaultConfig
+	cfg.Telegram.Token = "123456789:ABCdefGhIJKlmnOPqrsTUVwxyz012345678"
+	cfg.Telegram.Allowlist = []int64{1}
+	cfg.Karakeep.Token = "ak1_0123456789abcdef0123_456789abcdef01234567"
+	cfg.Logging.Output = "stderr"
+	cfg.Logging.Level = "error"
+	return &cfg
+}
+
+func TestKarakeepBot_applyConfig(t *testing.T) {
+	cfg := newTestConfig()
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("test configuration is not valid: %v", err)
+	}
+
+	processor, err := fileprocessor.New(&cfg.FileProcessor, false, "")
+	if err != nil {
+		t.Fatalf("fileprocessor.New() returned an unexpected error: %v", err)
+	}
+	logs := filepath.Join(t.TempDir(), "karakeepbot.log")
+	kb := &KarakeepBot{
+		logger:         logging.New(&config.LoggingConfig{Level: "info", Format: "json", Output: "file", Path: logs}),
+		fileProcessor:  processor,
+		fileValidators: map[string]fileprocessor.Validator{"image/jpeg": filevalidator.ImageValidator},
+		config:         cfg,
+		loaded:         cfg,
+	}
+	kb.settings.Store(newSettings(cfg))
+
+	t.Run("Valid reload", func(t *testing.T) {
+		newConfig := newTestConfig()
+		newConfig.Telegram.Allowlist = []int64{1, 2}
+		newConfig.FileProcessor.Mimetypes = []string{"image/jpeg"}
+
+		if err := kb.applyConfig(newConfig); err != nil {
+			t.Fatalf("applyConfig() returned an unexpected error: %v", err)
+		}
+		if !kb.isChatIdAllowed(2) {
+			t.Error("expected chat 2 to be allowed after reload")
+		}
+		if kb.config != cfg {
+			t.Error("expected the startup configuration to be kept in use")
+		}
+	})
+
+	t.Run("Restart required", func(t *testing.T) {
+		// Reloading twice keeps warning about the pending restart, but only
+		// logs the changes applied once.
+		for range 2 {
+			newConfig := newTestConfig()
+			newConfig.Telegram.Allowlist = []int64{1, 2}
+			newConfig.FileProcessor.Mimetypes = []string{"image/jpeg"}
+			newConfig.Scheduler.Timezone = "Europe/Madrid"
+			newConfig.Logging.Level = "info"
+
+			if err := kb.applyConfig(newConfig); err != nil {
+				t.Fatalf("applyConfig() returned an unexpected error: %v", err)
+			}
+		}
+
+		if kb.config.Scheduler.Timezone != cfg.Scheduler.Timezone {
+			t.Errorf("expected the time zone to stay %q until a restart, got %q", cfg.Scheduler.Timezone, kb.config.Scheduler.Timezone)
+		}
+		data, err := os.ReadFile(logs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if warnings := strings.Count(string(data), `"msg":"Configuration changed, but it requires a restart to take effect","key":"scheduler.timezone"`); warnings != 2 {
+			t.Errorf("expected 2 restart warnings, got %d in:\n%s", warnings, data)
+		}
+		if changes := strings.Count(string(data), `"key":"logging.level"`); changes != 1 {
+			t.Errorf("expected the log level change to be logged once, got %d in:\n%s", changes, data)
+		}
+	})
+
+	t.Run("Invalid reload", func(t *testing.T) {
+		invalidConfigs := map[string]func(*config.Config){
+			"invalid allowlist":     func(c *config.Config) { c.Telegram.Allowlist = []int64{-1} },
+			"unsupported MIME type": func(c *config.Config) { c.FileProcessor.Mimetypes = []string{"text/plain"} },
+		}
+
+		for name, modify := range invalidConfigs {
+			newConfig := newTestConfig()
+			newConfig.Telegram.Allowlist = []int64{3}
+			modify(newConfig)
+
+			if err := kb.applyConfig(newConfig); err == nil {
+				t.Errorf("expected an error for %s, got nil", name)
+			}
+			if !slices.Equal(kb.settings.Load().allowlist, []int64{1, 2}) {
+				t.Errorf("expected settings to be kept after refusing %s, got allowlist %v", name, kb.settings.Load().allowlist)
+			}
+		}
+	})
+}
@@ -0,0 +1,366 @@
+package karakeepbot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Madh93/karakeepbot/internal/scheduler"
+	"github.com/Madh93/karakeepbot/internal/state"
+	"github.com/Madh93/karakeepbot/internal/when"
+	"github.com/go-telegram/bot/models"
+)
+
+const (
+	remindersState             = "reminders"     // Name of the state document with the pending reminders.
+	confirmationsState         = "confirmations" // Name of the state document with the bookmarks of the messages sent by the bot.
+	cancelReminderCallbackData = "unremind:"     // Prefix of the callback data of the buttons cancelling a reminder.
+	confirmationsTTL           = 30 * 24 * time.Hour
+	reminderRetry              = 5 * time.Minute // Time to wait before posting a reminder again after a failure.
+	reminderExpiry             = 24 * time.Hour  // Reminders that can't be posted for this long are dropped.
+	reminderTimeLayout         = "Mon 2 Jan 2006 15:04 MST"
+	remindUsage                = "/remind <when>, e.g. /remind 3d or /remind tomorrow 9am"
+)
+
+// confirmation is a message sent by the bot in response to a saved bookmark.
+type confirmation struct {
+	BookmarkID string    `json:"bookmark_id"`
+	Sent       time.Time `json:"sent"`
+}
+
+// confirmations holds the bookmarks of the messages sent by the bot during the
+// last month, so users can reply to them with /remind. They are persisted in
+// the state store.
+type confirmations struct {
+	mu    sync.Mutex
+	store *state.Store
+	sent  map[string]confirmation
+}
+
+// newConfirmations loads the bookmarks of the messages sent by the bot from
+// the state store.
+func newConfirmations(store *state.Store) (*confirmations, error) {
+	sent := make(map[string]confirmation)
+	if err := store.Load(confirmationsState, &sent); err != nil {
+		return nil, err
+	}
+	return &confirmations{store: store, sent: sent}, nil
+}
+
+// confirmationKey returns the key of a message in a chat.
+func confirmationKey(chatID int64, messageID int) string {
+	return fmt.Sprintf("%d/%d", chatID, messageID)
+}
+
+// add records the bookmark of a message sent by the bot and persists it,
+// forgetting the messages older than a month.
+func (c *confirmations) add(msg *TelegramMessage, bookmarkID string, now time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	maps.DeleteFunc(c.sent, func(_ string, sent confirmation) bool { return now.Sub(sent.Sent) > confirmationsTTL })
+	c.sent[confirmationKey(msg.Chat.ID, msg.ID)] = confirmation{BookmarkID: bookmarkID, Sent: now}
+	return c.store.Save(confirmationsState, c.sent)
+}
+
+// bookmarkID returns the bookmark of a message sent by the bot, if known.
+func (c *confirmations) bookmarkID(chatID int64, messageID int) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	sent, ok := c.sent[confirmationKey(chatID, messageID)]
+	return sent.BookmarkID, ok
+}
+
+// reminder is a bookmark to post again in a chat at a given time.
+type reminder struct {
+	ID         int       `json:"id"`
+	BookmarkID string    `json:"bookmark_id"`
+	Title      string    `json:"title"` // Headline of the bookmark when the reminder was created.
+	ChatID     int64     `json:"chat_id"`
+	ThreadID   int       `json:"thread_id"`
+	UserID     int64     `json:"user_id"` // User who created the reminder.
+	At         time.Time `json:"at"`
+	Created    time.Time `json:"created"`
+}
+
+// job returns the name of the scheduled job of the reminder.
+func (r reminder) job() string {
+	return "reminder/" + strconv.Itoa(r.ID)
+}
+
+// Attrs returns a slice of logging attributes for the reminder.
+func (r reminder) Attrs() []any {
+	return []any{
+		"scope", "reminder",
+		"reminder_id", r.ID,
+		"bookmark_id", r.BookmarkID,
+		"chat_id", r.ChatID,
+		"thread_id", r.ThreadID,
+		"at", r.At,
+	}
+}
+
+// reminders holds the pending reminders, which are persisted in the state
+// store so they survive restarts.
+type reminders struct {
+	mu    sync.Mutex
+	store *state.Store
+	data  remindersData
+}
+
+// remindersData is the state document of the reminders.
+type remindersData struct {
+	NextID    int              `json:"next_id"` // IDs aren't reused, so buttons never cancel another reminder.
+	Reminders map[int]reminder `json:"reminders"`
+}
+
+// newReminders loads the pending reminders from the state store.
+func newReminders(store *state.Store) (*reminders, error) {
+	data := remindersData{NextID: 1, Reminders: make(map[int]reminder)}
+	if err := store.Load(remindersState, &data); err != nil {
+		return nil, err
+	}
+	if data.Reminders == nil {
+		data.Reminders = make(map[int]reminder)
+	}
+	return &reminders{store: store, data: data}, nil
+}
+
+// all returns the pending reminders, sorted by time.
+func (rs *reminders) all() []reminder {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	return slices.SortedFunc(maps.Values(rs.data.Reminders), func(a, b reminder) int { return a.At.Compare(b.At) })
+}
+
+// get returns a pending reminder.
+func (rs *reminders) get(id int) (reminder, bool) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	r, ok := rs.data.Reminders[id]
+	return r, ok
+}
+
+// add assigns an ID to the reminder and persists it.
+func (rs *reminders) add(r reminder) (reminder, error) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	r.ID = rs.data.NextID
+	rs.data.Reminders[r.ID] = r
+	rs.data.NextID++
+	if err := rs.store.Save(remindersState, rs.data); err != nil {
+		delete(rs.data.Reminders, r.ID)
+		rs.data.NextID--
+		return reminder{}, err
+	}
+	return r, nil
+}
+
+// remove deletes a reminder and persists it.
+func (rs *reminders) remove(id int) error {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	if _, ok := rs.data.Reminders[id]; !ok {
+		return nil
+	}
+	delete(rs.data.Reminders, id)
+	return rs.store.Save(remindersState, rs.data)
+}
+
+// confirmSent records the bookmark of a message sent by the bot, so users can
+// reply to it with /remind.
+func (kb *KarakeepBot) confirmSent(sent *TelegramMessage, bookmarkID string) {
+	if err := kb.confirmations.add(sent, bookmarkID, kb.clock.Now()); err != nil {
+		kb.logger.Error("Failed to persist sent message", append(sent.Attrs(), "bookmark_id", bookmarkID, "error", err)...)
+	}
+}
+
+// handleRemindCommand schedules a reminder of the bookmark of the bot message
+// the command replies to.
+func (kb *KarakeepBot) handleRemindCommand(ctx context.Context, msg *TelegramMessage, args []string) {
+	var bookmarkID string
+	var ok bool
+	if reply := msg.ReplyToMessage; reply != nil {
+		bookmarkID, ok = kb.confirmations.bookmarkID(msg.Chat.ID, reply.ID)
+	}
+	if !ok || len(args) == 0 {
+		kb.reply(ctx, msg, "⚠️ Reply to one of my messages about a bookmark with "+remindUsage)
+		return
+	}
+
+	loc := kb.config.Scheduler.Location()
+	now := kb.clock.Now().In(loc)
+	at, err := when.Parse(strings.Join(args, " "), now)
+	if errors.Is(err, when.ErrPast) {
+		kb.reply(ctx, msg, "⚠️ That time has already passed.")
+		return
+	} else if err != nil {
+		kb.reply(ctx, msg, fmt.Sprintf("⚠️ %v. Usage: %s", err, remindUsage))
+		return
+	}
+
+	bookmark, err := kb.karakeep.RetrieveBookmarkById(ctx, bookmarkID)
+	if err != nil {
+		kb.logger.Error("Failed to retrieve bookmark", append(msg.AttrsWithError(err), "bookmark_id", bookmarkID)...)
+		kb.reply(ctx, msg, "⚠️ Failed to get the bookmark from Karakeep, try again later")
+		return
+	}
+
+	r, err := kb.reminders.add(reminder{
+		BookmarkID: bookmarkID,
+		Title:      bookmark.Headline(),
+		ChatID:     msg.Chat.ID,
+		ThreadID:   msg.MessageThreadID,
+		UserID:     msg.From.ID,
+		At:         at,
+		Created:    now,
+	})
+	if err != nil {
+		kb.logger.Error("Failed to save reminder", append(msg.AttrsWithError(err), "bookmark_id", bookmarkID)...)
+		kb.reply(ctx, msg, "⚠️ Failed to save the reminder, try again later")
+		return
+	}
+	kb.scheduleReminder(r)
+
+	kb.logger.Info("Scheduled reminder", r.Attrs()...)
+	kb.reply(ctx, msg, fmt.Sprintf("⏰ I'll remind you about %q on %s.", r.Title, at.Format(reminderTimeLayout)))
+}
+
+// handleRemindersCommand lists the pending reminders of the chat, with
+// buttons to cancel them.
+func (kb *KarakeepBot) handleRemindersCommand(ctx context.Context, msg *TelegramMessage, _ []string) {
+	var pending []reminder
+	for _, r := range kb.reminders.all() {
+		if r.ChatID == msg.Chat.ID {
+			pending = append(pending, r)
+		}
+	}
+	if len(pending) == 0 {
+		kb.reply(ctx, msg, "⏰ No reminders in this chat. Reply to one of my messages about a bookmark with "+remindUsage)
+		return
+	}
+
+	text, keyboard := renderReminders(pending, kb.config.Scheduler.Location())
+	if _, err := kb.telegram.SendReplyWithKeyboard(ctx, msg, text, keyboard); err != nil {
+		kb.logger.Error("Failed to send reply to user", msg.AttrsWithError(err)...)
+	}
+}
+
+// renderReminders returns the list of reminders and the buttons to cancel
+// them.
+func renderReminders(pending []reminder, loc *time.Location) (string, *models.InlineKeyboardMarkup) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "⏰ Reminders (%d):", len(pending))
+	var rows [][]models.InlineKeyboardButton
+	for i, r := range pending {
+		fmt.Fprintf(&b, "\n\n%d. %s\n%s", i+1, r.At.In(loc).Format(reminderTimeLayout), r.Title)
+		if i%archiveButtonsPerRow == 0 {
+			rows = append(rows, nil)
+		}
+		button := models.InlineKeyboardButton{Text: fmt.Sprintf("❌ %d", i+1), CallbackData: cancelReminderCallbackData + strconv.Itoa(r.ID)}
+		rows[len(rows)-1] = append(rows[len(rows)-1], button)
+	}
+	b.WriteString("\n\nPress ❌ to cancel a reminder.")
+	return b.String(), &models.InlineKeyboardMarkup{InlineKeyboard: rows}
+}
+
+// handleCancelReminderCallback cancels the reminder of a button pressed by the
+// user who created it or by an admin, and removes the button.
+func (kb *KarakeepBot) handleCancelReminderCallback(ctx context.Context, query *models.CallbackQuery, msg *TelegramMessage, answer func(string)) {
+	id, _ := strconv.Atoi(strings.TrimPrefix(query.Data, cancelReminderCallbackData))
+	r, ok := kb.reminders.get(id)
+	if !ok || r.ChatID != msg.Chat.ID {
+		if err := kb.telegram.EditKeyboard(ctx, msg, withoutButton(msg.ReplyMarkup, query.Data)); err != nil {
+			kb.logger.Error("Failed to remove cancel button", append(msg.AttrsWithError(err), "reminder_id", id)...)
+		}
+		answer("ℹ️ This reminder was already sent or cancelled.")
+		return
+	}
+
+	if role := kb.roleOf(query.From.ID); r.UserID != query.From.ID && role < RoleAdmin {
+		kb.logger.Warn("Denied cancelling reminder: not the creator", append(r.Attrs(), "user_id", query.From.ID, "role", role)...)
+		answer("⛔ Only who created the reminder or an admin can cancel it.")
+		return
+	}
+
+	if err := kb.reminders.remove(id); err != nil {
+		kb.logger.Error("Failed to cancel reminder", append(r.Attrs(), "error", err)...)
+		answer("⚠️ Failed to cancel the reminder, try again later")
+		return
+	}
+	kb.scheduler.Remove(r.job())
+	kb.logger.Info("Cancelled reminder", append(r.Attrs(), "user_id", query.From.ID)...)
+
+	if err := kb.telegram.EditKeyboard(ctx, msg, withoutButton(msg.ReplyMarkup, query.Data)); err != nil {
+		kb.logger.Error("Failed to remove cancel button", append(r.Attrs(), "error", err)...)
+	}
+	answer("❌ Reminder cancelled")
+}
+
+// resumeReminders schedules the reminders persisted in a previous run. The
+// reminders missed while the bot was down are posted right away.
+func (kb *KarakeepBot) resumeReminders() {
+	pending := kb.reminders.all()
+	if len(pending) > 0 {
+		kb.logger.Info(fmt.Sprintf("Resuming %d reminders", len(pending)))
+	}
+	for _, r := range pending {
+		kb.scheduleReminder(r)
+	}
+}
+
+// scheduleReminder schedules a reminder at its time.
+func (kb *KarakeepBot) scheduleReminder(r reminder) {
+	kb.scheduler.Add(r.job(), scheduler.At(r.At), r.Created, func(ctx context.Context, _ time.Time) { kb.sendReminder(ctx, r.ID) })
+}
+
+// sendReminder posts the bookmark of the reminder in its chat. If it fails,
+// it's retried later, unless the reminder is too old.
+func (kb *KarakeepBot) sendReminder(ctx context.Context, id int) {
+	r, ok := kb.reminders.get(id)
+	if !ok {
+		return
+	}
+
+	err := kb.postReminder(ctx, r)
+	now := kb.clock.Now()
+	if err != nil && now.Sub(r.At) < reminderExpiry {
+		kb.logger.Error("Failed to send reminder, retrying later", append(r.Attrs(), "error", err)...)
+		kb.scheduler.Add(r.job(), scheduler.At(now.Add(reminderRetry)), now, func(ctx context.Context, _ time.Time) { kb.sendReminder(ctx, r.ID) })
+		return
+	} else if err != nil {
+		kb.logger.Error("Failed to send reminder, dropping it", append(r.Attrs(), "error", err)...)
+	} else {
+		kb.logger.Info("Sent reminder", r.Attrs()...)
+	}
+
+	if err := kb.reminders.remove(id); err != nil {
+		kb.logger.Error("Failed to remove sent reminder", append(r.Attrs(), "error", err)...)
+	}
+}
+
+// postReminder sends a message with the bookmark of the reminder.
+func (kb *KarakeepBot) postReminder(ctx context.Context, r reminder) error {
+	bookmark, err := kb.karakeep.RetrieveBookmarkById(ctx, r.BookmarkID)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve bookmark: %w", err)
+	}
+
+	msg := &TelegramMessage{
+		Chat:            models.Chat{ID: r.ChatID},
+		MessageThreadID: r.ThreadID,
+		Text:            "⏰ Reminder:\n\n" + shareText(*bookmark, bookmark.Hashtags(kb.settings.Load().hashtags)),
+	}
+	if _, err := kb.telegram.SendNewMessage(ctx, msg); err != nil {
+		return fmt.Errorf("failed to send message: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,305 @@
+package karakeepbot
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Madh93/karakeepbot/internal/state"
+	"github.com/go-telegram/bot/models"
+)
+
+// newTestRemindersBot returns a bot connected to the fake Karakeep server of
+// the digest tests, with a confirmation of bookmark "b1" in message 5 of chat
+// 1, and the Telegram calls.
+func newTestRemindersBot(t *testing.T) (*KarakeepBot, func() []string) {
+	t.Helper()
+
+	kb, _ := newTestDigestBot(t)
+	telegram, calls := newTestTelegram(t)
+	kb.telegram = telegram
+	kb.config.Scheduler.Timezone = "UTC"
+
+	var err error
+	if kb.reminders, err = newReminders(kb.state); err != nil {
+		t.Fatal(err)
+	}
+	if kb.confirmations, err = newConfirmations(kb.state); err != nil {
+		t.Fatal(err)
+	}
+	if err := kb.confirmations.add(&TelegramMessage{ID: 5, Chat: models.Chat{ID: 1}}, "b1", testNow); err != nil {
+		t.Fatal(err)
+	}
+	return kb, calls
+}
+
+// addTestReminder stores a reminder of bookmark "b1" in chat 1 by user 2.
+func addTestReminder(t *testing.T, kb *KarakeepBot, at time.Time) reminder {
+	t.Helper()
+	r, err := kb.reminders.add(reminder{BookmarkID: "b1", Title: "Bookmark b1", ChatID: 1, UserID: 2, At: at, Created: testNow.AddDate(0, 0, -1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestReminders_persistence(t *testing.T) {
+	store, err := state.New(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	reminders, err := newReminders(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, at := range []time.Time{testNow.Add(2 * time.Hour), testNow.Add(time.Hour)} {
+		if _, err := reminders.add(reminder{BookmarkID: "b1", At: at}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := reminders.remove(1); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded, err := newReminders(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r, err := reloaded.add(reminder{BookmarkID: "b2", At: testNow}); err != nil || r.ID != 3 {
+		t.Errorf("Expected the new reminder to get ID 3, got %d (%v)", r.ID, err)
+	}
+	var ids []int
+	for _, r := range reloaded.all() {
+		ids = append(ids, r.ID)
+	}
+	if expected := []int{3, 2}; !slices.Equal(ids, expected) {
+		t.Errorf("Expected reminders %v, got %v", expected, ids)
+	}
+}
+
+func TestConfirmations_add(t *testing.T) {
+	store, err := state.New(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	confirmations, err := newConfirmations(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	old := testNow.Add(-confirmationsTTL - time.Hour)
+	if err := confirmations.add(&TelegramMessage{ID: 1, Chat: models.Chat{ID: 1}}, "old", old); err != nil {
+		t.Fatal(err)
+	}
+	if err := confirmations.add(&TelegramMessage{ID: 2, Chat: models.Chat{ID: 1}}, "new", testNow); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded, err := newConfirmations(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := reloaded.bookmarkID(1, 1); ok {
+		t.Error("Expected the old message to be forgotten")
+	}
+	if id, ok := reloaded.bookmarkID(1, 2); !ok || id != "new" {
+		t.Errorf("Expected bookmark %q, got %q (%v)", "new", id, ok)
+	}
+}
+
+func TestKarakeepBot_handleRemindCommand(t *testing.T) {
+	tests := []struct {
+		name          string
+		replyTo       int
+		args          []string
+		expectedAt    time.Time // Zero if no reminder is scheduled.
+		expectedReply string
+	}{
+		{"Duration", 5, []string{"3d"}, testNow.AddDate(0, 0, 3), `⏰ I'll remind you about "Bookmark b1" on Wed 21 Oct 2026 09:00 UTC.`},
+		{"Day and time", 5, []string{"tomorrow", "6pm"}, testNow.Add(33 * time.Hour), `⏰ I'll remind you about "Bookmark b1" on Mon 19 Oct 2026 18:00 UTC.`},
+		{"Past time", 5, []string{"2026-10-01"}, time.Time{}, "⚠️ That time has already passed."},
+		{"Invalid time", 5, []string{"someday"}, time.Time{}, `⚠️ unknown time "someday". Usage: ` + remindUsage},
+		{"Not a reply", 0, []string{"3d"}, time.Time{}, "⚠️ Reply to one of my messages about a bookmark with " + remindUsage},
+		{"Reply to another message", 6, []string{"3d"}, time.Time{}, "⚠️ Reply to one of my messages about a bookmark with " + remindUsage},
+		{"Without time", 5, nil, time.Time{}, "⚠️ Reply to one of my messages about a bookmark with " + remindUsage},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kb, calls := newTestRemindersBot(t)
+
+			msg := &TelegramMessage{ID: 10, Chat: models.Chat{ID: 1}, MessageThreadID: 7, From: &models.User{ID: 2}}
+			if tt.replyTo != 0 {
+				msg.ReplyToMessage = &models.Message{ID: tt.replyTo, Chat: models.Chat{ID: 1}}
+			}
+			kb.handleRemindCommand(context.Background(), msg, tt.args)
+
+			if expected := []string{"sendMessage: " + tt.expectedReply}; !slices.Equal(calls(), expected) {
+				t.Errorf("Expected calls %q, got %q", expected, calls())
+			}
+
+			pending := kb.reminders.all()
+			if tt.expectedAt.IsZero() {
+				if len(pending) != 0 {
+					t.Errorf("Expected no reminders, got %+v", pending)
+				}
+				return
+			}
+			if len(pending) != 1 {
+				t.Fatalf("Expected a reminder, got %+v", pending)
+			}
+			r := pending[0]
+			if r.BookmarkID != "b1" || r.ChatID != 1 || r.ThreadID != 7 || r.UserID != 2 || !r.At.Equal(tt.expectedAt) {
+				t.Errorf("Unexpected reminder %+v", r)
+			}
+			if next, ok := kb.scheduler.Next(r.job()); !ok || !next.Equal(tt.expectedAt) {
+				t.Errorf("Expected the reminder to be scheduled at %v, got %v (%v)", tt.expectedAt, next, ok)
+			}
+		})
+	}
+}
+
+func TestKarakeepBot_handleRemindersCommand(t *testing.T) {
+	kb, calls := newTestRemindersBot(t)
+	msg := &TelegramMessage{ID: 10, Chat: models.Chat{ID: 1}, From: &models.User{ID: 2}}
+
+	kb.handleRemindersCommand(context.Background(), msg, nil)
+	addTestReminder(t, kb, testNow.Add(48*time.Hour))
+	addTestReminder(t, kb, testNow.Add(time.Hour))
+	if _, err := kb.reminders.add(reminder{BookmarkID: "b3", Title: "Elsewhere", ChatID: 2, At: testNow}); err != nil {
+		t.Fatal(err)
+	}
+	kb.handleRemindersCommand(context.Background(), msg, nil)
+
+	expected := []string{
+		"sendMessage: ⏰ No reminders in this chat. Reply to one of my messages about a bookmark with " + remindUsage,
+		"sendMessage: ⏰ Reminders (2):\n\n1. Sun 18 Oct 2026 10:00 UTC\nBookmark b1\n\n2. Tue 20 Oct 2026 09:00 UTC\nBookmark b1\n\nPress ❌ to cancel a reminder.",
+	}
+	if !slices.Equal(calls(), expected) {
+		t.Errorf("Expected calls %q, got %q", expected, calls())
+	}
+}
+
+func TestRenderReminders(t *testing.T) {
+	pending := make([]reminder, 7)
+	for i := range pending {
+		pending[i] = reminder{ID: i + 10, Title: "Bookmark", At: testNow}
+	}
+
+	_, keyboard := renderReminders(pending, time.UTC)
+
+	var rows []int
+	for _, row := range keyboard.InlineKeyboard {
+		rows = append(rows, len(row))
+	}
+	if expected := []int{5, 2}; !slices.Equal(rows, expected) {
+		t.Errorf("Expected rows of %v buttons, got %v", expected, rows)
+	}
+	if button := keyboard.InlineKeyboard[1][1]; button.Text != "❌ 7" || button.CallbackData != "unremind:16" {
+		t.Errorf("Unexpected button %+v", button)
+	}
+}
+
+func TestKarakeepBot_sendReminder(t *testing.T) {
+	tests := []struct {
+		name          string
+		bookmarkID    string
+		at            time.Time
+		expectedCalls []string
+		expectedRetry bool
+	}{
+		{"Sent", "b1", testNow, []string{"sendMessage: ⏰ Reminder:\n\nBookmark b1\nhttps://example.com/b1\n\n#golang"}, false},
+		{"Failed", "unknown", testNow, nil, true},
+		{"Failed for too long", "unknown", testNow.Add(-reminderExpiry), nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kb, calls := newTestRemindersBot(t)
+			r, err := kb.reminders.add(reminder{BookmarkID: tt.bookmarkID, ChatID: 1, At: tt.at})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			kb.sendReminder(context.Background(), r.ID)
+
+			if !slices.Equal(calls(), tt.expectedCalls) {
+				t.Errorf("Expected calls %q, got %q", tt.expectedCalls, calls())
+			}
+			if _, ok := kb.reminders.get(r.ID); ok != tt.expectedRetry {
+				t.Errorf("Expected the reminder to be kept: %v, got %v", tt.expectedRetry, ok)
+			}
+			next, ok := kb.scheduler.Next(r.job())
+			if tt.expectedRetry && (!ok || !next.Equal(testNow.Add(reminderRetry))) {
+				t.Errorf("Expected a retry at %v, got %v (%v)", testNow.Add(reminderRetry), next, ok)
+			} else if !tt.expectedRetry && ok {
+				t.Errorf("Expected no retry, got %v", next)
+			}
+		})
+	}
+}
+
+func TestKarakeepBot_resumeReminders(t *testing.T) {
+	kb, _ := newTestRemindersBot(t)
+	missed := addTestReminder(t, kb, testNow.Add(-time.Hour))
+	upcoming := addTestReminder(t, kb, testNow.Add(time.Hour))
+
+	kb.resumeReminders()
+
+	for _, r := range []reminder{missed, upcoming} {
+		if next, ok := kb.scheduler.Next(r.job()); !ok || !next.Equal(r.At) {
+			t.Errorf("Expected reminder %d to be scheduled at %v, got %v (%v)", r.ID, r.At, next, ok)
+		}
+	}
+}
+
+func TestKarakeepBot_handleCancelReminderCallback(t *testing.T) {
+	tests := []struct {
+		name          string
+		userID        int64
+		chatID        int64
+		data          string
+		expectedKept  bool
+		expectedCalls []string
+	}{
+		{"Creator", 2, 1, "unremind:1", false, []string{"editMessageReplyMarkup: ", "answerCallbackQuery: ❌ Reminder cancelled"}},
+		{"Admin", 1, 1, "unremind:1", false, []string{"editMessageReplyMarkup: ", "answerCallbackQuery: ❌ Reminder cancelled"}},
+		{"Another contributor", 3, 1, "unremind:1", true, []string{"answerCallbackQuery: ⛔ Only who created the reminder or an admin can cancel it."}},
+		{"Another chat", 2, 2, "unremind:1", true, []string{"editMessageReplyMarkup: ", "answerCallbackQuery: ℹ️ This reminder was already sent or cancelled."}},
+		{"Unknown reminder", 2, 1, "unremind:9", true, []string{"editMessageReplyMarkup: ", "answerCallbackQuery: ℹ️ This reminder was already sent or cancelled."}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kb, calls := newTestRemindersBot(t)
+			cfg := newTestConfig()
+			cfg.Telegram.Allowlist = nil
+			cfg.Roles.Admins = []int64{1}
+			cfg.Roles.Contributors = []int64{2, 3}
+			kb.settings.Store(newSettings(cfg))
+			r := addTestReminder(t, kb, testNow.Add(time.Hour))
+			kb.scheduleReminder(r)
+
+			keyboard := &models.InlineKeyboardMarkup{InlineKeyboard: [][]models.InlineKeyboardButton{{{Text: "❌ 1", CallbackData: tt.data}}}}
+			query := &models.CallbackQuery{
+				ID:      "query",
+				From:    models.User{ID: tt.userID},
+				Data:    tt.data,
+				Message: models.MaybeInaccessibleMessage{Message: &models.Message{ID: 10, Chat: models.Chat{ID: tt.chatID}, ReplyMarkup: keyboard}},
+			}
+			kb.handleCallback(context.Background(), query)
+
+			if !slices.Equal(calls(), tt.expectedCalls) {
+				t.Errorf("Expected calls %q, got %q", tt.expectedCalls, calls())
+			}
+			if _, ok := kb.reminders.get(r.ID); ok != tt.expectedKept {
+				t.Errorf("Expected the reminder to be kept: %v, got %v", tt.expectedKept, ok)
+			}
+			if _, ok := kb.scheduler.Next(r.job()); ok != tt.expectedKept {
+				t.Errorf("Expected the reminder to be scheduled: %v, got %v", tt.expectedKept, ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package karakeepbot
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"sync"
+
+	"github.com/Madh93/karakeepbot/internal/config"
+	"github.com/Madh93/karakeepbot/internal/state"
+)
+
+// rolesState is the name of the state document with the roles granted with
+// the /allow command.
+const rolesState = "roles"
+
+// Role defines what a Telegram user can do with the bot. Roles are ordered, so
+// each role can do everything the previous ones can.
+type Role int
+
+// Available roles.
+const (
+	RoleNone        Role = iota // Unknown users.
+	RoleReader                  // Can only search bookmarks.
+	RoleContributor             // Can save bookmarks.
+	RoleAdmin                   // Can run management commands.
+)
+
+// roleNames maps each role to its name.
+var roleNames = map[Role]string{
+	RoleNone:        "none",
+	RoleReader:      "reader",
+	RoleContributor: "contributor",
+	RoleAdmin:       "admin",
+}
+
+// String returns the name of the role.
+func (r Role) String() string {
+	return roleNames[r]
+}
+
+// MarshalText implements encoding.TextMarshaler, so roles are stored by name.
+func (r Role) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (r *Role) UnmarshalText(text []byte) error {
+	role, err := parseRole(string(text))
+	if err != nil {
+		return err
+	}
+	*r = role
+	return nil
+}
+
+// parseRole returns the role with the given name. The "none" role can't be
+// parsed, as it can't be granted.
+func parseRole(name string) (Role, error) {
+	for role, roleName := range roleNames {
+		if role != RoleNone && roleName == name {
+			return role, nil
+		}
+	}
+	return RoleNone, fmt.Errorf("unknown role %q", name)
+}
+
+// roleGrants holds the roles granted to users with the /allow command, which
+// are persisted in the state store.
+type roleGrants struct {
+	mu    sync.RWMutex
+	store *state.Store
+	users map[int64]Role
+}
+
+// newRoleGrants loads the granted roles from the state store.
+func newRoleGrants(store *state.Store) (*roleGrants, error) {
+	users := make(map[int64]Role)
+	if err := store.Load(rolesState, &users); err != nil {
+		return nil, err
+	}
+	return &roleGrants{store: store, users: users}, nil
+}
+
+// get returns the role granted to the user, if any.
+func (g *roleGrants) get(userID int64) (Role, bool) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	role, ok := g.users[userID]
+	return role, ok
+}
+
+// len returns the number of users with a granted role.
+func (g *roleGrants) len() int {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return len(g.users)
+}
+
+// grant assigns the role to the user and persists it.
+func (g *roleGrants) grant(userID int64, role Role) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	previous, existed := g.users[userID]
+	g.users[userID] = role
+	if err := g.store.Save(rolesState, g.users); err != nil {
+		// Keep memory and disk in sync.
+		if existed {
+			g.users[userID] = previous
+		} else {
+			delete(g.users, userID)
+		}
+		return err
+	}
+	return nil
+}
+
+// revoke removes the role granted to the user and persists it. It returns
+// false if the user had no granted role.
+func (g *roleGrants) revoke(userID int64) (bool, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	previous, existed := g.users[userID]
+	if !existed {
+		return false, nil
+	}
+	delete(g.users, userID)
+	if err := g.store.Save(rolesState, g.users); err != nil {
+		g.users[userID] = previous
+		return false, err
+	}
+	return true, nil
+}
+
+// configuredRole returns the role assigned to the user in the configuration.
+func configuredRole(roles *config.RolesConfig, userID int64) Role {
+	switch {
+	case slices.Contains(roles.Admins, userID):
+		return RoleAdmin
+	case slices.Contains(roles.Contributors, userID):
+		return RoleContributor
+	case slices.Contains(roles.Readers, userID):
+		return RoleReader
+	default:
+		return RoleNone
+	}
+}
+
+// roleOf returns the role of the user, the highest between the configured
+// and the granted one. When no roles are configured nor granted, every user is
+// a contributor, so all users in allowed chats can save bookmarks.
+func (kb *KarakeepBot) roleOf(userID int64) Role {
+	roles := &kb.settings.Load().roles
+	if len(roles.Admins)+len(roles.Contributors)+len(roles.Readers)+kb.grants.len() == 0 {
+		return RoleContributor
+	}
+
+	role := configuredRole(roles, userID)
+	if granted, ok := kb.grants.get(userID); ok && granted > role {
+		role = granted
+	}
+	return role
+}
+
+// parseUserID parses a Telegram user ID.
+func parseUserID(s string) (int64, error) {
+	userID, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || userID == 0 {
+		return 0, fmt.Errorf("invalid user ID %q", s)
+	}
+	return userID, nil
+}
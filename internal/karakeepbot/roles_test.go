@@ -0,0 +1,146 @@
+package karakeepbot
+
+import (
+	"testing"
+
+	"github.com/Madh93/karakeepbot/internal/state"
+	"github.com/go-telegram/bot/models"
+)
+
+// newTestRolesBot returns a bot with the given configured admins and an empty
+// in-memory state store.
+func newTestRolesBot(t *testing.T, admins ...int64) *KarakeepBot {
+	t.Helper()
+
+	store, err := state.New("")
+	if err != nil {
+		t.Fatalf("state.New() returned an unexpected error: %v", err)
+	}
+	grants, err := newRoleGrants(store)
+	if err != nil {
+		t.Fatalf("newRoleGrants() returned an unexpected error: %v", err)
+	}
+
+	cfg := newTestConfig()
+	cfg.Roles.Admins = admins
+	kb := &KarakeepBot{telegram: &Telegram{username: "KarakeepBot"}, state: store, grants: grants}
+	kb.settings.Store(newSettings(cfg))
+	return kb
+}
+
+func TestKarakeepBot_roleOf(t *testing.T) {
+	t.Run("Without roles everyone is a contributor", func(t *testing.T) {
+		kb := newTestRolesBot(t)
+		if role := kb.roleOf(42); role != RoleContributor {
+			t.Errorf("expected %s, got %s", RoleContributor, role)
+		}
+	})
+
+	t.Run("With roles unknown users have no role", func(t *testing.T) {
+		kb := newTestRolesBot(t, 1)
+		if role := kb.roleOf(1); role != RoleAdmin {
+			t.Errorf("expected %s, got %s", RoleAdmin, role)
+		}
+		if role := kb.roleOf(42); role != RoleNone {
+			t.Errorf("expected %s, got %s", RoleNone, role)
+		}
+	})
+
+	t.Run("Granted roles", func(t *testing.T) {
+		kb := newTestRolesBot(t, 1)
+		if err := kb.grants.grant(42, RoleReader); err != nil {
+			t.Fatalf("grant() returned an unexpected error: %v", err)
+		}
+		if role := kb.roleOf(42); role != RoleReader {
+			t.Errorf("expected %s, got %s", RoleReader, role)
+		}
+
+		// Granting a lower role doesn't downgrade configured roles.
+		if err := kb.grants.grant(1, RoleReader); err != nil {
+			t.Fatalf("grant() returned an unexpected error: %v", err)
+		}
+		if role := kb.roleOf(1); role != RoleAdmin {
+			t.Errorf("expected %s, got %s", RoleAdmin, role)
+		}
+
+		if revoked, err := kb.grants.revoke(42); err != nil || !revoked {
+			t.Fatalf("revoke() = %v, %v", revoked, err)
+		}
+		if role := kb.roleOf(42); role != RoleNone {
+			t.Errorf("expected %s after revoking, got %s", RoleNone, role)
+		}
+	})
+
+	t.Run("Granted roles are persisted", func(t *testing.T) {
+		kb := newTestRolesBot(t, 1)
+		if err := kb.grants.grant(42, RoleAdmin); err != nil {
+			t.Fatalf("grant() returned an unexpected error: %v", err)
+		}
+
+		grants, err := newRoleGrants(kb.state)
+		if err != nil {
+			t.Fatalf("newRoleGrants() returned an unexpected error: %v", err)
+		}
+		if role, ok := grants.get(42); !ok || role != RoleAdmin {
+			t.Errorf("expected persisted role %s, got %s", RoleAdmin, role)
+		}
+	})
+}
+
+func TestKarakeepBot_parseCommand(t *testing.T) {
+	kb := newTestRolesBot(t)
+
+	command := func(text string, length int) *TelegramMessage {
+		return &TelegramMessage{
+			Text:     text,
+			Entities: []models.MessageEntity{{Type: models.MessageEntityTypeBotCommand, Offset: 0, Length: length}},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		msg      *TelegramMessage
+		command  string
+		args     []string
+		expected bool
+	}{
+		{"Plain text", &TelegramMessage{Text: "hello /allow"}, "", nil, false},
+		{"Command", command("/allow 42 reader", 6), "allow", []string{"42", "reader"}, true},
+		{"Command addressed to the bot", command("/Revoke@karakeepbot 42", 19), "revoke", []string{"42"}, true},
+		{"Command addressed to another bot", command("/start@otherbot", 15), "", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name, args, ok := kb.parseCommand(tt.msg)
+			if ok != tt.expected || name != tt.command || len(args) != len(tt.args) {
+				t.Fatalf("expected (%q, %v, %v), got (%q, %v, %v)", tt.command, tt.args, tt.expected, name, args, ok)
+			}
+			for i := range args {
+				if args[i] != tt.args[i] {
+					t.Errorf("expected args %v, got %v", tt.args, args)
+				}
+			}
+		})
+	}
+}
+
+func TestParseRole(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected bool
+	}{
+		{"admin", true},
+		{"contributor", true},
+		{"reader", true},
+		{"none", false},
+		{"owner", false},
+	}
+
+	for _, test := range tests {
+		_, err := parseRole(test.name)
+		if (err == nil) != test.expected {
+			t.Errorf("For role %q, expected valid: %v, but got error: %v", test.name, test.expected, err)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package karakeepbot
+
+import (
+	"slices"
+	"time"
+
+	"github.com/Madh93/karakeepbot/internal/config"
+	"github.com/Madh93/karakeepbot/internal/hashtag"
+)
+
+// settings holds the part of the configuration that can be changed at runtime
+// without restarting the bot.
+type settings struct {
+	allowlist    []int64
+	threads      []int
+	roles        config.RolesConfig  // Roles assigned in the configuration.
+	hashtags     *hashtag.Normalizer // Rules to convert tags into hashtags and back.
+	listPrefix   string              // Prefix of the list name at the start of a message. Empty disables it.
+	inline       config.InlineConfig // Inline mode configuration.
+	randomWindow time.Duration       // Time before a random bookmark can be posted again to a chat.
+	defaults     chatSettings        // Settings used when no override applies.
+	chats        []config.ChatConfig // Per-chat and per-thread overrides.
+}
+
+// chatSettings holds the settings resolved for a chat and thread.
+type chatSettings struct {
+	waitInterval time.Duration // Initial interval before retrying tagging status.
+	maxInterval  time.Duration // Maximum interval between retries.
+	tagTimeout   time.Duration // Maximum time to wait for AI tags. Zero disables waiting.
+	tagTypes     []string      // Bookmark types to wait for AI tags.
+	deferred     bool          // Whether to reply at once and edit the message when AI tags arrive.
+	watchWindow  time.Duration // Maximum time to watch for AI tags after replying. Zero disables watching.
+	replyMode    string        // What to do with the original message.
+	tags         []string      // Extra tags added to every bookmark.
+	types        []string      // Allowed bookmark types. If empty, all types are allowed.
+}
+
+// newSettings returns the reloadable settings from the configuration.
+func newSettings(config *config.Config) *settings {
+	return &settings{
+		allowlist:    config.Telegram.Allowlist,
+		threads:      config.Telegram.Threads,
+		roles:        config.Roles,
+		hashtags:     hashtag.New(&config.Hashtags),
+		listPrefix:   config.Lists.Prefix,
+		inline:       config.Inline,
+		randomWindow: time.Duration(config.Random.Window) * 24 * time.Hour,
+		defaults: chatSettings{
+			waitInterval: time.Duration(config.Tagging.Interval) * time.Second,
+			maxInterval:  time.Duration(config.Tagging.MaxInterval) * time.Second,
+			tagTimeout:   time.Duration(config.Tagging.Timeout) * time.Second,
+			tagTypes:     config.Tagging.Types,
+			deferred:     config.Tagging.Deferred,
+			watchWindow:  time.Duration(config.Tagging.Watch) * time.Second,
+			replyMode:    config.Telegram.ReplyMode,
+		},
+		chats: config.Chats,
+	}
+}
+
+// settingsFor resolves the settings for a message in the given chat and
+// thread. The precedence, from lowest to highest, is: global configuration,
+// the chat-wide [[chats]] block (without thread) and the [[chats]] block
+// matching both the chat and the thread.
+func (s *settings) settingsFor(chatID int64, threadID int) chatSettings {
+	resolved := s.defaults
+
+	for _, matchThread := range []bool{false, true} {
+		for _, chat := range s.chats {
+			if chat.ID != chatID || (chat.Thread != nil) != matchThread {
+				continue
+			}
+			if matchThread && *chat.Thread != threadID {
+				continue
+			}
+			resolved.apply(chat)
+		}
+	}
+
+	return resolved
+}
+
+// apply overrides the settings with the fields set in the chat block.
+func (cs *chatSettings) apply(chat config.ChatConfig) {
+	if chat.Interval != nil {
+		cs.waitInterval = time.Duration(*chat.Interval) * time.Second
+	}
+	if chat.Timeout != nil {
+		cs.tagTimeout = time.Duration(*chat.Timeout) * time.Second
+	}
+	if chat.ReplyMode != nil {
+		cs.replyMode = *chat.ReplyMode
+	}
+	if chat.Tags != nil {
+		cs.tags = chat.Tags
+	}
+	if chat.Types != nil {
+		cs.types = chat.Types
+	}
+}
+
+// waitsForTags reports whether to wait for the AI tags of bookmarks of the
+// given type.
+func (cs chatSettings) waitsForTags(bookmarkType string) bool {
+	return cs.tagTimeout > 0 && slices.Contains(cs.tagTypes, bookmarkType)
+}
+
+// watchesTags reports whether to keep watching for the AI tags of bookmarks of
+// the given type after replying, to edit the message when they arrive.
+func (cs chatSettings) watchesTags(bookmarkType string) bool {
+	return cs.watchWindow > 0 && slices.Contains(cs.tagTypes, bookmarkType)
+}
+
+// nextInterval returns the interval to wait after the given one, doubling it
+// up to the maximum interval. A chat interval above the maximum is kept as is.
+func (cs chatSettings) nextInterval(interval time.Duration) time.Duration {
+	return max(min(2*interval, cs.maxInterval), cs.waitInterval)
+}
+
+// allowsType reports whether bookmarks of the given type can be created.
+func (cs chatSettings) allowsType(bookmarkType string) bool {
+	return len(cs.types) == 0 || slices.Contains(cs.types, bookmarkType)
+}
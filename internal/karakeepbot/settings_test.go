@@ -0,0 +1,140 @@
+package karakeepbot
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Madh93/karakeepbot/internal/config"
+)
+
+func TestSettings_settingsFor(t *testing.T) {
+	ptr := func(v int) *int { return &v }
+	reply, none := config.ReplyModeReply, config.ReplyModeNone
+
+	cfg := newTestConfig()
+	cfg.Chats = config.ChatsConfig{
+		// Thread block declared before the chat-wide block on purpose.
+		{ID: 100, Thread: ptr(7), Timeout: ptr(0), Tags: []string{"quick-notes"}},
+		{ID: 100, ReplyMode: &reply, Tags: []string{"group"}, Types: []string{"link"}},
+		{ID: 200, ReplyMode: &none, Interval: ptr(3)},
+	}
+	s := newSettings(cfg)
+
+	// with returns the global settings with the given changes.
+	with := func(change func(cs *chatSettings)) chatSettings {
+		cs := chatSettings{
+			waitInterval: 2 * time.Second,
+			maxInterval:  10 * time.Second,
+			tagTimeout:   30 * time.Second,
+			tagTypes:     []string{"link", "text", "asset"},
+			watchWindow:  10 * time.Minute,
+			replyMode:    "replace",
+		}
+		change(&cs)
+		return cs
+	}
+
+	tests := []struct {
+		name     string
+		chatID   int64
+		threadID int
+		expected chatSettings
+	}{
+		{
+			name:     "Global settings",
+			chatID:   1,
+			expected: with(func(cs *chatSettings) {}),
+		},
+		{
+			name:     "Chat-wide overrides",
+			chatID:   100,
+			threadID: 3,
+			expected: with(func(cs *chatSettings) {
+				cs.replyMode, cs.tags, cs.types = "reply", []string{"group"}, []string{"link"}
+			}),
+		},
+		{
+			name:     "Thread overrides take precedence over chat-wide ones",
+			chatID:   100,
+			threadID: 7,
+			expected: with(func(cs *chatSettings) {
+				cs.tagTimeout, cs.replyMode, cs.tags, cs.types = 0, "reply", []string{"quick-notes"}, []string{"link"}
+			}),
+		},
+		{
+			name:     "Another chat",
+			chatID:   200,
+			expected: with(func(cs *chatSettings) { cs.waitInterval, cs.replyMode = 3*time.Second, "none" }),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := s.settingsFor(tt.chatID, tt.threadID)
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %+v, got %+v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestChatSettings_allowsType(t *testing.T) {
+	tests := []struct {
+		types    []string
+		typ      string
+		expected bool
+	}{
+		{nil, config.AssetBookmarkType, true},
+		{[]string{config.LinkBookmarkType}, config.LinkBookmarkType, true},
+		{[]string{config.LinkBookmarkType}, config.TextBookmarkType, false},
+	}
+
+	for _, test := range tests {
+		got := chatSettings{types: test.types}.allowsType(test.typ)
+		if got != test.expected {
+			t.Errorf("For types %v and type %q, expected %v, but got %v", test.types, test.typ, test.expected, got)
+		}
+	}
+}
+
+func TestChatSettings_waitsForTags(t *testing.T) {
+	tests := []struct {
+		timeout  time.Duration
+		tagTypes []string
+		typ      string
+		expected bool
+	}{
+		{30 * time.Second, []string{config.LinkBookmarkType}, config.LinkBookmarkType, true},
+		{30 * time.Second, []string{config.LinkBookmarkType}, config.TextBookmarkType, false},
+		{0, []string{config.LinkBookmarkType}, config.LinkBookmarkType, false},
+	}
+
+	for _, test := range tests {
+		got := chatSettings{tagTimeout: test.timeout, tagTypes: test.tagTypes}.waitsForTags(test.typ)
+		if got != test.expected {
+			t.Errorf("For timeout %s, types %v and type %q, expected %v, but got %v", test.timeout, test.tagTypes, test.typ, test.expected, got)
+		}
+	}
+}
+
+func TestChatSettings_nextInterval(t *testing.T) {
+	tests := []struct {
+		waitInterval time.Duration
+		maxInterval  time.Duration
+		interval     time.Duration
+		expected     time.Duration
+	}{
+		{2 * time.Second, 10 * time.Second, 2 * time.Second, 4 * time.Second},
+		{2 * time.Second, 10 * time.Second, 8 * time.Second, 10 * time.Second},
+		{2 * time.Second, 10 * time.Second, 10 * time.Second, 10 * time.Second},
+		{15 * time.Second, 10 * time.Second, 15 * time.Second, 15 * time.Second}, // Chat interval above the maximum.
+	}
+
+	for _, test := range tests {
+		got := chatSettings{waitInterval: test.waitInterval, maxInterval: test.maxInterval}.nextInterval(test.interval)
+		if got != test.expected {
+			t.Errorf("For interval %s (min %s, max %s), expected %s, but got %s", test.interval, test.waitInterval, test.maxInterval, test.expected, got)
+		}
+	}
+}
@@ -0,0 +1,390 @@
+package karakeepbot
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Madh93/go-karakeep"
+	"github.com/Madh93/karakeepbot/internal/cron"
+	"github.com/Madh93/karakeepbot/internal/scheduler"
+	"github.com/Madh93/karakeepbot/internal/when"
+	"github.com/go-telegram/bot/models"
+)
+
+const (
+	eventsLog         = "events" // Name of the state log with the bookmarks saved and tagged.
+	savedEvent        = "saved"  // A bookmark was saved from a message.
+	taggedEvent       = "tagged" // The AI tags of a saved bookmark arrived.
+	defaultStatsSince = "30d"    // Period of the stats when --since isn't given.
+	statsTop          = 5        // Number of domains, tags and chats shown.
+	statsBarWidth     = 10       // Characters of the longest bar of the saves per period.
+	statsUsage        = "/stats [--since <when>], e.g. /stats --since 7d or /stats --since 2026-01-01"
+	statsCompaction   = "@daily"                 // When the events older than statsRetention are removed.
+	statsRetention    = 2 * 365 * 24 * time.Hour // How long the events are kept.
+)
+
+// statsEvent is a record of the events log, used to compute the stats.
+type statsEvent struct {
+	Kind       string    `json:"kind"`
+	Time       time.Time `json:"time"`
+	BookmarkID string    `json:"bookmark_id"`
+	ChatID     int64     `json:"chat_id,omitempty"`
+	Chat       string    `json:"chat,omitempty"` // Title of the chat when the event happened.
+	ThreadID   int       `json:"thread_id,omitempty"`
+	UserID     int64     `json:"user_id,omitempty"`
+	Type       string    `json:"type,omitempty"`
+	Domain     string    `json:"domain,omitempty"`
+	Tags       []string  `json:"tags,omitempty"`
+}
+
+// recordEvent appends the event to the events log.
+func (kb *KarakeepBot) recordEvent(e statsEvent) {
+	if err := kb.state.Append(eventsLog, e); err != nil {
+		kb.logger.Error("Failed to record event", "scope", "stats", "kind", e.Kind, "bookmark_id", e.BookmarkID, "error", err)
+	}
+}
+
+// scheduleEventsCompaction removes the events older than statsRetention from
+// the events log on start, and then every day.
+func (kb *KarakeepBot) scheduleEventsCompaction() {
+	schedule, err := cron.Parse(statsCompaction)
+	if err != nil {
+		kb.logger.Error("Failed to schedule the compaction of the events log", "scope", "stats", "error", err)
+		return
+	}
+	kb.scheduler.Add("stats/compaction", scheduler.In(schedule, kb.config.Scheduler.Location()), time.Time{}, func(context.Context, time.Time) { kb.compactEvents() })
+}
+
+// compactEvents removes the events older than statsRetention from the events
+// log.
+func (kb *KarakeepBot) compactEvents() {
+	before := kb.clock.Now().Add(-statsRetention)
+	removed, err := kb.state.Compact(eventsLog, func(record []byte) bool {
+		var e statsEvent
+		return json.Unmarshal(record, &e) == nil && !e.Time.Before(before)
+	})
+	if err != nil {
+		kb.logger.Error("Failed to compact the events log", "scope", "stats", "error", err)
+		return
+	}
+	if removed > 0 {
+		kb.logger.Info(fmt.Sprintf("Removed %d events older than %s from the events log", removed, before.Format(time.DateOnly)), "scope", "stats")
+	}
+}
+
+// recordSaved records that the message was saved as the bookmark.
+func (kb *KarakeepBot) recordSaved(msg TelegramMessage, bookmark *KarakeepBookmark, bookmarkType string) {
+	e := statsEvent{
+		Kind:       savedEvent,
+		Time:       kb.clock.Now(),
+		BookmarkID: bookmark.Id,
+		ChatID:     msg.Chat.ID,
+		Chat:       chatTitle(msg.Chat),
+		Type:       bookmarkType,
+		Domain:     domainOf(bookmark.URL()),
+	}
+	if msg.IsTopicMessage {
+		e.ThreadID = msg.MessageThreadID
+	}
+	if msg.From != nil {
+		e.UserID = msg.From.ID
+	}
+	kb.recordEvent(e)
+}
+
+// recordTagged records the tags of the bookmark, if its tagging is complete.
+func (kb *KarakeepBot) recordTagged(bookmark *KarakeepBookmark) {
+	if bookmark.taggingStatus() != karakeep.BookmarkTaggingStatusSuccess {
+		return
+	}
+	e := statsEvent{Kind: taggedEvent, Time: kb.clock.Now(), BookmarkID: bookmark.Id}
+	for _, tag := range bookmark.Tags {
+		e.Tags = append(e.Tags, tag.Name)
+	}
+	kb.recordEvent(e)
+}
+
+// chatTitle returns the name of a chat: its title, or the name of the user for
+// private chats.
+func chatTitle(chat models.Chat) string {
+	switch {
+	case chat.Title != "":
+		return chat.Title
+	case chat.Username != "":
+		return "@" + chat.Username
+	default:
+		return strings.TrimSpace(chat.FirstName + " " + chat.LastName)
+	}
+}
+
+// domainOf returns the host of the URL without "www.", or an empty string if
+// it isn't a URL.
+func domainOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+}
+
+// statsCount is the number of bookmarks of a period, type, chat, domain or tag.
+type statsCount struct {
+	name  string
+	count int
+}
+
+// stats are the saving habits computed from the events log.
+type stats struct {
+	since    time.Time
+	saved    int
+	period   string       // Length of the periods: "day", "week", "month" or "year".
+	periods  []statsCount // Saves per period, oldest first.
+	types    []statsCount // Saves by type, and by chat and domain below, most first.
+	chats    []statsCount
+	domains  []statsCount
+	tags     []statsCount
+	tagged   int           // Saved bookmarks whose tags arrived.
+	latency  time.Duration // Average time for the tags to arrive.
+	allChats bool          // Whether the stats cover every chat, or a single one.
+}
+
+// computeStats reads the events log and returns the stats of the bookmarks
+// saved since the given time (or since the first event, if zero) until now, in
+// the given chat or in every chat if chatID is zero. Periods are computed in
+// the given location. The tags added by the bot (#telegram and the extra tags
+// of the chat) are left out of the top tags.
+func (kb *KarakeepBot) computeStats(since, now time.Time, chatID int64, loc *time.Location, s *settings) (*stats, error) {
+	type savedBookmark struct {
+		at      time.Time
+		tagged  bool
+		botTags []string
+	}
+	saved := make(map[string]*savedBookmark)
+	var savedTimes []time.Time
+	types, chats, domains, tags := make(map[string]int), make(map[string]int), make(map[string]int), make(map[string]int)
+	var tagged int
+	var latency time.Duration
+
+	err := kb.state.Scan(eventsLog, func(record []byte) error {
+		var e statsEvent
+		if err := json.Unmarshal(record, &e); err != nil {
+			return nil // Skip records partially written on a crash.
+		}
+
+		switch e.Kind {
+		case savedEvent:
+			if e.Time.Before(since) || e.Time.After(now) || (chatID != 0 && e.ChatID != chatID) {
+				return nil
+			}
+			botTags := append([]string{"telegram"}, s.settingsFor(e.ChatID, e.ThreadID).tags...)
+			saved[e.BookmarkID] = &savedBookmark{at: e.Time, botTags: botTags}
+			savedTimes = append(savedTimes, e.Time)
+			types[cmp.Or(e.Type, "unknown")]++
+			chats[statsChatName(e, chatID == 0)]++
+			if e.Domain != "" {
+				domains[e.Domain]++
+			}
+		case taggedEvent:
+			// Only the first tags of a bookmark are counted
+			b, ok := saved[e.BookmarkID]
+			if !ok || b.tagged {
+				return nil
+			}
+			b.tagged = true
+			tagged++
+			latency += e.Time.Sub(b.at)
+			for _, tag := range e.Tags {
+				if slices.ContainsFunc(b.botTags, func(t string) bool { return strings.EqualFold(t, tag) }) {
+					continue
+				}
+				if name := s.hashtags.Hashtag(tag); name != "" {
+					tags["#"+name]++
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if since.IsZero() && len(savedTimes) > 0 {
+		since = slices.MinFunc(savedTimes, time.Time.Compare)
+	}
+	st := &stats{
+		since:    since,
+		saved:    len(savedTimes),
+		types:    topCounts(types, 0),
+		chats:    topCounts(chats, statsTop),
+		domains:  topCounts(domains, statsTop),
+		tags:     topCounts(tags, statsTop),
+		tagged:   tagged,
+		allChats: chatID == 0,
+	}
+	if tagged > 0 {
+		st.latency = latency / time.Duration(tagged)
+	}
+	st.period, st.periods = savesPerPeriod(savedTimes, since.In(loc), now.In(loc))
+	return st, nil
+}
+
+// statsChatName returns the name of the chat (or of the topic, if not
+// grouping by chat) of the event.
+func statsChatName(e statsEvent, byChat bool) string {
+	name := cmp.Or(e.Chat, fmt.Sprint(e.ChatID))
+	switch {
+	case byChat && e.ThreadID != 0:
+		return fmt.Sprintf("%s › topic %d", name, e.ThreadID)
+	case byChat:
+		return name
+	case e.ThreadID != 0:
+		return fmt.Sprintf("Topic %d", e.ThreadID)
+	default:
+		return "General"
+	}
+}
+
+// topCounts returns up to n counts (or all if n is zero), most first and then
+// by name.
+func topCounts(counts map[string]int, n int) []statsCount {
+	sorted := make([]statsCount, 0, len(counts))
+	for _, name := range slices.Sorted(maps.Keys(counts)) {
+		sorted = append(sorted, statsCount{name: name, count: counts[name]})
+	}
+	slices.SortStableFunc(sorted, func(a, b statsCount) int { return b.count - a.count })
+	if n > 0 && len(sorted) > n {
+		sorted = sorted[:n]
+	}
+	return sorted
+}
+
+// savesPerPeriod returns the length of the periods and the saves in every
+// period from since until now, oldest first. Periods are days for up to two
+// weeks, weeks (starting on Monday) for up to half a year, months for up to
+// three years, and years otherwise, so the stats fit in a message.
+func savesPerPeriod(times []time.Time, since, now time.Time) (string, []statsCount) {
+	loc := now.Location()
+	day := func(t time.Time) time.Time { return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc) }
+
+	period, layout := "year", "2006"
+	start := time.Date(since.Year(), 1, 1, 0, 0, 0, 0, loc)
+	next := func(t time.Time) time.Time { return t.AddDate(1, 0, 0) }
+	switch days := now.Sub(since).Hours() / 24; {
+	case days <= 14:
+		period, layout, start = "day", "Mon 2 Jan", day(since)
+		next = func(t time.Time) time.Time { return t.AddDate(0, 0, 1) }
+	case days <= 183:
+		period, layout = "week", "Week of 2 Jan"
+		start = day(since).AddDate(0, 0, -(int(since.Weekday())+6)%7)
+		next = func(t time.Time) time.Time { return t.AddDate(0, 0, 7) }
+	case days <= 3*366:
+		period, layout = "month", "Jan 2006"
+		start = time.Date(since.Year(), since.Month(), 1, 0, 0, 0, 0, loc)
+		next = func(t time.Time) time.Time { return t.AddDate(0, 1, 0) }
+	}
+
+	var periods []statsCount
+	for t := start; !t.After(now); t = next(t) {
+		end := next(t)
+		count := 0
+		for _, saved := range times {
+			if saved = saved.In(loc); !saved.Before(t) && saved.Before(end) {
+				count++
+			}
+		}
+		periods = append(periods, statsCount{name: t.Format(layout), count: count})
+	}
+	return period, periods
+}
+
+// renderStats returns the text of the stats.
+func renderStats(st *stats, loc *time.Location) string {
+	since := st.since.In(loc).Format("Mon 2 Jan 2006")
+	if st.saved == 0 {
+		return fmt.Sprintf("📊 No bookmarks saved since %s.", since)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "📊 Bookmarks saved since %s: %d", since, st.saved)
+
+	fmt.Fprintf(&b, "\n\n📅 Per %s:", st.period)
+	most := slices.MaxFunc(st.periods, func(a, b statsCount) int { return a.count - b.count }).count
+	for _, p := range st.periods {
+		b.WriteString("\n" + p.name + " ")
+		if p.count > 0 {
+			b.WriteString(strings.Repeat("▇", (p.count*statsBarWidth+most-1)/most) + " ")
+		}
+		fmt.Fprintf(&b, "%d", p.count)
+	}
+
+	writeCounts := func(title string, counts []statsCount) {
+		if len(counts) == 0 {
+			return
+		}
+		b.WriteString("\n\n" + title)
+		for _, c := range counts {
+			fmt.Fprintf(&b, "\n%s: %d", c.name, c.count)
+		}
+	}
+	writeCounts("🗂️ By type:", st.types)
+	if st.allChats {
+		writeCounts("💬 By chat:", st.chats)
+	} else if len(st.chats) > 1 || (len(st.chats) == 1 && st.chats[0].name != "General") {
+		writeCounts("💬 By topic:", st.chats)
+	}
+	writeCounts("🌐 Top domains:", st.domains)
+	writeCounts("🏷️ Top tags:", st.tags)
+
+	if st.tagged > 0 {
+		fmt.Fprintf(&b, "\n\n⏱️ Average tagging time: %s (%d bookmarks)", st.latency.Round(time.Second), st.tagged)
+	}
+	return b.String()
+}
+
+// handleStatsCommand replies with the stats of the bookmarks saved in the chat
+// since the given time. Admins get the stats of every chat in their private
+// chat with the bot.
+func (kb *KarakeepBot) handleStatsCommand(ctx context.Context, msg *TelegramMessage, args []string) {
+	expr := defaultStatsSince
+	if len(args) > 0 {
+		// Both "--since 7d" and "--since=7d" are accepted
+		value, ok := strings.CutPrefix(strings.Join(args, " "), "--since")
+		value = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(value), "="))
+		if !ok || value == "" {
+			kb.reply(ctx, msg, "⚠️ Usage: "+statsUsage)
+			return
+		}
+		expr = value
+	}
+
+	loc := kb.config.Scheduler.Location()
+	now := kb.clock.Now().In(loc)
+	var since time.Time
+	if !strings.EqualFold(expr, "all") {
+		var err error
+		if since, err = when.Since(expr, now); err != nil {
+			kb.reply(ctx, msg, fmt.Sprintf("⚠️ %v. Usage: %s", err, statsUsage))
+			return
+		}
+	}
+
+	chatID := msg.Chat.ID
+	if msg.Chat.Type == models.ChatTypePrivate && kb.roleOf(msg.From.ID) >= RoleAdmin {
+		chatID = 0
+	}
+
+	st, err := kb.computeStats(since, now, chatID, loc, kb.settings.Load())
+	if err != nil {
+		kb.logger.Error("Failed to compute stats", msg.AttrsWithError(err)...)
+		kb.reply(ctx, msg, "⚠️ Failed to compute the stats, try again later")
+		return
+	}
+	kb.reply(ctx, msg, renderStats(st, loc))
+}
@@ -0,0 +1,169 @@
+package karakeepbot
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Madh93/karakeepbot/internal/config"
+	"github.com/Madh93/karakeepbot/internal/logging"
+	"github.com/go-telegram/bot/models"
+)
+
+// newTestStatsBot returns a bot with user 1 as admin and a few events, and
+// the Telegram calls. Bookmarks saved in chat 1 get the "go-group" tag.
+func newTestStatsBot(t *testing.T) (*KarakeepBot, func() []string) {
+	t.Helper()
+
+	kb := newTestRolesBot(t, 1)
+	telegram, calls := newTestTelegram(t)
+	kb.telegram = telegram
+	kb.config = newTestConfig()
+	kb.config.Scheduler.Timezone = "UTC"
+	kb.config.Roles.Admins = []int64{1}
+	kb.config.Chats = config.ChatsConfig{{ID: 1, Tags: []string{"Go-Group"}}}
+	kb.settings.Store(newSettings(kb.config))
+	kb.logger = logging.New(&kb.config.Logging)
+	kb.clock = fixedClock(testNow)
+
+	events := []statsEvent{
+		{Kind: savedEvent, Time: testNow.AddDate(0, 0, -40), BookmarkID: "b0", ChatID: 1, Chat: "Go group", Type: "link", Domain: "old.example.com"},
+		{Kind: savedEvent, Time: testNow.AddDate(0, 0, -3), BookmarkID: "b3", ChatID: 2, Chat: "@alice", Type: "text"},
+		{Kind: savedEvent, Time: testNow.AddDate(0, 0, -2), BookmarkID: "b2", ChatID: 1, Chat: "Go group", ThreadID: 5, Type: "link", Domain: "go.dev"},
+		{Kind: taggedEvent, Time: testNow.AddDate(0, 0, -2).Add(30 * time.Second), BookmarkID: "b2", Tags: []string{"golang", "telegram", "go-group"}},
+		{Kind: savedEvent, Time: testNow.Add(-time.Hour), BookmarkID: "b1", ChatID: 1, Chat: "Go group", Type: "link", Domain: "github.com"},
+		{Kind: taggedEvent, Time: testNow.Add(-time.Hour + 90*time.Second), BookmarkID: "b1", Tags: []string{"telegram", "golang", "open source"}},
+		{Kind: taggedEvent, Time: testNow.Add(-time.Hour + 10*time.Minute), BookmarkID: "b1", Tags: []string{"ignored"}},
+	}
+	for _, e := range events {
+		kb.recordEvent(e)
+	}
+	return kb, calls
+}
+
+func TestDomainOf(t *testing.T) {
+	tests := []struct {
+		url      string
+		expected string
+	}{
+		{"https://www.GitHub.com/golang/go", "github.com"},
+		{"https://go.dev:443/doc", "go.dev"},
+		{"", ""},
+		{"://invalid", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			if got := domainOf(tt.url); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestSavesPerPeriod(t *testing.T) {
+	times := []time.Time{testNow.Add(-time.Hour), testNow.AddDate(0, 0, -1), testNow.AddDate(0, 0, -9), testNow.AddDate(0, -3, 0)}
+
+	tests := []struct {
+		name           string
+		since          time.Time
+		expectedPeriod string
+		expected       []statsCount
+	}{
+		{"Days", testNow.AddDate(0, 0, -2), "day", []statsCount{{"Fri 16 Oct", 0}, {"Sat 17 Oct", 1}, {"Sun 18 Oct", 1}}},
+		{"Weeks", testNow.AddDate(0, 0, -20), "week", []statsCount{{"Week of 28 Sep", 0}, {"Week of 5 Oct", 1}, {"Week of 12 Oct", 2}}},
+		{"Months", testNow.AddDate(0, -7, 0), "month", []statsCount{
+			{"Mar 2026", 0}, {"Apr 2026", 0}, {"May 2026", 0}, {"Jun 2026", 0}, {"Jul 2026", 1}, {"Aug 2026", 0}, {"Sep 2026", 0}, {"Oct 2026", 3},
+		}},
+		{"Years", testNow.AddDate(-4, 0, 0), "year", []statsCount{{"2022", 0}, {"2023", 0}, {"2024", 0}, {"2025", 0}, {"2026", 4}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			period, periods := savesPerPeriod(times, tt.since, testNow)
+			if period != tt.expectedPeriod {
+				t.Errorf("Expected periods of a %s, got %s", tt.expectedPeriod, period)
+			}
+			if !slices.Equal(periods, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, periods)
+			}
+		})
+	}
+}
+
+func TestKarakeepBot_compactEvents(t *testing.T) {
+	kb, _ := newTestStatsBot(t)
+	kb.recordEvent(statsEvent{Kind: savedEvent, Time: testNow.Add(-statsRetention - time.Hour), BookmarkID: "old"})
+	kb.compactEvents()
+
+	var ids []string
+	_ = kb.state.Scan(eventsLog, func(record []byte) error {
+		var e statsEvent
+		_ = json.Unmarshal(record, &e)
+		ids = append(ids, e.BookmarkID)
+		return nil
+	})
+	if expected := []string{"b0", "b3", "b2", "b2", "b1", "b1", "b1"}; !slices.Equal(ids, expected) {
+		t.Errorf("Expected events %v, got %v", expected, ids)
+	}
+}
+
+func TestKarakeepBot_handleStatsCommand(t *testing.T) {
+	allChats := "📊 Bookmarks saved since Sun 11 Oct 2026: 3\n\n" +
+		"📅 Per day:\nSun 11 Oct 0\nMon 12 Oct 0\nTue 13 Oct 0\nWed 14 Oct 0\nThu 15 Oct ▇▇▇▇▇▇▇▇▇▇ 1\nFri 16 Oct ▇▇▇▇▇▇▇▇▇▇ 1\nSat 17 Oct 0\nSun 18 Oct ▇▇▇▇▇▇▇▇▇▇ 1\n\n" +
+		"🗂️ By type:\nlink: 2\ntext: 1\n\n" +
+		"💬 By chat:\n@alice: 1\nGo group: 1\nGo group › topic 5: 1\n\n" +
+		"🌐 Top domains:\ngithub.com: 1\ngo.dev: 1\n\n" +
+		"🏷️ Top tags:\n#golang: 2\n#opensource: 1\n\n" +
+		"⏱️ Average tagging time: 1m0s (2 bookmarks)"
+
+	tests := []struct {
+		name     string
+		userID   int64
+		chat     models.Chat
+		args     []string
+		expected string
+	}{
+		{"Admin in private chat", 1, models.Chat{ID: 1, Type: models.ChatTypePrivate}, []string{"--since", "7d"}, allChats},
+		{"Admin in group", 1, models.Chat{ID: 1, Type: models.ChatTypeSupergroup}, []string{"--since=2026-10-17"},
+			"📊 Bookmarks saved since Sat 17 Oct 2026: 1\n\n" +
+				"📅 Per day:\nSat 17 Oct 0\nSun 18 Oct ▇▇▇▇▇▇▇▇▇▇ 1\n\n" +
+				"🗂️ By type:\nlink: 1\n\n" +
+				"🌐 Top domains:\ngithub.com: 1\n\n" +
+				"🏷️ Top tags:\n#golang: 1\n#opensource: 1\n\n" +
+				"⏱️ Average tagging time: 1m30s (1 bookmarks)"},
+		{"Topics", 2, models.Chat{ID: 1, Type: models.ChatTypeSupergroup}, []string{"--since", "3", "days", "ago"},
+			"📊 Bookmarks saved since Thu 15 Oct 2026: 2\n\n" +
+				"📅 Per day:\nThu 15 Oct 0\nFri 16 Oct ▇▇▇▇▇▇▇▇▇▇ 1\nSat 17 Oct 0\nSun 18 Oct ▇▇▇▇▇▇▇▇▇▇ 1\n\n" +
+				"🗂️ By type:\nlink: 2\n\n" +
+				"💬 By topic:\nGeneral: 1\nTopic 5: 1\n\n" +
+				"🌐 Top domains:\ngithub.com: 1\ngo.dev: 1\n\n" +
+				"🏷️ Top tags:\n#golang: 2\n#opensource: 1\n\n" +
+				"⏱️ Average tagging time: 1m0s (2 bookmarks)"},
+		{"Default period", 2, models.Chat{ID: 2, Type: models.ChatTypePrivate}, nil,
+			"📊 Bookmarks saved since Fri 18 Sep 2026: 1\n\n" +
+				"📅 Per week:\nWeek of 14 Sep 0\nWeek of 21 Sep 0\nWeek of 28 Sep 0\nWeek of 5 Oct 0\nWeek of 12 Oct ▇▇▇▇▇▇▇▇▇▇ 1\n\n" +
+				"🗂️ By type:\ntext: 1"},
+		{"All time", 2, models.Chat{ID: 2, Type: models.ChatTypePrivate}, []string{"--since", "all"},
+			"📊 Bookmarks saved since Thu 15 Oct 2026: 1\n\n" +
+				"📅 Per day:\nThu 15 Oct ▇▇▇▇▇▇▇▇▇▇ 1\nFri 16 Oct 0\nSat 17 Oct 0\nSun 18 Oct 0\n\n" +
+				"🗂️ By type:\ntext: 1"},
+		{"Nothing saved", 2, models.Chat{ID: 3, Type: models.ChatTypePrivate}, []string{"--since", "today"}, "📊 No bookmarks saved since Sun 18 Oct 2026."},
+		{"Missing flag", 2, models.Chat{ID: 1}, []string{"7d"}, "⚠️ Usage: " + statsUsage},
+		{"Missing value", 2, models.Chat{ID: 1}, []string{"--since"}, "⚠️ Usage: " + statsUsage},
+		{"Invalid value", 2, models.Chat{ID: 1}, []string{"--since", "someday"}, `⚠️ unknown time "someday". Usage: ` + statsUsage},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kb, calls := newTestStatsBot(t)
+			kb.handleStatsCommand(context.Background(), &TelegramMessage{ID: 10, Chat: tt.chat, From: &models.User{ID: tt.userID}}, tt.args)
+
+			if expected := []string{"sendMessage: " + tt.expected}; !slices.Equal(calls(), expected) {
+				t.Errorf("Expected calls %q, got %q", expected, calls())
+			}
+		})
+	}
+}
@@ -0,0 +1,243 @@
+package karakeepbot
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/Madh93/go-karakeep"
+)
+
+const (
+	tagsPageSize      = 20 // Number of tags per page of /tags.
+	bookmarksPageSize = 5  // Number of bookmarks per page of bookmark listings.
+)
+
+// tagUsage is the usage of the /tag command.
+const tagUsage = "/tag <name>, /tag rename <old> <new> or /tag merge <from> <into>"
+
+// handleTagsCommand lists the most used tags.
+func (kb *KarakeepBot) handleTagsCommand(ctx context.Context, msg *TelegramMessage, _ []string) {
+	kb.sendPage(ctx, msg, "tags", "")
+}
+
+// handleTagCommand lists the recent bookmarks with a tag, or renames or merges
+// tags for admins. Names with spaces can be quoted.
+func (kb *KarakeepBot) handleTagCommand(ctx context.Context, msg *TelegramMessage, args []string) {
+	args = splitQuoted(strings.Join(args, " "))
+	if len(args) == 0 {
+		kb.reply(ctx, msg, "⚠️ Missing tag name. Usage: "+tagUsage)
+		return
+	}
+
+	if action := strings.ToLower(args[0]); len(args) == 3 && (action == "rename" || action == "merge") {
+		if role := kb.roleOf(msg.From.ID); role < RoleAdmin {
+			kb.logger.Warn("Denied command: insufficient role", append(msg.Attrs(), "command", "tag "+action, "role", role, "required_role", RoleAdmin)...)
+			kb.reply(ctx, msg, fmt.Sprintf("⛔ /tag %s requires the %s role.", action, RoleAdmin))
+			return
+		}
+		if action == "rename" {
+			kb.renameTag(ctx, msg, args[1], args[2])
+		} else {
+			kb.mergeTags(ctx, msg, args[1], args[2])
+		}
+		return
+	}
+
+	name := strings.Join(args, " ")
+	tag, err := kb.findTag(ctx, name)
+	if err != nil {
+		kb.logger.Error("Failed to find tag", append(msg.AttrsWithError(err), "tag", name)...)
+		kb.reply(ctx, msg, "⚠️ Failed to get the tags from Karakeep, try again later")
+		return
+	}
+	if tag == nil {
+		kb.reply(ctx, msg, fmt.Sprintf("🔍 Tag %q not found. Use /tags to list them.", name))
+		return
+	}
+
+	kb.sendPage(ctx, msg, "tag", tag.Id)
+}
+
+// renameTag renames the tag old to new.
+func (kb *KarakeepBot) renameTag(ctx context.Context, msg *TelegramMessage, oldName, newName string) {
+	tags, err := kb.karakeep.Tags(ctx)
+	if err != nil {
+		kb.logger.Error("Failed to get tags", msg.AttrsWithError(err)...)
+		kb.reply(ctx, msg, "⚠️ Failed to get the tags from Karakeep, try again later")
+		return
+	}
+
+	tag := kb.matchTag(tags, oldName)
+	if tag == nil {
+		kb.reply(ctx, msg, fmt.Sprintf("🔍 Tag %q not found. Use /tags to list them.", oldName))
+		return
+	}
+	newName = strings.TrimPrefix(newName, "#")
+	if existing := kb.matchTag(tags, newName); existing != nil && existing.Id != tag.Id {
+		kb.reply(ctx, msg, fmt.Sprintf("⚠️ Tag %q already exists. Use /tag merge %q %q instead.", existing.Name, tag.Name, existing.Name))
+		return
+	}
+
+	if err := kb.karakeep.RenameTag(ctx, tag.Id, newName); err != nil {
+		kb.logger.Error("Failed to rename tag", append(msg.AttrsWithError(err), "tag", tag.Name, "new_name", newName)...)
+		kb.reply(ctx, msg, "⚠️ Failed to rename the tag, try again later")
+		return
+	}
+
+	kb.logger.Info("Renamed tag", append(msg.Attrs(), "tag", tag.Name, "new_name", newName)...)
+	kb.reply(ctx, msg, fmt.Sprintf("✅ Renamed tag %q to %q.", tag.Name, newName))
+}
+
+// mergeTags moves the bookmarks with the tag from to the tag into, and deletes
+// the tag from.
+func (kb *KarakeepBot) mergeTags(ctx context.Context, msg *TelegramMessage, fromName, intoName string) {
+	tags, err := kb.karakeep.Tags(ctx)
+	if err != nil {
+		kb.logger.Error("Failed to get tags", msg.AttrsWithError(err)...)
+		kb.reply(ctx, msg, "⚠️ Failed to get the tags from Karakeep, try again later")
+		return
+	}
+
+	from, into := kb.matchTag(tags, fromName), kb.matchTag(tags, intoName)
+	switch {
+	case from == nil:
+		kb.reply(ctx, msg, fmt.Sprintf("🔍 Tag %q not found. Use /tags to list them.", fromName))
+		return
+	case into == nil:
+		kb.reply(ctx, msg, fmt.Sprintf("🔍 Tag %q not found. Use /tag rename %q %q to rename it instead.", intoName, from.Name, intoName))
+		return
+	case from.Id == into.Id:
+		kb.reply(ctx, msg, "⚠️ Can't merge a tag into itself.")
+		return
+	}
+
+	merged, err := kb.karakeep.MergeTags(ctx, from.Id, into.Id)
+	if err != nil {
+		kb.logger.Error("Failed to merge tags", append(msg.AttrsWithError(err), "tag", from.Name, "into", into.Name, "merged", merged)...)
+		kb.reply(ctx, msg, fmt.Sprintf("⚠️ Failed to merge the tags after moving %d bookmarks, try again later", merged))
+		return
+	}
+
+	kb.logger.Info("Merged tags", append(msg.Attrs(), "tag", from.Name, "into", into.Name, "merged", merged)...)
+	kb.reply(ctx, msg, fmt.Sprintf("✅ Merged tag %q into %q (%d bookmarks).", from.Name, into.Name, merged))
+}
+
+// findTag returns the tag with the given name, or nil if it doesn't exist.
+func (kb *KarakeepBot) findTag(ctx context.Context, name string) (*karakeep.Tag, error) {
+	tags, err := kb.karakeep.Tags(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return kb.matchTag(tags, name), nil
+}
+
+// matchTag returns the tag with the given name, ignoring case, or nil if none
+// matches. The name can also be a hashtag as shown by the bot (e.g.
+// "#MachineLearning" for "Machine Learning").
+func (kb *KarakeepBot) matchTag(tags []karakeep.Tag, name string) *karakeep.Tag {
+	hashtag, isHashtag := strings.CutPrefix(name, "#")
+	for i, tag := range tags {
+		if strings.EqualFold(tag.Name, hashtag) {
+			return &tags[i]
+		}
+	}
+	if !isHashtag && strings.ContainsFunc(name, unicode.IsSpace) {
+		return nil
+	}
+
+	hashtags := kb.settings.Load().hashtags
+	for i, tag := range tags {
+		if strings.EqualFold(hashtags.Hashtag(tag.Name), hashtag) {
+			return &tags[i]
+		}
+	}
+	return nil
+}
+
+// renderTagsPage renders a page of the tags sorted by number of bookmarks.
+func (kb *KarakeepBot) renderTagsPage(ctx context.Context, _ string, page int) (string, bool, error) {
+	tags, err := kb.karakeep.Tags(ctx)
+	if err != nil {
+		return "", false, err
+	}
+	if len(tags) == 0 {
+		return "🏷️ There are no tags yet.", false, nil
+	}
+
+	slices.SortFunc(tags, func(a, b karakeep.Tag) int {
+		return cmp.Or(cmp.Compare(b.NumBookmarks, a.NumBookmarks), strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name)))
+	})
+
+	pages := (len(tags) + tagsPageSize - 1) / tagsPageSize
+	page = min(page, pages-1)
+	var b strings.Builder
+	fmt.Fprintf(&b, "🏷️ Tags (page %d/%d):\n", page+1, pages)
+	for _, tag := range tags[page*tagsPageSize : min((page+1)*tagsPageSize, len(tags))] {
+		fmt.Fprintf(&b, "\n• %s (%d)", tag.Name, int(tag.NumBookmarks))
+	}
+	b.WriteString("\n\nUse /tag <name> to see its bookmarks.")
+
+	return b.String(), page < pages-1, nil
+}
+
+// renderTagPage renders a page of the most recent bookmarks with a tag.
+func (kb *KarakeepBot) renderTagPage(ctx context.Context, tagID string, page int) (string, bool, error) {
+	tag, err := kb.karakeep.Tag(ctx, tagID)
+	if err != nil {
+		return "", false, err
+	}
+
+	bookmarks, more, err := kb.karakeep.TagBookmarks(ctx, tagID, page, bookmarksPageSize)
+	if err != nil {
+		return "", false, err
+	}
+	if len(bookmarks) == 0 && page == 0 {
+		return fmt.Sprintf("🔖 There are no bookmarks tagged %q.", tag.Name), false, nil
+	}
+
+	header := fmt.Sprintf("🔖 Bookmarks tagged %q (%d, page %d):", tag.Name, int(tag.NumBookmarks), page+1)
+	return header + renderBookmarks(bookmarks, page*bookmarksPageSize), more, nil
+}
+
+// renderBookmarks renders a numbered list of bookmarks, starting after offset.
+func renderBookmarks(bookmarks []KarakeepBookmark, offset int) string {
+	var b strings.Builder
+	for i, bookmark := range bookmarks {
+		fmt.Fprintf(&b, "\n\n%d. %s", offset+i+1, bookmark.Headline())
+		if url := bookmark.URL(); url != "" && url != bookmark.Headline() {
+			fmt.Fprintf(&b, "\n%s", url)
+		}
+	}
+	return b.String()
+}
+
+// splitQuoted splits s around spaces, keeping together the words enclosed in
+// double quotes, including the typographic ones added by some keyboards.
+func splitQuoted(s string) []string {
+	var fields []string
+	var field strings.Builder
+	quoted, started := false, false
+	for _, r := range s {
+		switch {
+		case r == '"' || r == '“' || r == '”':
+			quoted, started = !quoted, true
+		case unicode.IsSpace(r) && !quoted:
+			if started {
+				fields = append(fields, field.String())
+				field.Reset()
+				started = false
+			}
+		default:
+			field.WriteRune(r)
+			started = true
+		}
+	}
+	if started {
+		fields = append(fields, field.String())
+	}
+	return fields
+}
@@ -0,0 +1,254 @@
+package karakeepbot
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Madh93/karakeepbot/internal/logging"
+	"github.com/go-telegram/bot/models"
+)
+
+// fakeTags is an in-memory Karakeep server supporting the tags API. Bookmarks
+// are listed from the last one added, like Karakeep's most recent first.
+type fakeTags struct {
+	mu        sync.Mutex
+	names     map[string]string   // Tag names, by tag ID.
+	bookmarks map[string][]string // Bookmark IDs, by tag ID.
+}
+
+func (f *fakeTags) tagJSON(id string) map[string]any {
+	return map[string]any{"id": id, "name": f.names[id], "numBookmarks": len(f.bookmarks[id]), "numBookmarksByAttachedType": map[string]any{}}
+}
+
+func (f *fakeTags) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var body struct {
+		Name string `json:"name"`
+		Tags []struct {
+			TagID string `json:"tagId"`
+		} `json:"tags"`
+	}
+	_ = json.NewDecoder(r.Body).Decode(&body)
+	path := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/v1/"), "/")
+	w.Header().Set("Content-Type", "application/json")
+
+	switch {
+	case r.Method == http.MethodGet && len(path) == 1 && path[0] == "tags":
+		tags := []any{}
+		for id := range f.names {
+			tags = append(tags, f.tagJSON(id))
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"tags": tags})
+	case r.Method == http.MethodGet && len(path) == 2:
+		_ = json.NewEncoder(w).Encode(f.tagJSON(path[1]))
+	case r.Method == http.MethodGet && len(path) == 3:
+		ids := slices.Clone(f.bookmarks[path[1]])
+		slices.Reverse(ids)
+		start, _ := strconv.Atoi(r.URL.Query().Get("cursor"))
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		end := min(start+limit, len(ids))
+		bookmarks := []any{}
+		for _, id := range ids[start:end] {
+			bookmarks = append(bookmarks, map[string]any{"id": id, "tags": []any{}, "content": map[string]any{"type": "text", "text": "Bookmark " + id}})
+		}
+		var next any
+		if end < len(ids) {
+			next = strconv.Itoa(end)
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"bookmarks": bookmarks, "nextCursor": next})
+	case r.Method == http.MethodPatch && len(path) == 2:
+		f.names[path[1]] = body.Name
+		_ = json.NewEncoder(w).Encode(f.tagJSON(path[1]))
+	case r.Method == http.MethodDelete && len(path) == 2:
+		delete(f.names, path[1])
+		delete(f.bookmarks, path[1])
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPost && path[0] == "bookmarks":
+		for _, tag := range body.Tags {
+			if !slices.Contains(f.bookmarks[tag.TagID], path[1]) {
+				f.bookmarks[tag.TagID] = append(f.bookmarks[tag.TagID], path[1])
+			}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"attached": []string{}})
+	case r.Method == http.MethodDelete && path[0] == "bookmarks":
+		for _, tag := range body.Tags {
+			f.bookmarks[tag.TagID] = slices.DeleteFunc(f.bookmarks[tag.TagID], func(id string) bool { return id == path[1] })
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"detached": []string{}})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// newTestTagsBot returns a bot connected to a fake Karakeep server with the
+// given tags, where user 1 is an admin and user 2 a reader, and a function
+// returning the calls to Telegram.
+func newTestTagsBot(t *testing.T, tags *fakeTags) (*KarakeepBot, func() []string) {
+	t.Helper()
+
+	server := httptest.NewServer(tags)
+	t.Cleanup(server.Close)
+
+	kb := newTestRolesBot(t, 1)
+	if err := kb.grants.grant(2, RoleReader); err != nil {
+		t.Fatalf("grant() returned an unexpected error: %v", err)
+	}
+	cfg := newTestConfig()
+	cfg.Karakeep.URL = server.URL
+	kb.logger = logging.New(&cfg.Logging)
+	kb.karakeep = createKarakeep(kb.logger, &cfg.Karakeep)
+	telegram, calls := newTestTelegram(t)
+	kb.telegram = telegram
+	return kb, calls
+}
+
+// testBookmarkIDs returns the IDs "prefix1" to "prefixN".
+func testBookmarkIDs(prefix string, n int) []string {
+	ids := make([]string, n)
+	for i := range ids {
+		ids[i] = prefix + strconv.Itoa(i+1)
+	}
+	return ids
+}
+
+func TestSplitQuoted(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"", nil},
+		{"golang", []string{"golang"}},
+		{"rename  old new", []string{"rename", "old", "new"}},
+		{`rename "Machine Learning" ML`, []string{"rename", "Machine Learning", "ML"}},
+		{"merge “Machine Learning” “Deep Learning”", []string{"merge", "Machine Learning", "Deep Learning"}},
+		{`""`, []string{""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := splitQuoted(tt.input); !slices.Equal(got, tt.expected) {
+				t.Errorf("splitQuoted(%q) = %q, expected %q", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestKarakeepBot_renderTagsPage(t *testing.T) {
+	tags := &fakeTags{names: map[string]string{}, bookmarks: map[string][]string{}}
+	for i := range 25 {
+		id := "t" + strconv.Itoa(i)
+		tags.names[id] = "tag" + strconv.Itoa(i)
+		tags.bookmarks[id] = testBookmarkIDs(id+"b", i)
+	}
+	kb, _ := newTestTagsBot(t, tags)
+
+	text, more, err := kb.renderTagsPage(context.Background(), "", 0)
+	if err != nil {
+		t.Fatalf("renderTagsPage() returned an unexpected error: %v", err)
+	}
+	if !more || !strings.HasPrefix(text, "🏷️ Tags (page 1/2):\n\n• tag24 (24)\n• tag23 (23)") {
+		t.Errorf("Unexpected first page (more: %v):\n%s", more, text)
+	}
+
+	text, more, err = kb.renderTagsPage(context.Background(), "", 1)
+	if err != nil {
+		t.Fatalf("renderTagsPage() returned an unexpected error: %v", err)
+	}
+	if more || !strings.Contains(text, "• tag4 (4)") || !strings.Contains(text, "• tag0 (0)") || strings.Contains(text, "tag5") {
+		t.Errorf("Unexpected last page (more: %v):\n%s", more, text)
+	}
+}
+
+func TestKarakeepBot_renderTagPage(t *testing.T) {
+	tags := &fakeTags{names: map[string]string{"go": "golang"}, bookmarks: map[string][]string{"go": testBookmarkIDs("b", 7)}}
+	kb, _ := newTestTagsBot(t, tags)
+
+	tests := []struct {
+		page         int
+		expected     []string
+		expectedMore bool
+	}{
+		{0, []string{`🔖 Bookmarks tagged "golang" (7, page 1):`, "1. Bookmark b7", "5. Bookmark b3"}, true},
+		{1, []string{"6. Bookmark b2", "7. Bookmark b1"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run("Page "+strconv.Itoa(tt.page), func(t *testing.T) {
+			text, more, err := kb.renderTagPage(context.Background(), "go", tt.page)
+			if err != nil {
+				t.Fatalf("renderTagPage() returned an unexpected error: %v", err)
+			}
+			if more != tt.expectedMore {
+				t.Errorf("Expected more: %v, got %v", tt.expectedMore, more)
+			}
+			for _, expected := range tt.expected {
+				if !strings.Contains(text, expected) {
+					t.Errorf("Expected %q in:\n%s", expected, text)
+				}
+			}
+		})
+	}
+}
+
+func TestKarakeepBot_handleTagCommand(t *testing.T) {
+	newTags := func() *fakeTags {
+		return &fakeTags{
+			names:     map[string]string{"ml": "Machine Learning", "ai": "ai", "go": "golang"},
+			bookmarks: map[string][]string{"ml": {"b1", "b2"}, "ai": {"b2", "b3"}, "go": {"b4"}},
+		}
+	}
+
+	tests := []struct {
+		name          string
+		userID        int64
+		text          string
+		expectedReply string
+		expectedNames map[string]string
+	}{
+		{"List bookmarks by hashtag", 2, "#MachineLearning", `sendMessage: 🔖 Bookmarks tagged "Machine Learning"`, nil},
+		{"Unknown tag", 2, "rust", `sendMessage: 🔍 Tag "rust" not found`, nil},
+		{"Rename", 1, `rename golang "Go Language"`, `sendMessage: ✅ Renamed tag "golang" to "Go Language".`, map[string]string{"ml": "Machine Learning", "ai": "ai", "go": "Go Language"}},
+		{"Rename to an existing tag", 1, "rename ai golang", `sendMessage: ⚠️ Tag "golang" already exists`, nil},
+		{"Rename as reader", 2, "rename golang go", "sendMessage: ⛔ /tag rename requires the admin role.", nil},
+		{"Merge", 1, "merge ai “Machine Learning”", `sendMessage: ✅ Merged tag "ai" into "Machine Learning" (2 bookmarks).`, map[string]string{"ml": "Machine Learning", "go": "golang"}},
+		{"Merge into itself", 1, "merge ai AI", "sendMessage: ⚠️ Can't merge a tag into itself.", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tags := newTags()
+			kb, calls := newTestTagsBot(t, tags)
+			msg := &TelegramMessage{ID: 1, Chat: models.Chat{ID: 1}, From: &models.User{ID: tt.userID}}
+
+			kb.handleTagCommand(context.Background(), msg, strings.Fields(tt.text))
+
+			if got := calls(); len(got) != 1 || !strings.HasPrefix(got[0], tt.expectedReply) {
+				t.Errorf("Expected reply starting with %q, got %q", tt.expectedReply, got)
+			}
+			if tt.expectedNames != nil && !maps.Equal(tags.names, tt.expectedNames) {
+				t.Errorf("Expected tags %v, got %v", tt.expectedNames, tags.names)
+			}
+		})
+	}
+
+	t.Run("Merged bookmarks", func(t *testing.T) {
+		tags := newTags()
+		kb, _ := newTestTagsBot(t, tags)
+		if _, err := kb.karakeep.MergeTags(context.Background(), "ai", "ml"); err != nil {
+			t.Fatalf("MergeTags() returned an unexpected error: %v", err)
+		}
+		if expected := []string{"b1", "b2", "b3"}; !slices.Equal(tags.bookmarks["ml"], expected) {
+			t.Errorf("Expected bookmarks %v, got %v", expected, tags.bookmarks["ml"])
+		}
+	})
+}
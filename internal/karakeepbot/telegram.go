@@ -2,12 +2,14 @@ package karakeepbot
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
-	"net/url"
+	"os"
 	"time"
 
 	"github.com/Madh93/karakeepbot/internal/config"
+	"github.com/Madh93/karakeepbot/internal/httpclient"
 	"github.com/Madh93/karakeepbot/internal/logging"
 	"github.com/Madh93/karakeepbot/internal/secret"
 	tgbotapi "github.com/go-telegram/bot"
@@ -20,56 +22,116 @@ type Bot = tgbotapi.Bot
 // Telegram embeds the Telegram bot API client to add high level functionality.
 type Telegram struct {
 	*Bot
-	token secret.String
+	token    secret.String
+	username string // Bot username, used to recognize commands addressed to it.
+	retries  int    // Maximum number of retries for requests rejected with 429 Too Many Requests.
 }
 
 // createTelegram initializes the Telegram Bot API client.
 func createTelegram(logger *logging.Logger, config *config.TelegramConfig) *Telegram {
 	logger.Debug(fmt.Sprintf("Initializing Telegram Bot API using %s token", config.Token))
 
+	opts, err := telegramOptions(config)
+	if err != nil {
+		logger.Fatal("Error parsing proxy URL.", "error", err)
+	}
+
+	telegramBot, err := tgbotapi.New(config.Token.Value(), append(opts, tgbotapi.WithSkipGetMe())...)
+	if err != nil {
+		logger.Fatal("Error creating Telegram Bot API.", "error", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	me, err := telegramBot.GetMe(ctx)
+	if err != nil {
+		logger.Fatal("Error creating Telegram Bot API.", "error", err)
+	}
+
+	return &Telegram{Bot: telegramBot, token: config.Token, username: me.Username, retries: config.Retries}
+}
+
+// telegramOptions returns the Telegram Bot API client options for the given
+// configuration.
+func telegramOptions(config *config.TelegramConfig) ([]tgbotapi.Option, error) {
 	var opts []tgbotapi.Option
 
-	if config.ProxyEnabled {
-		proxyURL, err := url.Parse(config.ProxyURL)
-		if err != nil {
-			logger.Fatal("Error parsing proxy URL.", "error", err)
-		}
+	client, err := telegramHTTPClient(config)
+	if err != nil {
+		return nil, err
+	}
 
-		transport := &http.Transport{
-			Proxy: http.ProxyURL(proxyURL),
-		}
-		client := &http.Client{
-			Transport: transport,
-		}
+	if client != nil {
 		opts = append(opts, tgbotapi.WithHTTPClient(30*time.Second, client))
 		opts = append(opts, tgbotapi.WithCheckInitTimeout(30*time.Second))
 	}
 
-	telegramBot, err := tgbotapi.New(config.Token.Value(), opts...)
+	return opts, nil
+}
+
+// telegramHTTPClient returns the HTTP client used to reach Telegram servers
+// through the configured proxy. It returns nil if no proxy is enabled.
+func telegramHTTPClient(config *config.TelegramConfig) (*http.Client, error) {
+	if !config.ProxyEnabled {
+		return nil, nil
+	}
+
+	transport, err := httpclient.NewTransport(httpclient.TransportOptions{ProxyURL: config.ProxyURL.Value()})
 	if err != nil {
-		logger.Fatal("Error creating Telegram Bot API.", "error", err)
+		return nil, fmt.Errorf("invalid proxy URL %s", config.ProxyURL)
 	}
+	return &http.Client{
+		Transport: transport,
+	}, nil
+}
+
+// withRetry calls fn and, while Telegram rejects it with 429 Too Many
+// Requests, waits for the requested retry_after and calls it again, up to
+// retries times.
+func withRetry(ctx context.Context, retries int, fn func() error) error {
+	for attempt := 0; ; attempt++ {
+		err := fn()
+
+		var tooManyRequests *tgbotapi.TooManyRequestsError
+		if !errors.As(err, &tooManyRequests) || attempt >= retries {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(time.Duration(tooManyRequests.RetryAfter) * time.Second):
+		}
+	}
+}
 
-	return &Telegram{Bot: telegramBot, token: config.Token}
+// SendNewMessage sends a new message to the user's chat, and returns the sent
+// message.
+func (t Telegram) SendNewMessage(ctx context.Context, msg *TelegramMessage) (*TelegramMessage, error) {
+	return t.SendNewMessageWithKeyboard(ctx, msg, nil)
 }
 
-// SendNewMessage sends a new message to the user's chat.
-func (t Telegram) SendNewMessage(ctx context.Context, msg *TelegramMessage) error {
+// SendNewMessageWithKeyboard sends a new message to the chat (and thread) of
+// msg with inline keyboard buttons, and returns the sent message. The keyboard
+// may be nil.
+func (t Telegram) SendNewMessageWithKeyboard(ctx context.Context, msg *TelegramMessage, keyboard *models.InlineKeyboardMarkup) (*TelegramMessage, error) {
 	params := &tgbotapi.SendMessageParams{
 		ChatID:          msg.Chat.ID,
 		MessageThreadID: msg.MessageThreadID,
 		Text:            msg.Text,
 	}
-
-	if _, err := t.SendMessage(ctx, params); err != nil {
-		return err
+	if keyboard != nil {
+		params.ReplyMarkup = keyboard
 	}
 
-	return nil
+	return t.send(ctx, func() (*models.Message, error) {
+		return t.SendMessage(ctx, params)
+	})
 }
 
-// SendPhotoWithCaption sends a photo with a caption.
-func (t *Telegram) SendPhotoWithCaption(ctx context.Context, msg *TelegramMessage, photoID string, caption string) error {
+// SendPhotoWithCaption sends a photo with a caption, and returns the sent
+// message.
+func (t *Telegram) SendPhotoWithCaption(ctx context.Context, msg *TelegramMessage, photoID string, caption string) (*TelegramMessage, error) {
 	params := &tgbotapi.SendPhotoParams{
 		ChatID:          msg.Chat.ID,
 		MessageThreadID: msg.MessageThreadID,
@@ -77,27 +139,156 @@ func (t *Telegram) SendPhotoWithCaption(ctx context.Context, msg *TelegramMessag
 		Caption:         caption,
 	}
 
-	if _, err := t.SendPhoto(ctx, params); err != nil {
-		return err
-	}
+	return t.send(ctx, func() (*models.Message, error) {
+		return t.SendPhoto(ctx, params)
+	})
+}
+
+// SendDocumentReply sends the file at path as a document named filename in
+// reply to a specific message, and returns the sent message. The file is
+// streamed from disk, and read again if the request is retried.
+func (t Telegram) SendDocumentReply(ctx context.Context, msg *TelegramMessage, path, filename, caption string) (*TelegramMessage, error) {
+	return t.send(ctx, func() (*models.Message, error) {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer func() { _ = file.Close() }()
+
+		return t.SendDocument(ctx, &tgbotapi.SendDocumentParams{
+			ChatID:          msg.Chat.ID,
+			MessageThreadID: msg.MessageThreadID,
+			ReplyParameters: &models.ReplyParameters{MessageID: msg.ID},
+			Document:        &models.InputFileUpload{Filename: filename, Data: file},
+			Caption:         caption,
+		})
+	})
+}
 
-	return nil
+// SendReply sends a reply to a specific message, and returns the sent
+// message.
+func (t Telegram) SendReply(ctx context.Context, msg *TelegramMessage, text string) (*TelegramMessage, error) {
+	return t.SendReplyWithKeyboard(ctx, msg, text, nil)
 }
 
-// SendReply sends a reply to a specific message.
-func (t Telegram) SendReply(ctx context.Context, msg *TelegramMessage, text string) error {
+// SendReplyWithKeyboard sends a reply to a specific message with inline
+// keyboard buttons, and returns the sent message. The keyboard may be nil.
+func (t Telegram) SendReplyWithKeyboard(ctx context.Context, msg *TelegramMessage, text string, keyboard *models.InlineKeyboardMarkup) (*TelegramMessage, error) {
 	params := &tgbotapi.SendMessageParams{
 		ChatID:          msg.Chat.ID,
 		MessageThreadID: msg.MessageThreadID,
 		ReplyParameters: &models.ReplyParameters{MessageID: msg.ID},
 		Text:            text,
 	}
+	if keyboard != nil {
+		params.ReplyMarkup = keyboard
+	}
+
+	return t.send(ctx, func() (*models.Message, error) {
+		return t.SendMessage(ctx, params)
+	})
+}
+
+// EditTextWithKeyboard replaces the text and the inline keyboard buttons of a
+// message sent by the bot. A nil keyboard removes the buttons.
+func (t Telegram) EditTextWithKeyboard(ctx context.Context, msg *TelegramMessage, text string, keyboard *models.InlineKeyboardMarkup) error {
+	params := &tgbotapi.EditMessageTextParams{
+		ChatID:    msg.Chat.ID,
+		MessageID: msg.ID,
+		Text:      text,
+	}
+	if keyboard != nil {
+		params.ReplyMarkup = keyboard
+	}
+
+	return withRetry(ctx, t.retries, func() error {
+		_, err := t.EditMessageText(ctx, params)
+		return err
+	})
+}
+
+// EditKeyboard replaces the inline keyboard buttons of a message sent by the
+// bot. A nil keyboard removes the buttons.
+func (t Telegram) EditKeyboard(ctx context.Context, msg *TelegramMessage, keyboard *models.InlineKeyboardMarkup) error {
+	params := &tgbotapi.EditMessageReplyMarkupParams{
+		ChatID:    msg.Chat.ID,
+		MessageID: msg.ID,
+	}
+	if keyboard != nil {
+		params.ReplyMarkup = keyboard
+	}
+
+	return withRetry(ctx, t.retries, func() error {
+		_, err := t.EditMessageReplyMarkup(ctx, params)
+		return err
+	})
+}
+
+// AnswerCallback answers a callback query from an inline keyboard button,
+// showing the text as a notification if it's not empty.
+func (t Telegram) AnswerCallback(ctx context.Context, callbackID, text string) error {
+	params := &tgbotapi.AnswerCallbackQueryParams{
+		CallbackQueryID: callbackID,
+		Text:            text,
+	}
 
-	if _, err := t.SendMessage(ctx, params); err != nil {
+	return withRetry(ctx, t.retries, func() error {
+		_, err := t.AnswerCallbackQuery(ctx, params)
 		return err
+	})
+}
+
+// AnswerInline answers an inline query with the results, which Telegram caches
+// for cacheTime seconds for the user. Telegram caches them for 5 minutes when
+// the cache time is omitted, so it's at least 1 second. An empty next offset
+// means there are no more results.
+func (t Telegram) AnswerInline(ctx context.Context, queryID string, results []models.InlineQueryResult, nextOffset string, cacheTime int) error {
+	params := &tgbotapi.AnswerInlineQueryParams{
+		InlineQueryID: queryID,
+		Results:       results,
+		CacheTime:     max(cacheTime, 1),
+		IsPersonal:    true,
+		NextOffset:    nextOffset,
 	}
 
-	return nil
+	return withRetry(ctx, t.retries, func() error {
+		_, err := t.AnswerInlineQuery(ctx, params)
+		return err
+	})
+}
+
+// EditText replaces the text of a message sent by the bot, removing its inline
+// keyboard buttons if any.
+func (t Telegram) EditText(ctx context.Context, msg *TelegramMessage, text string) error {
+	return t.EditTextWithKeyboard(ctx, msg, text, nil)
+}
+
+// EditCaption replaces the caption of a photo sent by the bot.
+func (t Telegram) EditCaption(ctx context.Context, msg *TelegramMessage, caption string) error {
+	params := &tgbotapi.EditMessageCaptionParams{
+		ChatID:    msg.Chat.ID,
+		MessageID: msg.ID,
+		Caption:   caption,
+	}
+
+	return withRetry(ctx, t.retries, func() error {
+		_, err := t.EditMessageCaption(ctx, params)
+		return err
+	})
+}
+
+// send calls fn with retries, and returns the sent message.
+func (t Telegram) send(ctx context.Context, fn func() (*models.Message, error)) (*TelegramMessage, error) {
+	var sent *models.Message
+	err := withRetry(ctx, t.retries, func() error {
+		var err error
+		sent, err = fn()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return (*TelegramMessage)(sent), nil
 }
 
 // DeleteOriginalMessage deletes the original message from the user's chat.
@@ -107,16 +298,20 @@ func (t Telegram) DeleteOriginalMessage(ctx context.Context, msg *TelegramMessag
 		MessageID: msg.ID,
 	}
 
-	if _, err := t.DeleteMessage(ctx, params); err != nil {
+	return withRetry(ctx, t.retries, func() error {
+		_, err := t.DeleteMessage(ctx, params)
 		return err
-	}
-
-	return nil
+	})
 }
 
 // GetFileURL returns the download URL for a given file ID.
 func (t Telegram) GetFileURL(ctx context.Context, fileID string) (string, error) {
-	file, err := t.GetFile(ctx, &tgbotapi.GetFileParams{FileID: fileID})
+	var file *models.File
+	err := withRetry(ctx, t.retries, func() error {
+		var err error
+		file, err = t.GetFile(ctx, &tgbotapi.GetFileParams{FileID: fileID})
+		return err
+	})
 	if err != nil {
 		return "", err
 	}
@@ -0,0 +1,168 @@
+package karakeepbot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/go-telegram/bot/models"
+)
+
+// TelegramExportFile is the name of the JSON file produced by Telegram Desktop
+// when exporting chat history in machine-readable format.
+const TelegramExportFile = "result.json"
+
+// TelegramExport represents a Telegram Desktop JSON export. It supports both
+// single chat exports and full account exports (which nest chats in a list).
+type TelegramExport struct {
+	Dir   string               // Directory containing the export.
+	Chats []TelegramExportChat // Chats included in the export.
+}
+
+// TelegramExportChat represents a single chat in a Telegram Desktop export.
+type TelegramExportChat struct {
+	ID       int64                   `json:"id"`
+	Name     string                  `json:"name"`
+	Type     string                  `json:"type"`
+	Messages []TelegramExportMessage `json:"messages"`
+}
+
+// TelegramExportMessage represents a message in a Telegram Desktop export.
+type TelegramExportMessage struct {
+	ID            int64                  `json:"id"`
+	Type          string                 `json:"type"`
+	DateUnixtime  string                 `json:"date_unixtime"`
+	From          string                 `json:"from"`
+	ForwardedFrom string                 `json:"forwarded_from"`
+	SavedFrom     string                 `json:"saved_from"`
+	Photo         string                 `json:"photo"`
+	File          string                 `json:"file"`
+	MimeType      string                 `json:"mime_type"`
+	TextEntities  []TelegramExportEntity `json:"text_entities"`
+}
+
+// TelegramExportEntity represents a piece of formatted text in a Telegram
+// Desktop export.
+type TelegramExportEntity struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+	Href string `json:"href"`
+}
+
+// LoadTelegramExport reads and parses the Telegram Desktop export found in dir.
+func LoadTelegramExport(dir string) (*TelegramExport, error) {
+	data, err := os.ReadFile(filepath.Join(dir, TelegramExportFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read export: %w", err)
+	}
+
+	var raw struct {
+		TelegramExportChat
+		Chats *struct {
+			List []TelegramExportChat `json:"list"`
+		} `json:"chats"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse export: %w", err)
+	}
+
+	export := &TelegramExport{Dir: dir}
+	switch {
+	case raw.Chats != nil:
+		export.Chats = raw.Chats.List
+	case raw.Messages != nil:
+		export.Chats = []TelegramExportChat{raw.TelegramExportChat}
+	default:
+		return nil, errors.New("export doesn't contain any chat")
+	}
+
+	return export, nil
+}
+
+// Text returns the plain text of the message.
+func (m TelegramExportMessage) Text() string {
+	var b strings.Builder
+	for _, entity := range m.TextEntities {
+		b.WriteString(entity.Text)
+	}
+	return b.String()
+}
+
+// HasPhoto reports whether the message contains a photo included in the
+// export. Telegram Desktop keeps the "photo" key with a placeholder text when
+// media downloading is disabled.
+func (m TelegramExportMessage) HasPhoto() bool {
+	return m.Photo != "" && !strings.HasPrefix(m.Photo, "(")
+}
+
+// PhotoPath returns the absolute path to the message photo within dir.
+func (m TelegramExportMessage) PhotoPath(dir string) string {
+	return filepath.Join(dir, filepath.FromSlash(m.Photo))
+}
+
+// ToTelegramMessage converts the exported message into a TelegramMessage, so
+// it can be processed by the same logic used for live messages. Photos are not
+// converted, as they are read from the export directory instead of Telegram
+// servers.
+func (m TelegramExportMessage) ToTelegramMessage(chat TelegramExportChat) TelegramMessage {
+	msg := TelegramMessage{
+		ID:   int(m.ID),
+		Chat: models.Chat{ID: chat.ID, Title: chat.Name},
+		From: &models.User{FirstName: m.From},
+	}
+
+	if date, err := strconv.ParseInt(m.DateUnixtime, 10, 64); err == nil {
+		msg.Date = int(date)
+	}
+
+	if origin := m.forwardOrigin(); origin != "" {
+		msg.ForwardOrigin = &models.MessageOrigin{
+			Type: models.MessageOriginTypeHiddenUser,
+			MessageOriginHiddenUser: &models.MessageOriginHiddenUser{
+				Type:           models.MessageOriginTypeHiddenUser,
+				Date:           msg.Date,
+				SenderUserName: origin,
+			},
+		}
+	}
+
+	// Rebuild text and text_link entities. Offsets are measured in UTF-16 code
+	// units, as in the Telegram Bot API.
+	var text strings.Builder
+	offset := 0
+	for _, entity := range m.TextEntities {
+		length := len(utf16.Encode([]rune(entity.Text)))
+		if entity.Type == string(models.MessageEntityTypeTextLink) && entity.Href != "" {
+			msg.Entities = append(msg.Entities, models.MessageEntity{
+				Type:   models.MessageEntityTypeTextLink,
+				Offset: offset,
+				Length: length,
+				URL:    entity.Href,
+			})
+		}
+		text.WriteString(entity.Text)
+		offset += length
+	}
+
+	if m.HasPhoto() {
+		msg.Caption = text.String()
+	} else {
+		msg.Text = text.String()
+	}
+
+	return msg
+}
+
+// forwardOrigin returns the display name of the original author for forwarded
+// or saved messages.
+func (m TelegramExportMessage) forwardOrigin() string {
+	if m.ForwardedFrom != "" {
+		return m.ForwardedFrom
+	}
+	return m.SavedFrom
+}
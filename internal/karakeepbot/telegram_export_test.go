@@ -0,0 +1,188 @@
+package karakeepbot
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Madh93/karakeepbot/internal/config"
+	"github.com/Madh93/karakeepbot/internal/logging"
+	"github.com/Madh93/karakeepbot/internal/urlcleaner"
+)
+
+const testExport = `{
+  "name": "Saved Messages",
+  "type": "saved_messages",
+  "id": 42,
+  "messages": [
+    {
+      "id": 1,
+      "type": "service",
+      "date_unixtime": "1700000000",
+      "text_entities": []
+    },
+    {
+      "id": 2,
+      "type": "message",
+      "date_unixtime": "1700000100",
+      "from": "Alice",
+      "text_entities": [{"type": "plain", "text": "Just a note #ideas"}]
+    },
+    {
+      "id": 3,
+      "type": "message",
+      "date_unixtime": "1700000200",
+      "from": "Alice",
+      "forwarded_from": "Go Weekly",
+      "text_entities": [
+        {"type": "plain", "text": "Read the 🚀 "},
+        {"type": "text_link", "text": "release notes", "href": "https://go.dev/doc/go1.24?utm_source=tg"}
+      ]
+    },
+    {
+      "id": 4,
+      "type": "message",
+      "date_unixtime": "1700000300",
+      "from": "Alice",
+      "file": "files/report.pdf",
+      "mime_type": "application/pdf",
+      "text_entities": []
+    }
+  ]
+}`
+
+// writeTestExport writes the test export into a temporary directory.
+func writeTestExport(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, TelegramExportFile), []byte(testExport), 0600); err != nil {
+		t.Fatalf("failed to write export: %v", err)
+	}
+	return dir
+}
+
+func TestLoadTelegramExport(t *testing.T) {
+	t.Run("single chat export", func(t *testing.T) {
+		export, err := LoadTelegramExport(writeTestExport(t))
+		if err != nil {
+			t.Fatalf("LoadTelegramExport() returned an unexpected error: %v", err)
+		}
+		if len(export.Chats) != 1 || len(export.Chats[0].Messages) != 4 {
+			t.Fatalf("expected 1 chat with 4 messages, got %+v", export.Chats)
+		}
+	})
+
+	t.Run("full account export", func(t *testing.T) {
+		dir := t.TempDir()
+		data := `{"chats": {"list": [` + testExport + `, {"id": 7, "name": "Other", "messages": []}]}}`
+		if err := os.WriteFile(filepath.Join(dir, TelegramExportFile), []byte(data), 0600); err != nil {
+			t.Fatalf("failed to write export: %v", err)
+		}
+		export, err := LoadTelegramExport(dir)
+		if err != nil {
+			t.Fatalf("LoadTelegramExport() returned an unexpected error: %v", err)
+		}
+		if len(export.Chats) != 2 {
+			t.Fatalf("expected 2 chats, got %d", len(export.Chats))
+		}
+	})
+
+	t.Run("missing export", func(t *testing.T) {
+		if _, err := LoadTelegramExport(t.TempDir()); err == nil {
+			t.Error("expected an error for a missing export, got nil")
+		}
+	})
+}
+
+func TestTelegramExportMessage_ToTelegramMessage(t *testing.T) {
+	export, err := LoadTelegramExport(writeTestExport(t))
+	if err != nil {
+		t.Fatalf("LoadTelegramExport() returned an unexpected error: %v", err)
+	}
+	chat := export.Chats[0]
+	msg := chat.Messages[2].ToTelegramMessage(chat)
+
+	if msg.Text != "Read the 🚀 release notes" {
+		t.Errorf("unexpected text: %q", msg.Text)
+	}
+	if msg.Date != 1700000200 {
+		t.Errorf("unexpected date: %d", msg.Date)
+	}
+	if got := msg.ExtractURL(); got != "https://go.dev/doc/go1.24?utm_source=tg" {
+		t.Errorf("unexpected URL: %q", got)
+	}
+	// The rocket emoji takes two UTF-16 code units.
+	if entity := msg.Entities[0]; entity.Offset != 12 || entity.Length != 13 {
+		t.Errorf("unexpected entity offsets: %+v", entity)
+	}
+	note := msg.ContextNote()
+	if !strings.Contains(note, "✍️ Go Weekly") || !strings.Contains(note, "💬 Saved Messages") {
+		t.Errorf("context note doesn't preserve the origin: %q", note)
+	}
+}
+
+func TestImportTelegramExportDryRun(t *testing.T) {
+	dir := writeTestExport(t)
+	cleaner, err := urlcleaner.New(&config.URLCleanerConfig{Enabled: true})
+	if err != nil {
+		t.Fatalf("urlcleaner.New() returned an unexpected error: %v", err)
+	}
+	importer := &Importer{
+		kb: &KarakeepBot{
+			urlCleaner: cleaner,
+			logger:     logging.New(&config.LoggingConfig{Level: "error", Output: "stderr"}),
+		},
+		maxsize: 1024,
+	}
+	importer.kb.settings.Store(newSettings(&config.DefaultConfig))
+
+	var out bytes.Buffer
+	err = importer.ImportTelegramExport(context.Background(), dir, ImportOptions{DryRun: true, Output: &out})
+	if err != nil {
+		t.Fatalf("ImportTelegramExport() returned an unexpected error: %v", err)
+	}
+
+	for _, expected := range []string{
+		"[2/4] Would create TextBookmark",
+		"[3/4] Would create LinkBookmark (URL: https://go.dev/doc/go1.24)",
+		"[4/4] Skipped message 4",
+		"Done: 2 imported, 2 skipped, 4 total",
+	} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("expected output to contain %q, got:\n%s", expected, out.String())
+		}
+	}
+
+	// Dry runs must not create a checkpoint.
+	if _, err := os.Stat(filepath.Join(dir, DefaultCheckpointFile)); !os.IsNotExist(err) {
+		t.Errorf("expected no checkpoint file after a dry run, got: %v", err)
+	}
+}
+
+func TestImportCheckpoint(t *testing.T) {
+	path := filepath.Join(t.TempDir(), DefaultCheckpointFile)
+
+	checkpoint, err := loadCheckpoint(path)
+	if err != nil {
+		t.Fatalf("loadCheckpoint() returned an unexpected error: %v", err)
+	}
+	if len(checkpoint.Chats) != 0 {
+		t.Errorf("expected an empty checkpoint, got %+v", checkpoint)
+	}
+
+	checkpoint.Chats[42] = 3
+	if err := saveCheckpoint(path, checkpoint); err != nil {
+		t.Fatalf("saveCheckpoint() returned an unexpected error: %v", err)
+	}
+
+	loaded, err := loadCheckpoint(path)
+	if err != nil {
+		t.Fatalf("loadCheckpoint() returned an unexpected error: %v", err)
+	}
+	if loaded.Chats[42] != 3 {
+		t.Errorf("expected last message ID 3 for chat 42, got %+v", loaded.Chats)
+	}
+}
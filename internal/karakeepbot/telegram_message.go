@@ -10,8 +10,8 @@ import (
 )
 
 // hashtagRegexp matches Telegram-style hashtags, capturing the tag name
-// without the leading '#'. Supports unicode letters and digits.
-var hashtagRegexp = regexp.MustCompile(`#([\p{L}\p{N}_]+)`)
+// without the leading '#'. Supports unicode letters, marks and digits.
+var hashtagRegexp = regexp.MustCompile(`#([\p{L}\p{M}\p{N}_]+)`)
 
 // TelegramUpdate is an alias for models.Update.
 type TelegramUpdate = models.Update
@@ -0,0 +1,47 @@
+package karakeepbot
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	tgbotapi "github.com/go-telegram/bot"
+)
+
+func TestWithRetry(t *testing.T) {
+	tooManyRequests := &tgbotapi.TooManyRequestsError{Message: "Too Many Requests", RetryAfter: 0}
+	otherErr := errors.New("bad request")
+
+	tests := []struct {
+		name          string
+		retries       int
+		errs          []error // Errors returned by each call, nil once exhausted.
+		expectedCalls int
+		expected      bool
+	}{
+		{"Success", 3, nil, 1, true},
+		{"Retried after too many requests", 3, []error{tooManyRequests, tooManyRequests}, 3, true},
+		{"Retries exhausted", 2, []error{tooManyRequests, tooManyRequests, tooManyRequests, tooManyRequests}, 3, false},
+		{"No retries", 0, []error{tooManyRequests}, 1, false},
+		{"Other errors are not retried", 3, []error{otherErr}, 1, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			err := withRetry(context.Background(), tt.retries, func() error {
+				calls++
+				if calls <= len(tt.errs) {
+					return tt.errs[calls-1]
+				}
+				return nil
+			})
+			if (err == nil) != tt.expected {
+				t.Errorf("Expected success: %v, but got error: %v", tt.expected, err)
+			}
+			if calls != tt.expectedCalls {
+				t.Errorf("Expected %d calls, got %d", tt.expectedCalls, calls)
+			}
+		})
+	}
+}
@@ -0,0 +1,194 @@
+package karakeepbot
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/Madh93/go-karakeep"
+	"github.com/Madh93/karakeepbot/internal/config"
+	"github.com/Madh93/karakeepbot/internal/state"
+	"github.com/go-telegram/bot/models"
+)
+
+// watchesState is the name of the state document with the messages waiting
+// for the AI tags of their bookmark.
+const watchesState = "watches"
+
+// tagWatch is a message sent by the bot that is edited once the AI tags of its
+// bookmark arrive.
+type tagWatch struct {
+	BookmarkID string    `json:"bookmark_id"`
+	ChatID     int64     `json:"chat_id"`
+	ThreadID   int       `json:"thread_id,omitempty"`
+	MessageID  int       `json:"message_id"`
+	ReplyMode  string    `json:"reply_mode"`
+	Caption    bool      `json:"caption"`            // Whether the hashtags are in the caption of a photo.
+	Original   string    `json:"original"`           // Original text or caption of the message.
+	Hashtags   string    `json:"hashtags,omitempty"` // Hashtags known when the watch started.
+	Text       string    `json:"text"`               // Text currently shown.
+	Until      time.Time `json:"until"`              // When to stop watching.
+}
+
+// newTagWatch returns the watch for the message sent in response to msg, with
+// the current hashtags of the bookmark and the watch window of the chat
+// settings.
+func newTagWatch(msg TelegramMessage, sent *TelegramMessage, bookmarkID, hashtags string, s chatSettings) tagWatch {
+	return tagWatch{
+		BookmarkID: bookmarkID,
+		ChatID:     sent.Chat.ID,
+		ThreadID:   msg.MessageThreadID,
+		MessageID:  sent.ID,
+		ReplyMode:  s.replyMode,
+		Caption:    msg.Photo != nil && s.replyMode == config.ReplyModeReplace,
+		Original:   originalText(msg),
+		Hashtags:   hashtags,
+		Text:       hashtagsText(originalText(msg), hashtags, s.replyMode, true),
+		Until:      time.Now().Add(s.watchWindow),
+	}
+}
+
+// key returns the unique key of the watched message.
+func (w tagWatch) key() string {
+	return fmt.Sprintf("%d/%d", w.ChatID, w.MessageID)
+}
+
+// message returns the watched message.
+func (w tagWatch) message() *TelegramMessage {
+	return &TelegramMessage{ID: w.MessageID, Chat: models.Chat{ID: w.ChatID}}
+}
+
+// Attrs returns a slice of logging attributes for the watch.
+func (w tagWatch) Attrs() []any {
+	return []any{
+		"scope", "watcher",
+		"bookmark_id", w.BookmarkID,
+		"chat_id", w.ChatID,
+		"message_id", w.MessageID,
+		"until", w.Until,
+	}
+}
+
+// tagWatches holds the messages waiting for AI tags, which are persisted in
+// the state store so they survive restarts.
+type tagWatches struct {
+	mu      sync.Mutex
+	store   *state.Store
+	watches map[string]tagWatch
+}
+
+// newTagWatches loads the messages waiting for AI tags from the state store.
+func newTagWatches(store *state.Store) (*tagWatches, error) {
+	watches := make(map[string]tagWatch)
+	if err := store.Load(watchesState, &watches); err != nil {
+		return nil, err
+	}
+	return &tagWatches{store: store, watches: watches}, nil
+}
+
+// all returns the watched messages, sorted by expiration.
+func (tw *tagWatches) all() []tagWatch {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	return slices.SortedFunc(maps.Values(tw.watches), func(a, b tagWatch) int { return a.Until.Compare(b.Until) })
+}
+
+// add starts watching a message and persists it.
+func (tw *tagWatches) add(w tagWatch) error {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	tw.watches[w.key()] = w
+	if err := tw.store.Save(watchesState, tw.watches); err != nil {
+		delete(tw.watches, w.key())
+		return err
+	}
+	return nil
+}
+
+// remove stops watching a message and persists it.
+func (tw *tagWatches) remove(w tagWatch) error {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if _, ok := tw.watches[w.key()]; !ok {
+		return nil
+	}
+	delete(tw.watches, w.key())
+	return tw.store.Save(watchesState, tw.watches)
+}
+
+// watchTags persists the watch and starts watching the bookmark in the
+// background, until ctx is done.
+func (kb *KarakeepBot) watchTags(ctx context.Context, w tagWatch) {
+	if err := kb.watches.add(w); err != nil {
+		// Keep watching, it just won't survive a restart.
+		kb.logger.Error("Failed to persist message waiting for tags", append(w.Attrs(), "error", err)...)
+	}
+	kb.logger.Debug("Watching bookmark for tags", w.Attrs()...)
+	go kb.runWatch(ctx, w)
+}
+
+// resumeWatches starts watching the bookmarks persisted in a previous run, until
+// ctx is done.
+func (kb *KarakeepBot) resumeWatches(ctx context.Context) {
+	watches := kb.watches.all()
+	if len(watches) > 0 {
+		kb.logger.Info(fmt.Sprintf("Resuming %d messages waiting for tags", len(watches)))
+	}
+	for _, w := range watches {
+		go kb.runWatch(ctx, w)
+	}
+}
+
+// runWatch waits for the AI tags of the watched bookmark, until the watch
+// expires, and edits the message once they arrive. If tagging fails, the
+// message shows the hashtags known when the watch started instead. The watch
+// is kept if ctx is done first, so it's resumed on the next run.
+func (kb *KarakeepBot) runWatch(ctx context.Context, w tagWatch) {
+	settings := kb.settings.Load()
+	s := settings.settingsFor(w.ChatID, w.ThreadID)
+	s.tagTimeout = max(time.Until(w.Until), 0)
+
+	bookmark, err := kb.waitForTagCompletion(ctx, &KarakeepBookmark{Id: w.BookmarkID}, s)
+	if ctx.Err() != nil {
+		return
+	}
+	defer func() {
+		if err := kb.watches.remove(w); err != nil {
+			kb.logger.Error("Failed to remove message waiting for tags", append(w.Attrs(), "error", err)...)
+		}
+	}()
+
+	hashtags := w.Hashtags
+	switch {
+	case err != nil:
+		kb.logger.Error("Failed to wait for bookmark tagging", append(w.Attrs(), "error", err)...)
+	case bookmark.taggingStatus() != karakeep.BookmarkTaggingStatusSuccess:
+		kb.logger.Info("Stopped watching bookmark: tagging did not complete within the watch window", w.Attrs()...)
+		fallthrough
+	default:
+		kb.recordTagged(bookmark)
+		hashtags = bookmark.Hashtags(settings.hashtags)
+	}
+	text := hashtagsText(w.Original, hashtags, w.ReplyMode, false)
+	if text == w.Text {
+		kb.logger.Debug("No new tags, keeping the message", w.Attrs()...)
+		return
+	}
+
+	if w.Caption {
+		err = kb.telegram.EditCaption(ctx, w.message(), text)
+	} else {
+		err = kb.telegram.EditText(ctx, w.message(), text)
+	}
+	if err != nil {
+		kb.logger.Error("Failed to edit message with tags", append(w.Attrs(), "error", err)...)
+		return
+	}
+
+	kb.logger.Info("Edited message with tags", w.Attrs()...)
+}
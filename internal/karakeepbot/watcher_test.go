@@ -0,0 +1,173 @@
+package karakeepbot
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Madh93/karakeepbot/internal/config"
+	"github.com/Madh93/karakeepbot/internal/hashtag"
+	"github.com/Madh93/karakeepbot/internal/scheduler"
+	"github.com/Madh93/karakeepbot/internal/state"
+	tgbotapi "github.com/go-telegram/bot"
+)
+
+// newTestTelegram returns a Telegram client connected to a server that records
+// the methods called and their text, followed by the names of the documents.
+func newTestTelegram(t *testing.T) (*Telegram, func() []string) {
+	t.Helper()
+
+	var mu sync.Mutex
+	var calls []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseMultipartForm(1 << 20)
+		method := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+		call := method + ": " + r.FormValue("text") + r.FormValue("caption")
+		if r.MultipartForm != nil {
+			for _, file := range r.MultipartForm.File["document"] {
+				call += " (" + file.Filename + ")"
+			}
+		}
+		mu.Lock()
+		calls = append(calls, call)
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"ok": true, "result": map[string]any{"message_id": 1, "chat": map[string]any{"id": 1}}})
+	}))
+	t.Cleanup(server.Close)
+
+	bot, err := tgbotapi.New("123456789:ABCdefGhIJKlmnOPqrsTUVwxyz012345678", tgbotapi.WithServerURL(server.URL), tgbotapi.WithSkipGetMe())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &Telegram{Bot: bot}, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return calls
+	}
+}
+
+func TestTagWatches_persistence(t *testing.T) {
+	dir := t.TempDir()
+	store, err := state.New(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	watches, err := newTagWatches(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := tagWatch{BookmarkID: "bookmark", ChatID: 1, MessageID: 2, Until: time.Now().Add(time.Hour).Round(0)}
+	if err := watches.add(w); err != nil {
+		t.Fatalf("add() returned an unexpected error: %v", err)
+	}
+
+	// Reload the watches, as after a restart.
+	reloaded, err := newTagWatches(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if all := reloaded.all(); len(all) != 1 || all[0].BookmarkID != "bookmark" || !all[0].Until.Equal(w.Until) {
+		t.Fatalf("Expected the watch to be persisted, got %+v", all)
+	}
+
+	if err := reloaded.remove(w); err != nil {
+		t.Fatalf("remove() returned an unexpected error: %v", err)
+	}
+	if reloaded, _ = newTagWatches(store); len(reloaded.all()) != 0 {
+		t.Errorf("Expected the watch to be removed, got %+v", reloaded.all())
+	}
+}
+
+func TestKarakeepBot_runWatch(t *testing.T) {
+	status := func(s string) *string { return &s }
+
+	tests := []struct {
+		name          string
+		statuses      []*string
+		watch         tagWatch
+		expectedCalls []string
+	}{
+		{
+			name:          "Edits the text when tags arrive",
+			statuses:      []*string{status("pending"), status("success")},
+			watch:         tagWatch{ReplyMode: "replace", Original: "https://go.dev", Text: "https://go.dev\n\n"},
+			expectedCalls: []string{"editMessageText: https://go.dev\n\n🤖 #golang"},
+		},
+		{
+			name:          "Edits the caption of photos",
+			statuses:      []*string{status("success")},
+			watch:         tagWatch{ReplyMode: "replace", Caption: true, Original: "A gopher", Text: "A gopher\n\n"},
+			expectedCalls: []string{"editMessageCaption: A gopher\n\n🤖 #golang"},
+		},
+		{
+			name:          "Replaces the placeholder when tagging times out",
+			statuses:      []*string{status("pending")},
+			watch:         tagWatch{ReplyMode: "reply", Text: "⏳ Waiting for tags..."},
+			expectedCalls: []string{"editMessageText: 🏷️ No tags"},
+		},
+		{
+			name:          "Shows the known tags when tagging fails",
+			statuses:      []*string{status("failure")},
+			watch:         tagWatch{ReplyMode: "reply", Hashtags: "#go", Text: "⏳ Waiting for tags..."},
+			expectedCalls: []string{"editMessageText: #go"},
+		},
+		{
+			name:          "Replaces the placeholder when tagging fails",
+			statuses:      []*string{status("failure")},
+			watch:         tagWatch{ReplyMode: "reply", Text: "⏳ Waiting for tags..."},
+			expectedCalls: []string{"editMessageText: 🏷️ No tags"},
+		},
+		{
+			name:          "Keeps the message without new tags",
+			statuses:      []*string{status("pending")},
+			watch:         tagWatch{ReplyMode: "replace", Original: "text", Text: "text\n\n"},
+			expectedCalls: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kb, _ := newTestKarakeep(t, tt.statuses...)
+			telegram, calls := newTestTelegram(t)
+			store, _ := state.New("")
+			kb.telegram = telegram
+			kb.state = store
+			kb.clock = scheduler.SystemClock
+			kb.watches, _ = newTagWatches(store)
+			kb.settings.Store(&settings{defaults: chatSettings{waitInterval: time.Millisecond, maxInterval: time.Millisecond}, hashtags: hashtag.New(&config.DefaultConfig.Hashtags)})
+
+			w := tt.watch
+			w.BookmarkID, w.ChatID, w.MessageID = "bookmark", 1, 2
+			w.Until = time.Now().Add(50 * time.Millisecond)
+			if err := kb.watches.add(w); err != nil {
+				t.Fatal(err)
+			}
+
+			kb.runWatch(context.Background(), w)
+
+			got := calls()
+			if strings.Join(got, "|") != strings.Join(tt.expectedCalls, "|") {
+				t.Errorf("Expected calls %q, got %q", tt.expectedCalls, got)
+			}
+			var tagged []string
+			_ = store.Scan(eventsLog, func(record []byte) error {
+				tagged = append(tagged, string(record))
+				return nil
+			})
+			if expected := *tt.statuses[len(tt.statuses)-1] == "success"; (len(tagged) == 1) != expected {
+				t.Errorf("Expected the tags to be recorded: %v, got %q", expected, tagged)
+			}
+			if len(kb.watches.all()) != 0 {
+				t.Error("Expected the watch to be removed")
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package karakeepbot
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Madh93/karakeepbot/internal/config"
+)
+
+// maxWebhookBodySize is the maximum size of a webhook event body.
+const maxWebhookBodySize = 64 << 10
+
+// karakeepEvent is an event posted by a Karakeep webhook.
+type karakeepEvent struct {
+	JobID      string `json:"jobId"`
+	BookmarkID string `json:"bookmarkId"`
+	UserID     string `json:"userId"`
+	URL        string `json:"url"`
+	Type       string `json:"type"`
+	Operation  string `json:"operation"` // E.g. "created", "crawled", "ai tagged", "edited" or "deleted".
+}
+
+// Attrs returns a slice of logging attributes for the event.
+func (e karakeepEvent) Attrs() []any {
+	return []any{
+		"scope", "webhook",
+		"bookmark_id", e.BookmarkID,
+		"operation", e.Operation,
+	}
+}
+
+// tagEvents notifies the goroutines waiting for the tags of a bookmark when
+// Karakeep reports a change in it.
+type tagEvents struct {
+	mu          sync.Mutex
+	subscribers map[string][]chan struct{}
+	stopped     bool // Whether the webhook server stopped, so no more events arrive.
+}
+
+// newTagEvents creates a new tagEvents.
+func newTagEvents() *tagEvents {
+	return &tagEvents{subscribers: make(map[string][]chan struct{})}
+}
+
+// subscribe returns a channel that receives a value when the bookmark changes,
+// and a function to stop receiving them. The channel is nil once the events
+// are stopped.
+func (e *tagEvents) subscribe(bookmarkID string) (<-chan struct{}, func()) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.stopped {
+		return nil, func() {}
+	}
+
+	ch := make(chan struct{}, 1)
+	e.subscribers[bookmarkID] = append(e.subscribers[bookmarkID], ch)
+
+	return ch, func() {
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		subscribers := e.subscribers[bookmarkID]
+		for i, subscriber := range subscribers {
+			if subscriber == ch {
+				subscribers = append(subscribers[:i], subscribers[i+1:]...)
+				break
+			}
+		}
+		if len(subscribers) == 0 {
+			delete(e.subscribers, bookmarkID)
+		} else {
+			e.subscribers[bookmarkID] = subscribers
+		}
+	}
+}
+
+// notify wakes up the subscribers of the bookmark. It returns the number of
+// subscribers notified.
+func (e *tagEvents) notify(bookmarkID string) int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, ch := range e.subscribers[bookmarkID] {
+		select {
+		case ch <- struct{}{}:
+		default: // Already notified.
+		}
+	}
+	return len(e.subscribers[bookmarkID])
+}
+
+// stop wakes up all the subscribers, so they check their bookmarks again, and
+// stops further subscriptions, as no more events will arrive.
+func (e *tagEvents) stop() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.stopped = true
+	for _, subscribers := range e.subscribers {
+		for _, ch := range subscribers {
+			select {
+			case ch <- struct{}{}:
+			default: // Already notified.
+			}
+		}
+	}
+}
+
+// serveWebhook listens for Karakeep webhook events until ctx is done.
+func (kb *KarakeepBot) serveWebhook(ctx context.Context, config *config.WebhookConfig) error {
+	listener, err := net.Listen("tcp", config.Listen)
+	if err != nil {
+		return fmt.Errorf("failed to listen for webhook events: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(config.Path, kb.webhookHandler(config.Token.Value()))
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+
+	go func() {
+		kb.logger.Info(fmt.Sprintf("Listening for Karakeep webhook events on %s%s", listener.Addr(), config.Path))
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			kb.logger.Error("Webhook server stopped, falling back to polling", "error", err)
+			kb.tagEvents.stop()
+		}
+	}()
+
+	return nil
+}
+
+// webhookHandler returns the HTTP handler for Karakeep webhook events. Events
+// must carry the shared token as a bearer token.
+func (kb *KarakeepBot) webhookHandler(token string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		received, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(received), []byte(token)) != 1 {
+			kb.logger.Warn("Rejected webhook event with an invalid token", "scope", "webhook", "remote_addr", r.RemoteAddr)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var event karakeepEvent
+		if err := json.NewDecoder(io.LimitReader(r.Body, maxWebhookBodySize)).Decode(&event); err != nil || event.BookmarkID == "" {
+			kb.logger.Warn("Rejected invalid webhook event", "scope", "webhook", "error", err)
+			http.Error(w, "invalid event", http.StatusBadRequest)
+			return
+		}
+
+		// Any change may complete the tagging, so the waiting goroutines check
+		// the bookmark again.
+		notified := kb.tagEvents.notify(event.BookmarkID)
+		kb.logger.Debug(fmt.Sprintf("Received webhook event, notified %d pending messages", notified), event.Attrs()...)
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
@@ -0,0 +1,162 @@
+package karakeepbot
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// postEvent posts a Karakeep webhook event to url, as Karakeep does.
+func postEvent(t *testing.T, url, token, body string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	return resp
+}
+
+func TestKarakeepBot_webhookHandler(t *testing.T) {
+	kb, _ := newTestKarakeep(t, nil)
+	kb.tagEvents = newTagEvents()
+	server := httptest.NewServer(kb.webhookHandler("secret"))
+	t.Cleanup(server.Close)
+
+	tests := []struct {
+		name           string
+		token          string
+		body           string
+		expectedStatus int
+	}{
+		{"Valid event", "secret", `{"bookmarkId":"bookmark","operation":"ai tagged"}`, http.StatusNoContent},
+		{"Invalid token", "wrong", `{"bookmarkId":"bookmark","operation":"ai tagged"}`, http.StatusUnauthorized},
+		{"Invalid body", "secret", `not json`, http.StatusBadRequest},
+		{"Missing bookmark", "secret", `{"operation":"ai tagged"}`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if resp := postEvent(t, server.URL, tt.token, tt.body); resp.StatusCode != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, resp.StatusCode)
+			}
+		})
+	}
+
+	t.Run("Only POST is allowed", func(t *testing.T) {
+		resp, err := http.Get(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusMethodNotAllowed {
+			t.Errorf("Expected status %d, got %d", http.StatusMethodNotAllowed, resp.StatusCode)
+		}
+	})
+}
+
+func TestKarakeepBot_waitForTagCompletionWithWebhook(t *testing.T) {
+	status := func(s string) *string { return &s }
+	kb, calls := newTestKarakeep(t, status("pending"), status("success"))
+	kb.tagEvents = newTagEvents()
+	server := httptest.NewServer(kb.webhookHandler("secret"))
+	t.Cleanup(server.Close)
+
+	// Karakeep posts the events once the bookmark is tagged, after the first
+	// retrieval.
+	go func() {
+		for calls.Load() == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		time.Sleep(20 * time.Millisecond)
+		for _, body := range []string{
+			`{"jobId":"1","bookmarkId":"other","operation":"ai tagged"}`,
+			`{"jobId":"2","bookmarkId":"bookmark","operation":"ai tagged"}`,
+		} {
+			req, _ := http.NewRequest(http.MethodPost, server.URL, strings.NewReader(body))
+			req.Header.Set("Authorization", "Bearer secret")
+			if resp, err := http.DefaultClient.Do(req); err == nil {
+				_ = resp.Body.Close()
+			}
+		}
+	}()
+
+	// Long intervals, so only the event can wake up the wait in time.
+	s := chatSettings{waitInterval: 10 * time.Second, maxInterval: 10 * time.Second, tagTimeout: 10 * time.Second}
+	start := time.Now()
+	bookmark, err := kb.waitForTagCompletion(context.Background(), &KarakeepBookmark{Id: "bookmark"}, s)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected the event to complete the wait, took %s", elapsed)
+	}
+	if hashtags := bookmark.Hashtags(kb.settings.Load().hashtags); hashtags != "🤖 #golang" {
+		t.Errorf("Expected hashtags %q, got %q", "🤖 #golang", hashtags)
+	}
+	if got := calls.Load(); got != 2 {
+		t.Errorf("Expected 2 calls to Karakeep, got %d", got)
+	}
+}
+
+func TestKarakeepBot_waitForTagCompletionWithLostEvents(t *testing.T) {
+	status := func(s string) *string { return &s }
+	kb, calls := newTestKarakeep(t, status("pending"), status("success"))
+	kb.tagEvents = newTagEvents()
+
+	// No event arrives, so Karakeep is polled again after the maximum
+	// interval.
+	s := chatSettings{waitInterval: time.Millisecond, maxInterval: 20 * time.Millisecond, tagTimeout: 10 * time.Second}
+	start := time.Now()
+	if _, err := kb.waitForTagCompletion(context.Background(), &KarakeepBookmark{Id: "bookmark"}, s); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected Karakeep to be polled, took %s", elapsed)
+	}
+	if got := calls.Load(); got != 2 {
+		t.Errorf("Expected 2 calls to Karakeep, got %d", got)
+	}
+}
+
+func TestTagEvents(t *testing.T) {
+	events := newTagEvents()
+
+	ch, unsubscribe := events.subscribe("bookmark")
+	if n := events.notify("bookmark"); n != 1 {
+		t.Errorf("Expected 1 subscriber notified, got %d", n)
+	}
+	events.notify("bookmark") // Doesn't block when already notified.
+	select {
+	case <-ch:
+	default:
+		t.Error("Expected a notification")
+	}
+
+	unsubscribe()
+	if n := events.notify("bookmark"); n != 0 {
+		t.Errorf("Expected no subscribers after unsubscribing, got %d", n)
+	}
+
+	ch, _ = events.subscribe("bookmark")
+	events.stop()
+	select {
+	case <-ch:
+	default:
+		t.Error("Expected a notification when stopping")
+	}
+	if ch, _ = events.subscribe("bookmark"); ch != nil {
+		t.Error("Expected no channel after stopping")
+	}
+}
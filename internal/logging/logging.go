@@ -27,6 +27,7 @@ import (
 // Logger represents an instance of the logging system.
 type Logger struct {
 	slogger *slog.Logger
+	level   *slog.LevelVar
 }
 
 // New creates a new Logger instance with the specified logging configuration.
@@ -37,13 +38,22 @@ func New(config *config.LoggingConfig) *Logger {
 		log.Fatalf("Couldn't parse logging output: %v", err)
 	}
 
+	// Set the level, which can be changed later with SetLevel
+	level := new(slog.LevelVar)
+	level.Set(parseLevel(config.Level))
+
 	// Setup the handler based on the format
-	handler, err := parseFormat(output, config)
+	handler, err := parseFormat(output, level, config)
 	if err != nil {
 		log.Fatalf("Couldn't setup logging handler: %v", err)
 	}
 
-	return &Logger{slogger: slog.New(handler)}
+	return &Logger{slogger: slog.New(handler), level: level}
+}
+
+// SetLevel changes the log level at runtime (e.g. on configuration reload).
+func (l *Logger) SetLevel(level string) {
+	l.level.Set(parseLevel(level))
 }
 
 // Debug logs a message at the debug level.
@@ -107,17 +117,17 @@ func parseLevel(s string) slog.Level {
 }
 
 // parseFormat takes a logging configuration and returns an slog.Handler.
-func parseFormat(output *os.File, config *config.LoggingConfig) (slog.Handler, error) {
+func parseFormat(output *os.File, level slog.Leveler, config *config.LoggingConfig) (slog.Handler, error) {
 	var handler slog.Handler
 
 	switch config.Format {
 	case "json":
 		handler = slog.NewJSONHandler(output, &slog.HandlerOptions{
-			Level: parseLevel(config.Level),
+			Level: level,
 		})
 	default:
 		handler = tint.NewHandler(output, &tint.Options{
-			Level:      parseLevel(config.Level),
+			Level:      level,
 			TimeFormat: time.DateTime,
 			NoColor:    !config.Colored,
 		})
@@ -0,0 +1,109 @@
+// Package ratelimit provides token bucket rate limiting by key.
+//
+// Each key (e.g. a Telegram user or chat ID) gets its own bucket, which holds
+// up to burst tokens and is refilled at a constant rate. Every allowed event
+// takes one token, and events are denied while the bucket is empty. Buckets
+// that are full again are pruned periodically to keep memory bounded.
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// pruneInterval is the minimum time between prunes of idle buckets.
+const pruneInterval = time.Minute
+
+// Limiter limits the rate of events for each key.
+type Limiter[K comparable] struct {
+	rate  float64 // Tokens added per second.
+	burst float64 // Maximum number of tokens.
+	now   func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[K]*bucket
+	lastPrune time.Time
+}
+
+// bucket holds the tokens available for a key.
+type bucket struct {
+	tokens   float64
+	updated  time.Time
+	notified bool // Whether the first denial since the last allowed event was already reported.
+}
+
+// New creates a new Limiter that allows rate events per period with bursts of
+// up to burst events, for each key.
+func New[K comparable](rate int, period time.Duration, burst int) *Limiter[K] {
+	return &Limiter[K]{
+		rate:    float64(rate) / period.Seconds(),
+		burst:   float64(max(burst, 1)),
+		now:     time.Now,
+		buckets: make(map[K]*bucket),
+	}
+}
+
+// Allow reports whether an event for key is allowed, taking a token if so.
+// When the event is denied, notify is true only for the first denial since
+// the last allowed event, so callers can warn users once instead of on every
+// event.
+func (l *Limiter[K]) Allow(key K) (allowed, notify bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.prune(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, updated: now}
+		l.buckets[key] = b
+	}
+	b.refill(now, l.rate, l.burst)
+
+	if b.tokens >= 1 {
+		b.tokens--
+		b.notified = false
+		return true, false
+	}
+
+	notify = !b.notified
+	b.notified = true
+	return false, notify
+}
+
+// Cancel gives back the token taken by an allowed event for key, e.g. when
+// the event is denied by another limiter afterwards.
+func (l *Limiter[K]) Cancel(key K) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if b, ok := l.buckets[key]; ok {
+		b.refill(l.now(), l.rate, l.burst)
+		b.tokens = min(l.burst, b.tokens+1)
+	}
+}
+
+// prune removes the buckets that are full again, as they behave like new
+// ones.
+func (l *Limiter[K]) prune(now time.Time) {
+	if now.Sub(l.lastPrune) < pruneInterval {
+		return
+	}
+	l.lastPrune = now
+
+	for key, b := range l.buckets {
+		b.refill(now, l.rate, l.burst)
+		if b.tokens >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// refill adds the tokens accumulated since the last update.
+func (b *bucket) refill(now time.Time, rate, burst float64) {
+	if elapsed := now.Sub(b.updated).Seconds(); elapsed > 0 {
+		b.tokens = min(burst, b.tokens+elapsed*rate)
+	}
+	b.updated = now
+}
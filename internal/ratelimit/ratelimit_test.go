@@ -0,0 +1,73 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiter_Allow(t *testing.T) {
+	now := time.Now()
+	l := New[int64](1, time.Second, 2) // 1 event per second, bursts of 2
+	l.now = func() time.Time { return now }
+
+	steps := []struct {
+		name    string
+		advance time.Duration
+		key     int64
+		allowed bool
+		notify  bool
+	}{
+		{"First event", 0, 1, true, false},
+		{"Burst", 0, 1, true, false},
+		{"First denial notifies", 0, 1, false, true},
+		{"Next denials don't notify", 0, 1, false, false},
+		{"Other keys have their own bucket", 0, 2, true, false},
+		{"Refilled after a second", time.Second, 1, true, false},
+		{"Denied again notifies again", 0, 1, false, true},
+	}
+
+	for _, step := range steps {
+		now = now.Add(step.advance)
+		allowed, notify := l.Allow(step.key)
+		if allowed != step.allowed || notify != step.notify {
+			t.Errorf("%s: expected (allowed: %v, notify: %v), got (%v, %v)", step.name, step.allowed, step.notify, allowed, notify)
+		}
+	}
+}
+
+func TestLimiter_prune(t *testing.T) {
+	now := time.Now()
+	l := New[int64](1, time.Second, 1)
+	l.now = func() time.Time { return now }
+
+	l.Allow(1)
+	now = now.Add(2 * pruneInterval)
+	l.Allow(2)
+
+	if _, ok := l.buckets[1]; ok {
+		t.Error("expected idle bucket to be pruned")
+	}
+	if _, ok := l.buckets[2]; !ok {
+		t.Error("expected active bucket to be kept")
+	}
+}
+
+func TestLimiter_Cancel(t *testing.T) {
+	now := time.Now()
+	l := New[int64](1, time.Minute, 1)
+	l.now = func() time.Time { return now }
+
+	l.Allow(1)
+	l.Cancel(1)
+	l.Cancel(2) // Unknown keys are ignored
+
+	if allowed, _ := l.Allow(1); !allowed {
+		t.Error("expected the cancelled token to be given back")
+	}
+	if allowed, _ := l.Allow(1); allowed {
+		t.Error("expected the bucket to be empty after the burst")
+	}
+	if _, ok := l.buckets[2]; ok {
+		t.Error("expected no bucket for unknown keys")
+	}
+}
@@ -0,0 +1,206 @@
+// Package scheduler runs jobs at the times given by their schedules, such as
+// cron expressions or one-off times.
+//
+// Jobs whose next run is already in the past when they are added run right
+// away, so jobs missed while the process was down are caught up on start.
+package scheduler
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock tells the time. It's injected in the scheduler so tests can control
+// the passing of time.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+// systemClock is the clock of the system.
+type systemClock struct{}
+
+func (systemClock) Now() time.Time                         { return time.Now() }
+func (systemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// SystemClock is the clock of the system.
+var SystemClock Clock = systemClock{}
+
+// Schedule returns when a job runs next.
+type Schedule interface {
+	// Next returns the first run strictly after t, or the zero time if the
+	// job doesn't run anymore.
+	Next(t time.Time) time.Time
+}
+
+// At is a schedule that runs once at the given time.
+type At time.Time
+
+// Next returns the time of the schedule if it's after t.
+func (a At) Next(t time.Time) time.Time {
+	if t.Before(time.Time(a)) {
+		return time.Time(a)
+	}
+	return time.Time{}
+}
+
+// In returns a schedule computing the next runs of s in the given time zone,
+// e.g. for cron expressions, whose times are in the location of t.
+func In(s Schedule, loc *time.Location) Schedule {
+	return inLocation{schedule: s, loc: loc}
+}
+
+// inLocation is a schedule in a time zone.
+type inLocation struct {
+	schedule Schedule
+	loc      *time.Location
+}
+
+// Next returns the next run of the schedule after t, in the time zone.
+func (l inLocation) Next(t time.Time) time.Time {
+	return l.schedule.Next(t.In(l.loc))
+}
+
+// Func is a job. at is the time the job was due, which is earlier than the
+// current time when a missed run is caught up.
+type Func func(ctx context.Context, at time.Time)
+
+// job is a scheduled job.
+type job struct {
+	name     string
+	schedule Schedule
+	fn       Func
+	next     time.Time
+}
+
+// Scheduler runs jobs at the times given by their schedules.
+type Scheduler struct {
+	clock Clock
+	wake  chan struct{}
+
+	mu   sync.Mutex
+	jobs map[string]*job
+}
+
+// New returns a scheduler that tells the time with the given clock.
+func New(clock Clock) *Scheduler {
+	return &Scheduler{
+		clock: clock,
+		wake:  make(chan struct{}, 1),
+		jobs:  make(map[string]*job),
+	}
+}
+
+// Add schedules a job with a unique name, replacing any job with the same
+// name. Its first run is the next time of the schedule after from, which is
+// usually the time of its last run (or of its creation). It reports whether
+// the job has any run left.
+func (s *Scheduler) Add(name string, schedule Schedule, from time.Time, fn Func) bool {
+	next := schedule.Next(from)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.jobs, name)
+	if next.IsZero() {
+		return false
+	}
+	s.jobs[name] = &job{name: name, schedule: schedule, fn: fn, next: next}
+	s.notify()
+	return true
+}
+
+// Remove unschedules a job. It reports whether the job was scheduled.
+func (s *Scheduler) Remove(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.jobs[name]
+	delete(s.jobs, name)
+	return ok
+}
+
+// Next returns the next run of a job, and whether it's scheduled.
+func (s *Scheduler) Next(name string) (time.Time, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if j, ok := s.jobs[name]; ok {
+		return j.next, true
+	}
+	return time.Time{}, false
+}
+
+// Run runs the jobs when they are due until the context is canceled. Jobs run
+// one at a time, in order of their due time.
+func (s *Scheduler) Run(ctx context.Context) {
+	for {
+		s.runDue(ctx)
+
+		var timer <-chan time.Time
+		if next, ok := s.earliest(); ok {
+			timer = s.clock.After(max(next.Sub(s.clock.Now()), 0))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.wake:
+		case <-timer:
+		}
+	}
+}
+
+// runDue runs the jobs that are due and schedules their next run after the
+// current time, so several missed runs are caught up only once.
+func (s *Scheduler) runDue(ctx context.Context) {
+	now := s.clock.Now()
+
+	s.mu.Lock()
+	var due []*job
+	for _, j := range s.jobs {
+		if !j.next.After(now) {
+			due = append(due, j)
+		}
+	}
+	s.mu.Unlock()
+	sort.Slice(due, func(i, k int) bool { return due[i].next.Before(due[k].next) })
+
+	for _, j := range due {
+		if ctx.Err() != nil {
+			return
+		}
+		j.fn(ctx, j.next)
+
+		s.mu.Lock()
+		// The job may have been removed or replaced while running
+		if s.jobs[j.name] == j {
+			j.next = j.schedule.Next(s.clock.Now())
+			if j.next.IsZero() {
+				delete(s.jobs, j.name)
+			}
+		}
+		s.mu.Unlock()
+	}
+}
+
+// earliest returns the earliest next run of the jobs.
+func (s *Scheduler) earliest() (time.Time, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var earliest time.Time
+	for _, j := range s.jobs {
+		if earliest.IsZero() || j.next.Before(earliest) {
+			earliest = j.next
+		}
+	}
+	return earliest, !earliest.IsZero()
+}
+
+// notify wakes up Run to wait for the new earliest job. It must be called
+// with the lock held.
+func (s *Scheduler) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
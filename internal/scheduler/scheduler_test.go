@@ -0,0 +1,189 @@
+package scheduler
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is a clock whose time only passes when advanced.
+type fakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []waiter
+}
+
+// waiter is a channel waiting for the clock to reach a time.
+type waiter struct {
+	until time.Time
+	ch    chan time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ch := make(chan time.Time, 1)
+	c.waiters = append(c.waiters, waiter{until: c.now.Add(d), ch: ch})
+	return ch
+}
+
+// Advance moves the clock forward, firing the channels waiting until then.
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	c.waiters = slices.DeleteFunc(c.waiters, func(w waiter) bool {
+		if w.until.After(c.now) {
+			return false
+		}
+		w.ch <- c.now
+		return true
+	})
+}
+
+// every is a schedule running at the given interval.
+type every time.Duration
+
+func (e every) Next(t time.Time) time.Time {
+	return t.Truncate(time.Duration(e)).Add(time.Duration(e))
+}
+
+func TestAt_Next(t *testing.T) {
+	at := time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		from     time.Time
+		expected time.Time
+	}{
+		{"Before", at.Add(-time.Hour), at},
+		{"At", at, time.Time{}},
+		{"After", at.Add(time.Hour), time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := At(at).Next(tt.from); !got.Equal(tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestIn(t *testing.T) {
+	loc := time.FixedZone("UTC+2", 2*60*60)
+	from := time.Date(2026, 10, 18, 23, 30, 0, 0, time.UTC)
+
+	// Truncating to days depends on the time zone of the time
+	daily := every(24 * time.Hour)
+	expected := daily.Next(from.In(loc))
+	if got := In(daily, loc).Next(from); !got.Equal(expected) || got.Location() != loc {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
+
+func TestScheduler_runDue(t *testing.T) {
+	start := time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)
+	clock := &fakeClock{now: start}
+	s := New(clock)
+
+	var runs []string
+	record := func(name string) Func {
+		return func(_ context.Context, at time.Time) {
+			runs = append(runs, name+"@"+at.Format("15:04"))
+		}
+	}
+
+	s.Add("hourly", every(time.Hour), start, record("hourly"))
+	s.Add("missed", every(time.Hour), start.Add(-3*time.Hour), record("missed"))
+	s.Add("once", At(start.Add(90*time.Minute)), start, record("once"))
+	if s.Add("past", At(start.Add(-time.Hour)), start, record("past")) {
+		t.Error("Expected a one-off job in the past of its start to have no runs")
+	}
+	s.Add("removed", every(time.Hour), start, record("removed"))
+	if !s.Remove("removed") || s.Remove("removed") {
+		t.Error("Expected the job to be removed only once")
+	}
+
+	steps := []struct {
+		name         string
+		advance      time.Duration
+		expectedRuns []string
+	}{
+		{"Missed runs are caught up once", 0, []string{"missed@07:00"}},
+		{"Nothing due", 30 * time.Minute, nil},
+		{"Hourly", 30 * time.Minute, []string{"missed@10:00", "hourly@10:00"}},
+		{"One-off job", 30 * time.Minute, []string{"once@10:30"}},
+		{"Late run", 2 * time.Hour, []string{"missed@11:00", "hourly@11:00"}},
+	}
+
+	for _, step := range steps {
+		runs = nil
+		clock.Advance(step.advance)
+		s.runDue(context.Background())
+		slices.Sort(runs)
+		slices.Sort(step.expectedRuns)
+		if !slices.Equal(runs, step.expectedRuns) {
+			t.Errorf("%s: expected runs %v, got %v", step.name, step.expectedRuns, runs)
+		}
+	}
+
+	if _, ok := s.Next("once"); ok {
+		t.Error("Expected the one-off job to be removed after running")
+	}
+	if next, ok := s.Next("hourly"); !ok || !next.Equal(start.Add(4*time.Hour)) {
+		t.Errorf("Expected the hourly job to run next at 13:00, got %v (%v)", next, ok)
+	}
+}
+
+func TestScheduler_Run(t *testing.T) {
+	start := time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)
+	clock := &fakeClock{now: start}
+	s := New(clock)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+
+	ran := make(chan time.Time, 1)
+	s.Add("once", At(start.Add(time.Hour)), start, func(_ context.Context, at time.Time) { ran <- at })
+
+	// Wait for Run to wait for the job
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		clock.mu.Lock()
+		waiting := len(clock.waiters) > 0
+		clock.mu.Unlock()
+		if waiting {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for Run to wait for the job")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	clock.Advance(time.Hour)
+
+	select {
+	case at := <-ran:
+		if !at.Equal(start.Add(time.Hour)) {
+			t.Errorf("Expected the job to run at %v, got %v", start.Add(time.Hour), at)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for the job to run")
+	}
+
+	cancel()
+	<-done
+}
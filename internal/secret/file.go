@@ -0,0 +1,35 @@
+package secret
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ReadFile reads a secret string from the file at path, trimming any
+// surrounding whitespace (e.g. the trailing newline added by editors). Files
+// that are readable or writable by group or others, or empty, are rejected.
+func ReadFile(path string) (String, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret file: %w", err)
+	}
+	if !info.Mode().IsRegular() {
+		return "", fmt.Errorf("secret file %s is not a regular file", path)
+	}
+	if perm := info.Mode().Perm(); perm&0066 != 0 {
+		return "", fmt.Errorf("secret file %s has insecure permissions %#o: it must not be readable or writable by group or others (e.g. 0600 or 0400)", path, perm)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret file: %w", err)
+	}
+
+	value := strings.TrimSpace(string(data))
+	if value == "" {
+		return "", fmt.Errorf("secret file %s is empty", path)
+	}
+
+	return New(value), nil
+}
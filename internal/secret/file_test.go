@@ -0,0 +1,51 @@
+package secret
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		perm     os.FileMode
+		expected String
+		valid    bool
+	}{
+		{"Valid file", "mySecret", 0600, "mySecret", true},
+		{"Trims whitespace", "  mySecret\n", 0400, "mySecret", true},
+		{"World-readable file", "mySecret", 0604, "", false},
+		{"Group-readable file", "mySecret", 0640, "", false},
+		{"Group-writable file", "mySecret", 0620, "", false},
+		{"World-writable file", "mySecret", 0606, "", false},
+		{"Empty file", " \n", 0600, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "secret")
+			if err := os.WriteFile(path, []byte(tt.content), 0600); err != nil {
+				t.Fatalf("failed to write secret file: %v", err)
+			}
+			if err := os.Chmod(path, tt.perm); err != nil {
+				t.Fatalf("failed to change permissions: %v", err)
+			}
+
+			got, err := ReadFile(path)
+			if (err == nil) != tt.valid {
+				t.Fatalf("expected valid: %v, but got error: %v", tt.valid, err)
+			}
+			if got != tt.expected {
+				t.Errorf("expected %q, but got %q", tt.expected, got)
+			}
+		})
+	}
+
+	t.Run("Missing file", func(t *testing.T) {
+		if _, err := ReadFile(filepath.Join(t.TempDir(), "missing")); err == nil {
+			t.Error("expected an error for a missing file, got nil")
+		}
+	})
+}
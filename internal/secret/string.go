@@ -9,7 +9,8 @@
 //
 // The intended use of this package is to encapsulate sensitive information
 // while allowing safe display without revealing the entire content of the
-// string.
+// string. Secrets can also be read from files (e.g. Docker or Kubernetes
+// secrets) with ReadFile.
 package secret
 
 import "strings"
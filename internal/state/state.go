@@ -0,0 +1,235 @@
+// Package state provides a small persistent store for the bot state.
+//
+// The Store keeps named JSON documents (e.g. the roles granted with /allow) in
+// a directory, writing each one atomically to "<dir>/<name>.json", and named
+// logs of JSON records (e.g. the bookmarks saved) appended one per line to
+// "<dir>/<name>.jsonl". When no directory is configured, documents and logs are
+// kept in memory and lost on restart, which is useful for tests and for
+// deployments without persistent storage.
+package state
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Store persists named JSON documents.
+type Store struct {
+	dir    string
+	mu     sync.Mutex
+	memory map[string][]byte
+}
+
+// New creates a new Store in dir, creating it if needed. If dir is empty, the
+// documents are kept in memory.
+func New(dir string) (*Store, error) {
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return nil, fmt.Errorf("failed to create state directory: %w", err)
+		}
+	}
+
+	return &Store{dir: dir, memory: make(map[string][]byte)}, nil
+}
+
+// Persistent reports whether the documents are stored on disk.
+func (s *Store) Persistent() bool {
+	return s.dir != ""
+}
+
+// Load decodes the document with the given name into v. If the document
+// doesn't exist, v is left untouched and no error is returned.
+func (s *Store) Load(name string, v any) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := s.read(name)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s state: %w", name, err)
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to parse %s state: %w", name, err)
+	}
+
+	return nil
+}
+
+// Save encodes v and stores it as the document with the given name, replacing
+// any previous version.
+func (s *Store) Save(name string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s state: %w", name, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.write(name, data); err != nil {
+		return fmt.Errorf("failed to write %s state: %w", name, err)
+	}
+
+	return nil
+}
+
+// Append encodes v and adds it as a line at the end of the log with the given
+// name.
+func (s *Store) Append(name string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s record: %w", name, err)
+	}
+	data = append(data, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.Persistent() {
+		s.memory[name+".jsonl"] = append(s.memory[name+".jsonl"], data...)
+		return nil
+	}
+
+	f, err := os.OpenFile(s.logPath(name), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open %s log: %w", name, err)
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write %s record: %w", name, err)
+	}
+	return f.Close()
+}
+
+// Scan calls fn with every record of the log with the given name, in the order
+// they were appended, until fn returns an error. A missing log has no records.
+func (s *Store) Scan(name string, fn func(record []byte) error) error {
+	var r io.Reader
+	if !s.Persistent() {
+		s.mu.Lock()
+		r = bytes.NewReader(bytes.Clone(s.memory[name+".jsonl"]))
+		s.mu.Unlock()
+	} else {
+		f, err := os.Open(s.logPath(name))
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to open %s log: %w", name, err)
+		}
+		defer f.Close()
+		r = f
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		if err := fn(scanner.Bytes()); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read %s log: %w", name, err)
+	}
+	return nil
+}
+
+// Compact rewrites the log with the given name, keeping only the records for
+// which keep returns true, and returns the number of records removed. Records
+// appended meanwhile wait until it's done.
+func (s *Store) Compact(name string, keep func(record []byte) bool) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data := s.memory[name+".jsonl"]
+	if s.Persistent() {
+		var err error
+		data, err = os.ReadFile(s.logPath(name))
+		if errors.Is(err, os.ErrNotExist) {
+			return 0, nil
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to read %s log: %w", name, err)
+		}
+	}
+
+	var kept []byte
+	removed := 0
+	for line := range bytes.Lines(data) {
+		record := bytes.TrimSuffix(line, []byte("\n"))
+		if len(record) == 0 {
+			continue
+		}
+		if !keep(record) {
+			removed++
+			continue
+		}
+		kept = append(kept, line...)
+	}
+	if removed == 0 {
+		return 0, nil
+	}
+
+	if !s.Persistent() {
+		s.memory[name+".jsonl"] = kept
+		return removed, nil
+	}
+	tmp := s.logPath(name) + ".tmp"
+	if err := os.WriteFile(tmp, kept, 0600); err != nil {
+		return 0, fmt.Errorf("failed to write %s log: %w", name, err)
+	}
+	if err := os.Rename(tmp, s.logPath(name)); err != nil {
+		return 0, fmt.Errorf("failed to write %s log: %w", name, err)
+	}
+	return removed, nil
+}
+
+// read returns the raw document with the given name.
+func (s *Store) read(name string) ([]byte, error) {
+	if !s.Persistent() {
+		data, ok := s.memory[name]
+		if !ok {
+			return nil, os.ErrNotExist
+		}
+		return data, nil
+	}
+
+	return os.ReadFile(s.path(name))
+}
+
+// write atomically stores the raw document with the given name.
+func (s *Store) write(name string, data []byte) error {
+	if !s.Persistent() {
+		s.memory[name] = data
+		return nil
+	}
+
+	tmp := s.path(name) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path(name))
+}
+
+// path returns the file path of the document with the given name.
+func (s *Store) path(name string) string {
+	return filepath.Join(s.dir, name+".json")
+}
+
+// logPath returns the file path of the log with the given name.
+func (s *Store) logPath(name string) string {
+	return filepath.Join(s.dir, name+".jsonl")
+}
@@ -0,0 +1,155 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestStore(t *testing.T) {
+	type document struct {
+		Users map[int64]string `json:"users"`
+	}
+
+	tests := []struct {
+		name       string
+		dir        string
+		persistent bool
+	}{
+		{"In memory", "", false},
+		{"On disk", t.TempDir(), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, err := New(tt.dir)
+			if err != nil {
+				t.Fatalf("New() returned an unexpected error: %v", err)
+			}
+			if store.Persistent() != tt.persistent {
+				t.Errorf("expected persistent: %v, got %v", tt.persistent, store.Persistent())
+			}
+
+			// Missing documents are not an error.
+			doc := document{Users: map[int64]string{}}
+			if err := store.Load("roles", &doc); err != nil {
+				t.Fatalf("Load() returned an unexpected error: %v", err)
+			}
+			if len(doc.Users) != 0 {
+				t.Errorf("expected an empty document, got %+v", doc)
+			}
+
+			doc.Users[42] = "admin"
+			if err := store.Save("roles", doc); err != nil {
+				t.Fatalf("Save() returned an unexpected error: %v", err)
+			}
+
+			// Reopen the store to check persistence.
+			if tt.persistent {
+				if store, err = New(tt.dir); err != nil {
+					t.Fatalf("New() returned an unexpected error: %v", err)
+				}
+			}
+
+			var loaded document
+			if err := store.Load("roles", &loaded); err != nil {
+				t.Fatalf("Load() returned an unexpected error: %v", err)
+			}
+			if loaded.Users[42] != "admin" {
+				t.Errorf("expected user 42 to be admin, got %+v", loaded)
+			}
+		})
+	}
+}
+
+func TestStore_log(t *testing.T) {
+	type record struct {
+		ID int `json:"id"`
+	}
+
+	tests := []struct {
+		name string
+		dir  string
+	}{
+		{"In memory", ""},
+		{"On disk", t.TempDir()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, err := New(tt.dir)
+			if err != nil {
+				t.Fatalf("New() returned an unexpected error: %v", err)
+			}
+
+			// Missing logs have no records.
+			if err := store.Scan("events", func([]byte) error { return errors.New("unexpected record") }); err != nil {
+				t.Fatalf("Scan() returned an unexpected error: %v", err)
+			}
+
+			for id := range 3 {
+				if err := store.Append("events", record{ID: id}); err != nil {
+					t.Fatalf("Append() returned an unexpected error: %v", err)
+				}
+			}
+
+			// Logs don't clash with documents of the same name.
+			if err := store.Save("events", record{ID: 42}); err != nil {
+				t.Fatalf("Save() returned an unexpected error: %v", err)
+			}
+
+			// Reopen the store to check persistence.
+			if tt.dir != "" {
+				if store, err = New(tt.dir); err != nil {
+					t.Fatalf("New() returned an unexpected error: %v", err)
+				}
+			}
+
+			var ids []int
+			err = store.Scan("events", func(data []byte) error {
+				var r record
+				if err := json.Unmarshal(data, &r); err != nil {
+					return err
+				}
+				ids = append(ids, r.ID)
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("Scan() returned an unexpected error: %v", err)
+			}
+			if expected := []int{0, 1, 2}; !slices.Equal(ids, expected) {
+				t.Errorf("expected records %v, got %v", expected, ids)
+			}
+
+			// Errors stop the scan.
+			stop := errors.New("stop")
+			calls := 0
+			if err := store.Scan("events", func([]byte) error { calls++; return stop }); !errors.Is(err, stop) || calls != 1 {
+				t.Errorf("expected the scan to stop with %v after 1 record, got %v after %d", stop, err, calls)
+			}
+
+			// Compacting keeps the records in order.
+			removed, err := store.Compact("events", func(data []byte) bool {
+				var r record
+				return json.Unmarshal(data, &r) == nil && r.ID != 1
+			})
+			if err != nil || removed != 1 {
+				t.Fatalf("expected Compact() to remove 1 record, got %d and %v", removed, err)
+			}
+			if err := store.Append("events", record{ID: 3}); err != nil {
+				t.Fatalf("Append() returned an unexpected error: %v", err)
+			}
+			ids = nil
+			_ = store.Scan("events", func(data []byte) error {
+				var r record
+				_ = json.Unmarshal(data, &r)
+				ids = append(ids, r.ID)
+				return nil
+			})
+			if expected := []int{0, 2, 3}; !slices.Equal(ids, expected) {
+				t.Errorf("expected records %v after compacting, got %v", expected, ids)
+			}
+		})
+	}
+}
@@ -0,0 +1,199 @@
+package urlcleaner
+
+import (
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/Madh93/karakeepbot/internal/validation"
+)
+
+// trackingParams are query parameters removed from every URL.
+var trackingParams = []string{
+	"utm_*",
+	"fbclid",
+	"gclid",
+	"dclid",
+	"gbraid",
+	"wbraid",
+	"msclkid",
+	"yclid",
+	"mc_cid",
+	"mc_eid",
+	"igshid",
+	"igsh",
+	"_hsenc",
+	"_hsmi",
+	"mkt_tok",
+	"oly_anon_id",
+	"oly_enc_id",
+	"vero_id",
+	"ref_src",
+	"ref_url",
+}
+
+// hostTrackingParams are query parameters removed only for specific domains,
+// as they carry meaningful values elsewhere.
+var hostTrackingParams = map[string][]string{
+	"youtube.com":   {"si", "feature", "pp"},
+	"youtu.be":      {"si", "feature"},
+	"spotify.com":   {"si", "context"},
+	"twitter.com":   {"s", "t"},
+	"x.com":         {"s", "t"},
+	"instagram.com": {"img_index"},
+	"amazon.com":    {"ref", "ref_", "pd_rd_*", "pf_rd_*"},
+	"linkedin.com":  {"trk", "trackingid", "lipi"},
+	"reddit.com":    {"share_id", "rdt"},
+	"tiktok.com":    {"_r", "_t", "is_from_webapp", "sender_device"},
+	"medium.com":    {"source"},
+}
+
+// redirector describes a known link wrapper that carries the target URL in a
+// query parameter.
+type redirector struct {
+	host   string   // Domain (subdomains included) of the redirector.
+	path   string   // Path prefix of the redirect endpoint. Empty matches any.
+	params []string // Query parameters that may contain the target URL.
+}
+
+// redirectors are the known link wrappers unwrapped by the cleaner.
+var redirectors = []redirector{
+	{host: "l.facebook.com", path: "/l.php", params: []string{"u"}},
+	{host: "lm.facebook.com", path: "/l.php", params: []string{"u"}},
+	{host: "m.facebook.com", path: "/l.php", params: []string{"u"}},
+	{host: "l.messenger.com", path: "/l.php", params: []string{"u"}},
+	{host: "l.instagram.com", path: "/", params: []string{"u"}},
+	{host: "l.threads.net", path: "/", params: []string{"u"}},
+	{host: "google.com", path: "/url", params: []string{"q", "url"}},
+	{host: "out.reddit.com", path: "/", params: []string{"url"}},
+	{host: "youtube.com", path: "/redirect", params: []string{"q"}},
+	{host: "t.umblr.com", path: "/redirect", params: []string{"z"}},
+	{host: "slack-redir.net", path: "/link", params: []string{"url"}},
+	{host: "steamcommunity.com", path: "/linkfilter", params: []string{"url", "u"}},
+	{host: "vk.com", path: "/away.php", params: []string{"to"}},
+	{host: "away.vk.com", path: "/away.php", params: []string{"to"}},
+}
+
+// unwrapRedirector returns the target URL if u is a known redirector, or nil
+// otherwise.
+func unwrapRedirector(u *url.URL) *url.URL {
+	host := strings.ToLower(u.Hostname())
+
+	// href.li carries the target URL right after the '?'.
+	if host == "href.li" && u.RawQuery != "" {
+		return parseTarget(u.RawQuery)
+	}
+
+	for _, r := range redirectors {
+		if !hostMatches(host, r.host) || (r.path != "" && !strings.HasPrefix(u.Path, r.path)) {
+			continue
+		}
+		query := u.Query()
+		for _, param := range r.params {
+			if target := parseTarget(query.Get(param)); target != nil {
+				return target
+			}
+		}
+	}
+
+	return nil
+}
+
+// parseTarget parses a redirect target, returning nil if it is not a valid
+// HTTP(S) URL.
+func parseTarget(raw string) *url.URL {
+	if validation.ValidateURL(raw) != nil {
+		return nil
+	}
+	target, err := url.Parse(raw)
+	if err != nil {
+		return nil
+	}
+	return target
+}
+
+// ampCacheRegexp matches AMP cache paths like "/c/s/example.com/article",
+// capturing whether the original was HTTPS and the remaining host and path.
+var ampCacheRegexp = regexp.MustCompile(`^/(?:c|v|i)/(s/)?(.+)$`)
+
+// ampViewerRegexp matches Google AMP viewer paths like "/amp/s/example.com/a".
+var ampViewerRegexp = regexp.MustCompile(`^/amp/(s/)?(.+)$`)
+
+// ampParams are query parameters used to request AMP versions of a page.
+var ampParams = []string{"amp", "amp_js_v", "amp_gsa", "usqp", "outputtype"}
+
+// ampToCanonical returns the canonical URL for an AMP URL, or nil if u is not
+// an AMP URL.
+func ampToCanonical(u *url.URL) *url.URL {
+	host := strings.ToLower(u.Hostname())
+
+	var matches []string
+	switch {
+	case hostMatches(host, "cdn.ampproject.org"):
+		matches = ampCacheRegexp.FindStringSubmatch(u.Path)
+	case hostMatches(host, "google.com"):
+		matches = ampViewerRegexp.FindStringSubmatch(u.Path)
+	}
+
+	if matches != nil {
+		scheme := "http://"
+		if matches[1] != "" {
+			scheme = "https://"
+		}
+		target := parseTarget(scheme + matches[2])
+		if target != nil {
+			target.RawQuery = u.RawQuery
+			u = target
+		}
+	}
+
+	if matches == nil {
+		return nil
+	}
+
+	// Strip the AMP parameters only from AMP URLs, as other sites may use them
+	rawQuery, _ := removeParams(u.RawQuery, func(key string) bool {
+		return slices.ContainsFunc(ampParams, func(param string) bool { return strings.EqualFold(key, param) })
+	})
+	cleaned := *u
+	cleaned.RawQuery = rawQuery
+	return &cleaned
+}
+
+// youTubeHosts are hosts rewritten to www.youtube.com.
+var youTubeHosts = []string{"youtube.com", "m.youtube.com"}
+
+// normalizeYouTube converts youtu.be short links and mobile YouTube links into
+// the canonical https://www.youtube.com/watch?v=ID form.
+func normalizeYouTube(u *url.URL) *url.URL {
+	host := strings.ToLower(u.Hostname())
+
+	if host == "youtu.be" || host == "www.youtu.be" {
+		id := strings.Trim(u.Path, "/")
+		if id == "" || strings.Contains(id, "/") {
+			return u
+		}
+		// The video goes first, followed by the other parameters as they were
+		rawQuery := "v=" + url.QueryEscape(id)
+		if rest, _ := removeParams(u.RawQuery, func(key string) bool { return key == "v" }); rest != "" {
+			rawQuery += "&" + rest
+		}
+		return &url.URL{
+			Scheme:   "https",
+			Host:     "www.youtube.com",
+			Path:     "/watch",
+			RawQuery: rawQuery,
+			Fragment: u.Fragment,
+		}
+	}
+
+	if slices.Contains(youTubeHosts, host) {
+		normalized := *u
+		normalized.Scheme = "https"
+		normalized.Host = "www.youtube.com"
+		return &normalized
+	}
+
+	return u
+}
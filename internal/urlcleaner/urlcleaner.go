@@ -0,0 +1,221 @@
+// Package urlcleaner rewrites shared links into a clean, canonical form before
+// they are saved as bookmarks.
+//
+// Links shared from mobile apps are often wrapped by redirectors (such as
+// l.facebook.com or Google's /url endpoint), served through AMP caches, or
+// decorated with tracking parameters like utm_source and fbclid. The Cleaner
+// applies a pipeline of built-in rules to undo these transformations:
+//
+//   - Redirector unwrapping: extracts the target URL from known redirectors.
+//
+//   - AMP to canonical: converts Google AMP viewer and AMP cache URLs into the
+//     original publisher URL.
+//
+//   - YouTube normalization: converts youtu.be and mobile links into the
+//     canonical www.youtube.com/watch form.
+//
+//   - Tracking parameter removal: strips well-known tracking query parameters
+//     as well as any user-configured ones.
+//
+// Optionally, redirects can be resolved with a HEAD request (useful for
+// shorteners like t.co that do not carry the target URL), and user-defined
+// regular expression rules are applied last.
+package urlcleaner
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Madh93/karakeepbot/internal/config"
+	"github.com/Madh93/karakeepbot/internal/validation"
+)
+
+// maxUnwrapDepth limits how many nested redirectors are unwrapped.
+const maxUnwrapDepth = 5
+
+// rule represents a compiled user-defined rewrite rule.
+type rule struct {
+	re          *regexp.Regexp
+	replacement string
+}
+
+// Cleaner rewrites URLs according to the configured pipeline.
+type Cleaner struct {
+	enabled bool
+	resolve bool
+	params  []string
+	rules   []rule
+	client  *http.Client
+}
+
+// New creates a new Cleaner using the provided configuration.
+func New(config *config.URLCleanerConfig) (*Cleaner, error) {
+	rules := make([]rule, 0, len(config.Rules))
+	for _, r := range config.Rules {
+		re, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule{re: re, replacement: r.Replacement})
+	}
+
+	params := append([]string(nil), trackingParams...)
+	for _, param := range config.Params {
+		params = append(params, strings.ToLower(strings.TrimSpace(param)))
+	}
+
+	return &Cleaner{
+		enabled: config.Enabled,
+		resolve: config.Resolve,
+		params:  params,
+		rules:   rules,
+		client: &http.Client{
+			Timeout: time.Duration(config.Timeout) * time.Second,
+		},
+	}, nil
+}
+
+// Clean returns the cleaned version of rawURL. If the URL cannot be parsed or
+// the cleaner is disabled, it is returned unchanged.
+func (c *Cleaner) Clean(ctx context.Context, rawURL string) string {
+	if !c.enabled {
+		return rawURL
+	}
+
+	cleaned := c.applyBuiltins(rawURL)
+
+	if c.resolve {
+		if resolved := c.resolveRedirects(ctx, cleaned); resolved != cleaned {
+			cleaned = c.applyBuiltins(resolved)
+		}
+	}
+
+	for _, r := range c.rules {
+		cleaned = r.re.ReplaceAllString(cleaned, r.replacement)
+	}
+
+	return cleaned
+}
+
+// applyBuiltins runs the built-in rules over rawURL.
+func (c *Cleaner) applyBuiltins(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return rawURL
+	}
+
+	for range maxUnwrapDepth {
+		target := unwrapRedirector(u)
+		if target == nil {
+			break
+		}
+		u = target
+	}
+
+	if target := ampToCanonical(u); target != nil {
+		u = target
+	}
+
+	u = normalizeYouTube(u)
+	u = c.stripParams(u)
+
+	return u.String()
+}
+
+// resolveRedirects follows HTTP redirects for rawURL with a HEAD request and
+// returns the final URL. On any failure, rawURL is returned unchanged.
+func (c *Cleaner) resolveRedirects(ctx context.Context, rawURL string) string {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, rawURL, nil)
+	if err != nil {
+		return rawURL
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return rawURL
+	}
+	_ = resp.Body.Close()
+
+	if final := resp.Request.URL.String(); validation.ValidateURL(final) == nil {
+		return final
+	}
+	return rawURL
+}
+
+// stripParams removes tracking query parameters from u.
+func (c *Cleaner) stripParams(u *url.URL) *url.URL {
+	if u.RawQuery == "" {
+		return u
+	}
+
+	rawQuery, changed := removeParams(u.RawQuery, func(key string) bool {
+		return c.isTrackingParam(u.Hostname(), key)
+	})
+	if !changed {
+		return u
+	}
+
+	cleaned := *u
+	cleaned.RawQuery = rawQuery
+	return &cleaned
+}
+
+// removeParams returns the raw query without the parameters whose key matches
+// drop, keeping the order and encoding of the others, and whether any was
+// removed.
+func removeParams(rawQuery string, drop func(key string) bool) (string, bool) {
+	var kept []string
+	changed := false
+	for _, param := range strings.Split(rawQuery, "&") {
+		key, _, _ := strings.Cut(param, "=")
+		if unescaped, err := url.QueryUnescape(key); err == nil {
+			key = unescaped
+		}
+		if param != "" && drop(key) {
+			changed = true
+			continue
+		}
+		kept = append(kept, param)
+	}
+	return strings.Join(kept, "&"), changed
+}
+
+// isTrackingParam reports whether the query parameter key on host should be
+// removed.
+func (c *Cleaner) isTrackingParam(host, key string) bool {
+	key = strings.ToLower(key)
+	for _, param := range c.params {
+		if matchParam(param, key) {
+			return true
+		}
+	}
+	for suffix, params := range hostTrackingParams {
+		if hostMatches(host, suffix) {
+			for _, param := range params {
+				if matchParam(param, key) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// matchParam reports whether key matches pattern. A trailing '*' in pattern
+// matches any suffix.
+func matchParam(pattern, key string) bool {
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+		return strings.HasPrefix(key, prefix)
+	}
+	return pattern == key
+}
+
+// hostMatches reports whether host is domain or one of its subdomains.
+func hostMatches(host, domain string) bool {
+	host = strings.ToLower(host)
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
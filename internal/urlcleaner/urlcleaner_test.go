@@ -0,0 +1,158 @@
+package urlcleaner
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Madh93/karakeepbot/internal/config"
+)
+
+func TestClean(t *testing.T) {
+	cleaner, err := New(&config.URLCleanerConfig{
+		Enabled: true,
+		Params:  []string{"custom_*"},
+		Rules: []config.URLRewriteRule{
+			{Pattern: `^https://(www\.)?reddit\.com/`, Replacement: "https://old.reddit.com/"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() returned an unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "clean URL is unchanged",
+			input:    "https://example.com/article?id=42",
+			expected: "https://example.com/article?id=42",
+		},
+		{
+			name:     "strips utm parameters",
+			input:    "https://example.com/article?utm_source=twitter&utm_medium=social&id=42",
+			expected: "https://example.com/article?id=42",
+		},
+		{
+			name:     "strips fbclid",
+			input:    "https://example.com/?fbclid=IwAR0abc",
+			expected: "https://example.com/",
+		},
+		{
+			name:     "strips user-configured parameters",
+			input:    "https://example.com/?custom_campaign=1&page=2",
+			expected: "https://example.com/?page=2",
+		},
+		{
+			name:     "keeps si outside of known hosts",
+			input:    "https://example.com/?si=keep",
+			expected: "https://example.com/?si=keep",
+		},
+		{
+			name:     "unwraps facebook redirector",
+			input:    "https://l.facebook.com/l.php?u=https%3A%2F%2Fexample.com%2Fpost%3Futm_source%3Dfb&h=AT0",
+			expected: "https://example.com/post",
+		},
+		{
+			name:     "unwraps google redirector",
+			input:    "https://www.google.com/url?sa=t&url=https%3A%2F%2Fexample.com%2Fpage&usg=AOv",
+			expected: "https://example.com/page",
+		},
+		{
+			name:     "unwraps nested redirectors",
+			input:    "https://out.reddit.com/t3_x?url=https%3A%2F%2Fl.facebook.com%2Fl.php%3Fu%3Dhttps%253A%252F%252Fexample.com%252F",
+			expected: "https://example.com/",
+		},
+		{
+			name:     "converts Google AMP viewer URL",
+			input:    "https://www.google.com/amp/s/example.com/news/story.amp?amp=1",
+			expected: "https://example.com/news/story.amp",
+		},
+		{
+			name:     "converts AMP cache URL",
+			input:    "https://example-com.cdn.ampproject.org/c/s/example.com/news/story",
+			expected: "https://example.com/news/story",
+		},
+		{
+			name:     "keeps AMP parameters of other URLs",
+			input:    "https://example.com/feed?outputType=rss&amp=1&b=2&a=1",
+			expected: "https://example.com/feed?outputType=rss&amp=1&b=2&a=1",
+		},
+		{
+			name:     "keeps the order of the parameters",
+			input:    "https://www.google.com/amp/s/example.com/search?q=go&usqp=mq331AQ&page=2&lang=en",
+			expected: "https://example.com/search?q=go&page=2&lang=en",
+		},
+		{
+			name:     "normalizes youtu.be links",
+			input:    "https://youtu.be/dQw4w9WgXcQ?si=abcdef&t=42",
+			expected: "https://www.youtube.com/watch?v=dQw4w9WgXcQ&t=42",
+		},
+		{
+			name:     "keeps the parameters of youtu.be links as they were",
+			input:    "https://youtu.be/dQw4w9WgXcQ?t=42&list=PL%2Fa+b&v=other",
+			expected: "https://www.youtube.com/watch?v=dQw4w9WgXcQ&t=42&list=PL%2Fa+b",
+		},
+		{
+			name:     "normalizes mobile YouTube links",
+			input:    "https://m.youtube.com/watch?v=dQw4w9WgXcQ&feature=share",
+			expected: "https://www.youtube.com/watch?v=dQw4w9WgXcQ",
+		},
+		{
+			name:     "applies user-defined rules",
+			input:    "https://www.reddit.com/r/golang/?share_id=abc",
+			expected: "https://old.reddit.com/r/golang/",
+		},
+		{
+			name:     "invalid URL is unchanged",
+			input:    "not a url",
+			expected: "not a url",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := cleaner.Clean(context.Background(), tt.input)
+			if got != tt.expected {
+				t.Errorf("Clean(%q) = %q, expected %q", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCleanDisabled(t *testing.T) {
+	cleaner, err := New(&config.URLCleanerConfig{Enabled: false})
+	if err != nil {
+		t.Fatalf("New() returned an unexpected error: %v", err)
+	}
+
+	input := "https://example.com/?utm_source=twitter"
+	if got := cleaner.Clean(context.Background(), input); got != input {
+		t.Errorf("Clean(%q) = %q, expected the URL to be unchanged", input, got)
+	}
+}
+
+func TestCleanResolvesRedirects(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/short", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/article?utm_source=short", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/article", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	cleaner, err := New(&config.URLCleanerConfig{Enabled: true, Resolve: true, Timeout: 5})
+	if err != nil {
+		t.Fatalf("New() returned an unexpected error: %v", err)
+	}
+
+	got := cleaner.Clean(context.Background(), server.URL+"/short")
+	if expected := server.URL + "/article"; got != expected {
+		t.Errorf("Clean() = %q, expected %q", got, expected)
+	}
+}
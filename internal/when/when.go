@@ -0,0 +1,224 @@
+// Package when parses the times people write to schedule something, such as
+// "3d", "in 2 hours", "tomorrow 9am" or "friday 18:30".
+//
+// Supported expressions are:
+//
+//   - Durations from now: "30m", "2h", "3d", "1w", "1d12h", "3 days", "in 2
+//     hours".
+//   - Days: "today", "tonight", "tomorrow", weekdays ("monday", "next fri")
+//     and dates ("2026-12-24"), optionally followed by a time. Days without a
+//     time default to 9:00, and "tonight" to 20:00.
+//   - Times: "9am", "9:30pm", "18:30", "noon" or "midnight", optionally
+//     preceded by "at". A time without a day is today, or tomorrow if it has
+//     already passed.
+//
+// Times are in the location of the current time passed to [Parse]. [Since]
+// parses times in the past, such as "7d" (ago), "yesterday" or "2026-01-01".
+package when
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Default times of the expressions without a time.
+const (
+	defaultHour = 9  // For days, e.g. "tomorrow".
+	tonightHour = 20 // For "tonight".
+)
+
+var (
+	durationRegex = regexp.MustCompile(`^(?:(\d+)\s*([a-z]+)\s*)+$`)
+	durationPart  = regexp.MustCompile(`(\d+)\s*([a-z]+)`)
+	clockRegex    = regexp.MustCompile(`^(\d{1,2})(?::(\d{2}))?(am|pm)?$`)
+)
+
+// units are the duration units, by name.
+var units = map[string]time.Duration{
+	"m": time.Minute, "min": time.Minute, "mins": time.Minute, "minute": time.Minute, "minutes": time.Minute,
+	"h": time.Hour, "hr": time.Hour, "hrs": time.Hour, "hour": time.Hour, "hours": time.Hour,
+	"d": 24 * time.Hour, "day": 24 * time.Hour, "days": 24 * time.Hour,
+	"w": 7 * 24 * time.Hour, "wk": 7 * 24 * time.Hour, "week": 7 * 24 * time.Hour, "weeks": 7 * 24 * time.Hour,
+}
+
+// weekdays are the days of the week, by name and abbreviation.
+var weekdays = map[string]time.Weekday{
+	"sunday": time.Sunday, "sun": time.Sunday,
+	"monday": time.Monday, "mon": time.Monday,
+	"tuesday": time.Tuesday, "tue": time.Tuesday, "tues": time.Tuesday,
+	"wednesday": time.Wednesday, "wed": time.Wednesday,
+	"thursday": time.Thursday, "thu": time.Thursday, "thurs": time.Thursday,
+	"friday": time.Friday, "fri": time.Friday,
+	"saturday": time.Saturday, "sat": time.Saturday,
+}
+
+// ErrPast is returned when the expression refers to a time that has passed.
+var ErrPast = errors.New("time is in the past")
+
+// Parse returns the time referred to by the expression, relative to now. The
+// time must be after now.
+func Parse(expr string, now time.Time) (time.Time, error) {
+	s := strings.Join(strings.Fields(strings.ToLower(expr)), " ")
+	s = strings.TrimPrefix(s, "in ")
+	if s == "" {
+		return time.Time{}, errors.New("empty time")
+	}
+
+	t, ok := parseDuration(s, now, 1)
+	if !ok {
+		var err error
+		if t, err = parseDayAndTime(s, now); err != nil {
+			return time.Time{}, err
+		}
+	}
+	if !t.After(now) {
+		return time.Time{}, ErrPast
+	}
+	return t, nil
+}
+
+// Since returns the time in the past referred to by the expression, relative
+// to now: a duration ago ("7d", "2 weeks ago"), "today", "yesterday" or a date
+// ("2026-01-01"). Days start at midnight.
+func Since(expr string, now time.Time) (time.Time, error) {
+	s := strings.Join(strings.Fields(strings.ToLower(expr)), " ")
+	s = strings.TrimSuffix(s, " ago")
+
+	var t time.Time
+	switch s {
+	case "":
+		return time.Time{}, errors.New("empty time")
+	case "today":
+		t = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	case "yesterday":
+		t = time.Date(now.Year(), now.Month(), now.Day()-1, 0, 0, 0, 0, now.Location())
+	default:
+		var ok bool
+		if t, ok = parseDuration(s, now, -1); !ok {
+			date, err := time.ParseInLocation(time.DateOnly, s, now.Location())
+			if err != nil {
+				return time.Time{}, fmt.Errorf("unknown time %q", s)
+			}
+			t = date
+		}
+	}
+	if t.After(now) {
+		return time.Time{}, fmt.Errorf("time %q is in the future", s)
+	}
+	return t, nil
+}
+
+// parseDuration returns now plus the duration (or minus it, if sign is
+// negative), e.g. "1d12h" or "3 days", and whether the expression is a
+// duration. Days and weeks are calendar days, so they keep the time of the day
+// across daylight saving time changes.
+func parseDuration(s string, now time.Time, sign int) (time.Time, bool) {
+	if !durationRegex.MatchString(s) {
+		return time.Time{}, false
+	}
+
+	t := now
+	for _, part := range durationPart.FindAllStringSubmatch(s, -1) {
+		n, err := strconv.Atoi(part[1])
+		unit, ok := units[part[2]]
+		if err != nil || !ok {
+			return time.Time{}, false
+		}
+		n *= sign
+		if unit%(24*time.Hour) == 0 {
+			t = t.AddDate(0, 0, n*int(unit/(24*time.Hour)))
+		} else {
+			t = t.Add(time.Duration(n) * unit)
+		}
+	}
+	return t, true
+}
+
+// parseDayAndTime returns the time referred to by a day, a time of the day or
+// both, e.g. "tomorrow 9am".
+func parseDayAndTime(s string, now time.Time) (time.Time, error) {
+	// Join the times separated from their suffix, e.g. "9 am"
+	s = strings.ReplaceAll(strings.ReplaceAll(s, " am", "am"), " pm", "pm")
+
+	var day time.Time
+	hour, minute := -1, 0
+	defaultHourOfDay := defaultHour
+	for _, token := range strings.Fields(s) {
+		switch {
+		case token == "at" || token == "on" || token == "next":
+			continue
+		case token == "today" || token == "tonight":
+			day = now
+			if token == "tonight" {
+				defaultHourOfDay = tonightHour
+			}
+		case token == "tomorrow":
+			day = now.AddDate(0, 0, 1)
+		case token == "noon":
+			hour, minute = 12, 0
+		case token == "midnight":
+			hour, minute = 0, 0
+		default:
+			if weekday, ok := weekdays[token]; ok {
+				days := (int(weekday)-int(now.Weekday())+6)%7 + 1 // Next occurrence, not today.
+				day = now.AddDate(0, 0, days)
+				continue
+			}
+			if date, err := time.ParseInLocation(time.DateOnly, token, now.Location()); err == nil {
+				day = date
+				continue
+			}
+			h, m, err := parseClock(token)
+			if err != nil {
+				return time.Time{}, fmt.Errorf("unknown time %q", token)
+			}
+			hour, minute = h, m
+		}
+	}
+
+	switch {
+	case day.IsZero() && hour < 0:
+		return time.Time{}, fmt.Errorf("unknown time %q", s)
+	case day.IsZero():
+		// A time alone is the next time it happens
+		t := time.Date(now.Year(), now.Month(), now.Day(), hour, minute, 0, 0, now.Location())
+		if !t.After(now) {
+			t = time.Date(now.Year(), now.Month(), now.Day()+1, hour, minute, 0, 0, now.Location())
+		}
+		return t, nil
+	case hour < 0:
+		hour = defaultHourOfDay
+	}
+	return time.Date(day.Year(), day.Month(), day.Day(), hour, minute, 0, 0, now.Location()), nil
+}
+
+// parseClock returns the hour and minute of a time of the day, in 12-hour
+// ("9am", "9:30pm") or 24-hour ("18:30") format.
+func parseClock(s string) (hour, minute int, err error) {
+	match := clockRegex.FindStringSubmatch(s)
+	if match == nil || (match[2] == "" && match[3] == "") {
+		return 0, 0, fmt.Errorf("invalid time %q", s)
+	}
+
+	hour, _ = strconv.Atoi(match[1])
+	if match[2] != "" {
+		minute, _ = strconv.Atoi(match[2])
+	}
+	if match[3] != "" {
+		if hour < 1 || hour > 12 {
+			return 0, 0, fmt.Errorf("invalid time %q", s)
+		}
+		hour %= 12
+		if match[3] == "pm" {
+			hour += 12
+		}
+	}
+	if hour > 23 || minute > 59 {
+		return 0, 0, fmt.Errorf("invalid time %q", s)
+	}
+	return hour, minute, nil
+}
@@ -0,0 +1,114 @@
+package when
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	madrid, err := time.LoadLocation("Europe/Madrid")
+	if err != nil {
+		t.Skipf("Time zone database not available: %v", err)
+	}
+	now := time.Date(2026, 10, 18, 10, 30, 0, 0, madrid) // Sunday
+	date := func(month time.Month, day, hour, minute int) time.Time {
+		return time.Date(2026, month, day, hour, minute, 0, 0, madrid)
+	}
+
+	tests := []struct {
+		expr          string
+		expected      time.Time
+		expectedError bool
+	}{
+		{"30m", now.Add(30 * time.Minute), false},
+		{"2h", now.Add(2 * time.Hour), false},
+		{"3d", date(10, 21, 10, 30), false},
+		{"1w", date(10, 25, 10, 30), false},
+		{"1d12h", date(10, 19, 22, 30), false},
+		{"in 3 days", date(10, 21, 10, 30), false},
+		{"In 2 Hours", now.Add(2 * time.Hour), false},
+		{"1 day 2 hours", date(10, 19, 12, 30), false},
+		{"tomorrow", date(10, 19, 9, 0), false},
+		{"tomorrow 9am", date(10, 19, 9, 0), false},
+		{"tomorrow at 9:30 pm", date(10, 19, 21, 30), false},
+		{"tonight", date(10, 18, 20, 0), false},
+		{"today 18:30", date(10, 18, 18, 30), false},
+		{"monday", date(10, 19, 9, 0), false},
+		{"next fri 8am", date(10, 23, 8, 0), false},
+		{"sunday", date(10, 25, 9, 0), false},
+		{"2026-12-24", date(12, 24, 9, 0), false},
+		{"on 2026-12-24 at 20:00", date(12, 24, 20, 0), false},
+		{"9pm", date(10, 18, 21, 0), false},
+		{"at 9am", date(10, 19, 9, 0), false},
+		{"noon", date(10, 18, 12, 0), false},
+		{"midnight", date(10, 19, 0, 0), false},
+		{"12am", date(10, 19, 0, 0), false},
+		{"", time.Time{}, true},
+		{"someday", time.Time{}, true},
+		{"3 lightyears", time.Time{}, true},
+		{"25:00", time.Time{}, true},
+		{"13pm", time.Time{}, true},
+		{"today 9am", time.Time{}, true},
+		{"2026-01-01", time.Time{}, true},
+		{"0d", time.Time{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			got, err := Parse(tt.expr, now)
+			if (err != nil) != tt.expectedError {
+				t.Fatalf("Expected error: %v, got %v", tt.expectedError, err)
+			}
+			if !got.Equal(tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+
+	t.Run("Past times", func(t *testing.T) {
+		if _, err := Parse("today 9am", now); !errors.Is(err, ErrPast) {
+			t.Errorf("Expected ErrPast, got %v", err)
+		}
+	})
+}
+
+func TestSince(t *testing.T) {
+	madrid, err := time.LoadLocation("Europe/Madrid")
+	if err != nil {
+		t.Skipf("Time zone database not available: %v", err)
+	}
+	now := time.Date(2026, 10, 18, 10, 30, 0, 0, madrid) // Sunday
+	date := func(month time.Month, day, hour, minute int) time.Time {
+		return time.Date(2026, month, day, hour, minute, 0, 0, madrid)
+	}
+
+	tests := []struct {
+		expr          string
+		expected      time.Time
+		expectedError bool
+	}{
+		{"7d", date(10, 11, 10, 30), false},
+		{"2 weeks ago", date(10, 4, 10, 30), false},
+		{"12h", now.Add(-12 * time.Hour), false},
+		{"30d", date(9, 18, 10, 30), false}, // Across the DST change.
+		{"today", date(10, 18, 0, 0), false},
+		{"Yesterday", date(10, 17, 0, 0), false},
+		{"2026-01-01", date(1, 1, 0, 0), false},
+		{"", time.Time{}, true},
+		{"someday", time.Time{}, true},
+		{"2026-12-24", time.Time{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			got, err := Since(tt.expr, now)
+			if (err != nil) != tt.expectedError {
+				t.Fatalf("Expected error: %v, got %v", tt.expectedError, err)
+			}
+			if !got.Equal(tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
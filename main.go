@@ -1,9 +1,14 @@
 // Package main is the entry point of the application. It initializes the
-// configuration, sets up logging, and starts the Karakeepbot.
+// configuration, sets up logging, and starts the Karakeepbot or runs the
+// requested command.
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
+	"log"
+	"os"
 
 	"github.com/Madh93/karakeepbot/internal/config"
 	"github.com/Madh93/karakeepbot/internal/karakeepbot"
@@ -14,14 +19,32 @@ import (
 // Karakeepbot.
 func main() {
 	// Load configuration
-	config := config.New()
+	config := loadConfig()
 
-	// Setup logger
-	logger := logging.New(&config.Logging)
+	// Validate configuration before using it, unless the command reports the
+	// problems itself
+	invalid := config.Validate()
+	if invalid != nil && (len(config.Args) == 0 || commands[config.Args[0]].validate) {
+		printValidationErrors(invalid)
+		os.Exit(exitFailure)
+	}
+
+	// Setup logger, with the default settings if the configuration is invalid
+	logger := newLogger(config, invalid != nil)
 	if config.Path != "" {
 		logger.Debug(fmt.Sprintf("Loaded configuration from %s", config.Path))
 	}
 
+	// Warn about deprecated settings
+	for _, deprecation := range config.Deprecations() {
+		logger.Warn(deprecation)
+	}
+
+	// Run command, if any
+	if len(config.Args) > 0 {
+		os.Exit(runCommand(logger, config, config.Args))
+	}
+
 	// Setup karakeepbot
 	karakeepbot := karakeepbot.New(logger, config)
 
@@ -31,3 +54,25 @@ func main() {
 		logger.Fatal("💥 Something went wrong.", "error", err)
 	}
 }
+
+// newLogger returns the logger configured in cfg, or one with the default
+// settings if cfg is invalid.
+func newLogger(cfg *config.Config, invalid bool) *logging.Logger {
+	if invalid {
+		return logging.New(&config.DefaultConfig.Logging)
+	}
+	return logging.New(&cfg.Logging)
+}
+
+// loadConfig loads the configuration from the command line arguments, the
+// configuration file and the environment variables.
+func loadConfig() *config.Config {
+	cfg, err := config.New(os.Args[1:])
+	switch {
+	case errors.Is(err, flag.ErrHelp), errors.Is(err, config.ErrVersion):
+		os.Exit(exitOK)
+	case err != nil:
+		log.Fatalf("Error loading configuration: %v", err)
+	}
+	return cfg
+}